@@ -8,23 +8,217 @@
 
 package did
 
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/crypto/pb"
+)
+
 type GetAnchorFunc func(did DID) (Anchor, error)
 
-var anchorMethods map[string]GetAnchorFunc
+// GetAnchorFuncCtx is a context-aware anchor constructor, for methods whose
+// resolution may block (e.g. on the network).
+type GetAnchorFuncCtx func(ctx context.Context, did DID) (Anchor, error)
+
+// GetAnchorFuncTrust is an anchor constructor that can consult the trust
+// context resolving the DID, e.g. to resolve the anchor of a controller DID
+// referenced by a document.
+type GetAnchorFuncTrust func(ctx context.Context, trust TrustContext, did DID) (Anchor, error)
+
+// ResolutionKind describes what resolving a DID's anchor involves.
+type ResolutionKind int
+
+const (
+	// ResolutionUnavailable means no anchor method is registered.
+	ResolutionUnavailable ResolutionKind = iota
+	// ResolutionLocal means the anchor is derived from the DID itself.
+	ResolutionLocal
+	// ResolutionNetwork means resolution may make network requests.
+	ResolutionNetwork
+	// ResolutionHardware means resolution talks to a hardware device.
+	ResolutionHardware
+)
+
+func (k ResolutionKind) String() string {
+	switch k {
+	case ResolutionUnavailable:
+		return "unavailable"
+	case ResolutionLocal:
+		return "local"
+	case ResolutionNetwork:
+		return "network"
+	case ResolutionHardware:
+		return "hardware"
+	default:
+		return fmt.Sprintf("ResolutionKind(%d)", int(k))
+	}
+}
+
+var (
+	anchorMethodsMx    sync.RWMutex
+	anchorMethods      map[string]GetAnchorFunc
+	anchorMethodsCtx   = map[string]GetAnchorFuncCtx{}
+	anchorMethodsTrust = map[string]GetAnchorFuncTrust{}
+	resolutionKinds    = map[string]ResolutionKind{
+		"ethr":  ResolutionNetwork,
+		"key":   ResolutionLocal,
+		"multi": ResolutionLocal,
+		"peer":  ResolutionLocal,
+		"pkh":   ResolutionLocal,
+		"web":   ResolutionNetwork,
+	}
+)
 
 func init() {
 	anchorMethods = map[string]GetAnchorFunc{
-		"key": makeKeyAnchor,
+		"key":   makeKeyAnchor,
+		"multi": makeMultiAnchor,
+		"peer":  makePeerAnchor,
+		"pkh":   makePKHAnchor,
 	}
+	anchorMethodsCtx[ethrMethod] = makeEthrAnchor
+}
+
+// SetResolutionKind declares how the anchor method for method resolves.
+// Registered methods without a declared kind are assumed to use the
+// network.
+func SetResolutionKind(method string, kind ResolutionKind) {
+	anchorMethodsMx.Lock()
+	defer anchorMethodsMx.Unlock()
+
+	resolutionKinds[method] = kind
+}
+
+// Resolution reports what resolving did's anchor with the registered
+// method involves, so callers can avoid network resolution in
+// latency-sensitive paths.
+func Resolution(did DID) ResolutionKind {
+	method := did.Method()
+
+	anchorMethodsMx.RLock()
+	defer anchorMethodsMx.RUnlock()
+
+	_, ok := anchorMethods[method]
+	_, okCtx := anchorMethodsCtx[method]
+	_, okTrust := anchorMethodsTrust[method]
+	if !ok && !okCtx && !okTrust {
+		return ResolutionUnavailable
+	}
+
+	if kind, ok := resolutionKinds[method]; ok {
+		return kind
+	}
+
+	return ResolutionNetwork
+}
+
+// IsOffline reports whether did's anchor can be resolved locally.
+func IsOffline(did DID) bool {
+	return Resolution(did) == ResolutionLocal
+}
+
+// RegisterAnchorMethod registers the anchor constructor for a DID method,
+// replacing any existing one.
+func RegisterAnchorMethod(method string, fn GetAnchorFunc) {
+	anchorMethodsMx.Lock()
+	defer anchorMethodsMx.Unlock()
+
+	delete(anchorMethodsCtx, method)
+	delete(anchorMethodsTrust, method)
+	anchorMethods[method] = fn
+}
+
+// RegisterAnchorMethodContext registers a context-aware anchor constructor
+// for a DID method, replacing any existing one.
+func RegisterAnchorMethodContext(method string, fn GetAnchorFuncCtx) {
+	anchorMethodsMx.Lock()
+	defer anchorMethodsMx.Unlock()
+
+	delete(anchorMethods, method)
+	delete(anchorMethodsTrust, method)
+	anchorMethodsCtx[method] = fn
+}
+
+// RegisterAnchorMethodWithTrust registers an anchor constructor for a DID
+// method that is passed the trust context doing the resolution, replacing
+// any existing one. Resolution outside a trust context (GetAnchorForDID)
+// passes a ReadOnlyTrustContext.
+func RegisterAnchorMethodWithTrust(method string, fn GetAnchorFuncTrust) {
+	anchorMethodsMx.Lock()
+	defer anchorMethodsMx.Unlock()
+
+	delete(anchorMethods, method)
+	delete(anchorMethodsCtx, method)
+	anchorMethodsTrust[method] = fn
+}
+
+// UnregisterAnchorMethod removes the anchor constructor for a DID method.
+func UnregisterAnchorMethod(method string) {
+	anchorMethodsMx.Lock()
+	defer anchorMethodsMx.Unlock()
+
+	delete(anchorMethods, method)
+	delete(anchorMethodsCtx, method)
+	delete(anchorMethodsTrust, method)
+}
+
+// getAnchorMethod returns the constructor for the method, adapting the
+// context-aware and plain variants.
+func getAnchorMethod(method string) (GetAnchorFuncTrust, bool) {
+	anchorMethodsMx.RLock()
+	defer anchorMethodsMx.RUnlock()
+
+	if fn, ok := anchorMethodsTrust[method]; ok {
+		return fn, true
+	}
+
+	if fn, ok := anchorMethodsCtx[method]; ok {
+		return func(ctx context.Context, _ TrustContext, did DID) (Anchor, error) {
+			return fn(ctx, did)
+		}, true
+	}
+
+	fn, ok := anchorMethods[method]
+	if !ok {
+		return nil, false
+	}
+
+	return func(_ context.Context, _ TrustContext, did DID) (Anchor, error) {
+		return fn(did)
+	}, true
 }
 
 func GetAnchorForDID(did DID) (Anchor, error) {
-	makeAnchor, ok := anchorMethods[did.Method()]
+	return GetAnchorForDIDContext(context.Background(), did)
+}
+
+// GetAnchorForDIDContext resolves the anchor for did; ctx bounds resolution
+// for methods registered with RegisterAnchorMethodContext and is otherwise
+// only checked before resolving.
+func GetAnchorForDIDContext(ctx context.Context, did DID) (Anchor, error) {
+	return resolveAnchor(ctx, ReadOnlyTrustContext{}, did)
+}
+
+// resolveAnchor resolves the anchor for did with the registered method,
+// passing trust to methods registered with RegisterAnchorMethodWithTrust.
+func resolveAnchor(ctx context.Context, trust TrustContext, did DID) (Anchor, error) {
+	makeAnchor, ok := getAnchorMethod(did.Method())
 	if !ok {
-		return nil, ErrNoAnchorMethod
+		return nil, &DIDError{Op: "resolve", DID: did.URI, Err: ErrNoAnchorMethod}
 	}
 
-	return makeAnchor(did)
+	if err := ctx.Err(); err != nil {
+		return nil, &DIDError{Op: "resolve", DID: did.URI, Err: err}
+	}
+
+	anchor, err := makeAnchor(ctx, trust, did)
+	if err != nil {
+		return nil, &DIDError{Op: "resolve", DID: did.URI, Err: err}
+	}
+
+	return anchor, nil
 }
 
 func makeKeyAnchor(did DID) (Anchor, error) {
@@ -35,3 +229,25 @@ func makeKeyAnchor(did DID) (Anchor, error) {
 
 	return NewAnchor(did, pubk), nil
 }
+
+// AnchorFromDIDString parses s and resolves its anchor with the registered
+// anchor methods.
+func AnchorFromDIDString(s string) (Anchor, error) {
+	did, err := FromString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetAnchorForDID(did)
+}
+
+// ParseDID parses s and returns the DID with the key type of its anchor,
+// which is KeyTypeNone for anchors not backed by a single key.
+func ParseDID(s string) (DID, pb.KeyType, error) {
+	anchor, err := AnchorFromDIDString(s)
+	if err != nil {
+		return DID{}, KeyTypeNone, err
+	}
+
+	return anchor.DID(), anchor.KeyType(), nil
+}
@@ -1,8 +1,13 @@
 package did
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/depinkit/crypto"
 )
@@ -52,14 +57,11 @@ func TestMakeKeyAnchorInvalidInputs(t *testing.T) {
 }
 
 func TestGetAnchorForDIDWithInjectedCustomMethod(t *testing.T) {
-	// save and restore the original map so we don't break other tests
-	orig := anchorMethods
-	t.Cleanup(func() { anchorMethods = orig })
-
-	// inject a fake method handler
-	anchorMethods["foo"] = func(did DID) (Anchor, error) {
+	// inject a fake method handler, removing it so we don't break other tests
+	RegisterAnchorMethod("foo", func(did DID) (Anchor, error) {
 		return NewAnchor(did, nil), nil
-	}
+	})
+	t.Cleanup(func() { UnregisterAnchorMethod("foo") })
 
 	did, err := FromString("did:foo:bar")
 	require.NoError(t, err)
@@ -68,3 +70,173 @@ func TestGetAnchorForDIDWithInjectedCustomMethod(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, did, anchor.DID())
 }
+
+func TestAnchorFromDIDStringAndParseDID(t *testing.T) {
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Secp256k1)
+	require.NoError(t, err)
+	did := FromPublicKey(pubk)
+
+	anchor, err := AnchorFromDIDString(did.URI)
+	require.NoError(t, err)
+	require.Equal(t, did, anchor.DID())
+	require.True(t, pubk.Equals(anchor.PublicKey()))
+
+	parsed, kt, err := ParseDID(did.URI)
+	require.NoError(t, err)
+	require.Equal(t, did, parsed)
+	require.Equal(t, crypto.Secp256k1, int(kt))
+
+	// works for any registered method
+	peerDID, err := FromPeerKey(pubk)
+	require.NoError(t, err)
+	_, kt, err = ParseDID(peerDID.URI)
+	require.NoError(t, err)
+	require.Equal(t, pubk.Type(), kt)
+
+	_, err = AnchorFromDIDString("not a did")
+	require.ErrorIs(t, err, ErrInvalidDID)
+
+	_, kt, err = ParseDID("did:unknown:123")
+	require.ErrorIs(t, err, ErrNoAnchorMethod)
+	require.Equal(t, KeyTypeNone, kt)
+}
+
+func TestAnchorMethodRegistryConcurrent(t *testing.T) {
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	did := FromPublicKey(pubk)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		method := fmt.Sprintf("race%d", i)
+		go func() {
+			defer wg.Done()
+			RegisterAnchorMethod(method, makeKeyAnchor)
+			UnregisterAnchorMethod(method)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := GetAnchorForDID(did)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	_, err = GetAnchorForDID(DID{URI: "did:race0:x"})
+	require.ErrorIs(t, err, ErrNoAnchorMethod)
+}
+
+func TestGetAnchorForDIDContext(t *testing.T) {
+	type ctxKey struct{}
+
+	RegisterAnchorMethodContext("slow", func(ctx context.Context, did DID) (Anchor, error) {
+		if v, ok := ctx.Value(ctxKey{}).(string); ok && v == "fast" {
+			return NewAnchor(did, nil), nil
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	t.Cleanup(func() { UnregisterAnchorMethod("slow") })
+
+	did := DID{URI: "did:slow:example"}
+
+	anchor, err := GetAnchorForDIDContext(context.WithValue(context.Background(), ctxKey{}, "fast"), did)
+	require.NoError(t, err)
+	require.Equal(t, did, anchor.DID())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = GetAnchorForDIDContext(ctx, did)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// plain methods see a cancelled context before resolving
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	_, err = GetAnchorForDIDContext(cancelled, FromPublicKey(pubk))
+	require.ErrorIs(t, err, context.Canceled)
+
+	// the trust context does not cache cancelled resolutions
+	tc := NewTrustContext()
+	_, err = tc.GetAnchorContext(cancelled, did)
+	require.ErrorIs(t, err, context.Canceled)
+	anchor, err = tc.GetAnchorContext(context.WithValue(context.Background(), ctxKey{}, "fast"), did)
+	require.NoError(t, err)
+	require.Equal(t, did, anchor.DID())
+
+	// registering a plain method replaces the context-aware one
+	RegisterAnchorMethod("slow", func(did DID) (Anchor, error) { return NewAnchor(did, nil), nil })
+	_, err = GetAnchorForDIDContext(context.Background(), did)
+	require.NoError(t, err)
+}
+
+func TestResolution(t *testing.T) {
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	peerDID, err := FromPeerKey(pubk)
+	require.NoError(t, err)
+
+	require.Equal(t, ResolutionLocal, Resolution(FromPublicKey(pubk)))
+	require.Equal(t, ResolutionLocal, Resolution(peerDID))
+	require.True(t, IsOffline(FromPublicKey(pubk)))
+
+	web := DID{URI: "did:web:example.com"}
+	require.Equal(t, ResolutionUnavailable, Resolution(web))
+	require.False(t, IsOffline(web))
+
+	RegisterAnchorMethod("web", func(DID) (Anchor, error) { return nil, ErrTODO })
+	t.Cleanup(func() { UnregisterAnchorMethod("web") })
+	require.Equal(t, ResolutionNetwork, Resolution(web))
+
+	// undeclared methods are assumed to hit the network
+	RegisterAnchorMethod("custom", func(DID) (Anchor, error) { return nil, ErrTODO })
+	t.Cleanup(func() { UnregisterAnchorMethod("custom") })
+	custom := DID{URI: "did:custom:1"}
+	require.Equal(t, ResolutionNetwork, Resolution(custom))
+
+	SetResolutionKind("custom", ResolutionHardware)
+	t.Cleanup(func() {
+		anchorMethodsMx.Lock()
+		delete(resolutionKinds, "custom")
+		anchorMethodsMx.Unlock()
+	})
+	require.Equal(t, ResolutionHardware, Resolution(custom))
+	require.Equal(t, "hardware", Resolution(custom).String())
+}
+
+func TestRegisterAnchorMethodWithTrust(t *testing.T) {
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	controller := FromPublicKey(pubk)
+
+	// the delegate's anchor is its controller's key, resolved through the
+	// trust context doing the resolution
+	RegisterAnchorMethodWithTrust("delegate", func(ctx context.Context, trust TrustContext, did DID) (Anchor, error) {
+		anchor, err := trust.GetAnchorContext(ctx, controller)
+		if err != nil {
+			return nil, err
+		}
+		return NewAnchor(did, anchor.PublicKey()), nil
+	})
+	t.Cleanup(func() { UnregisterAnchorMethod("delegate") })
+
+	did := DID{URI: "did:delegate:1"}
+	require.Equal(t, ResolutionNetwork, Resolution(did))
+
+	tc := NewTrustContext()
+	anchor, err := tc.GetAnchor(did)
+	require.NoError(t, err)
+	require.Equal(t, did, anchor.DID())
+	require.True(t, pubk.Equals(anchor.PublicKey()))
+	require.ElementsMatch(t, []DID{did, controller}, tc.Anchors())
+
+	// outside a trust context the method is passed a read-only one
+	anchor, err = GetAnchorForDID(did)
+	require.NoError(t, err)
+	require.True(t, pubk.Equals(anchor.PublicKey()))
+
+	UnregisterAnchorMethod("delegate")
+	require.Equal(t, ResolutionUnavailable, Resolution(did))
+}
@@ -0,0 +1,201 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+//go:build bls
+
+package did
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/cloudflare/circl/sign/bls"
+	"github.com/libp2p/go-libp2p/core/crypto/pb"
+
+	"github.com/depinkit/crypto"
+)
+
+// BLS keys live in G2 and signatures in G1 (the "minimal signature size"
+// variant), using the basic scheme of the IETF BLS signature draft.
+type blsGroup = bls.KeyG2SigG1
+
+// BLSPublicKey is a BLS12-381 G2 public key.
+type BLSPublicKey struct {
+	key *bls.PublicKey[blsGroup]
+}
+
+// BLSPrivateKey is a BLS12-381 private key whose public key is in G2.
+type BLSPrivateKey struct {
+	key *bls.PrivateKey[blsGroup]
+}
+
+// BLSAnchor is an anchor for a BLS12-381 G2 public key.
+type BLSAnchor struct {
+	*PublicKeyAnchor
+	key *BLSPublicKey
+}
+
+var (
+	_ crypto.PubKey  = (*BLSPublicKey)(nil)
+	_ crypto.PrivKey = (*BLSPrivateKey)(nil)
+	_ Anchor         = (*BLSAnchor)(nil)
+)
+
+// GenerateBLSKeyPair generates a BLS12-381 key pair.
+func GenerateBLSKeyPair() (*BLSPrivateKey, *BLSPublicKey, error) {
+	ikm := make([]byte, 32)
+	if _, err := rand.Read(ikm); err != nil {
+		return nil, nil, fmt.Errorf("read random: %w", err)
+	}
+
+	privk, err := bls.KeyGen[blsGroup](ikm, nil, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate bls key: %w", err)
+	}
+
+	return &BLSPrivateKey{key: privk}, &BLSPublicKey{key: privk.PublicKey()}, nil
+}
+
+// UnmarshalBLSPublicKey decodes a compressed G2 point, checking that it is a
+// valid, non-identity element of the subgroup.
+func UnmarshalBLSPublicKey(data []byte) (*BLSPublicKey, error) {
+	key := new(bls.PublicKey[blsGroup])
+	if err := key.UnmarshalBinary(data); err != nil {
+		return nil, fmt.Errorf("unmarshal bls public key: %w: %w", ErrInvalidKeyType, err)
+	}
+
+	if !key.Validate() {
+		return nil, fmt.Errorf("invalid bls public key: %w", ErrInvalidKeyType)
+	}
+
+	return &BLSPublicKey{key: key}, nil
+}
+
+func unmarshalBLSPublicKey(data []byte) (crypto.PubKey, error) {
+	return UnmarshalBLSPublicKey(data)
+}
+
+func (k *BLSPublicKey) Verify(data []byte, sig []byte) (bool, error) {
+	return bls.Verify(k.key, data, sig), nil
+}
+
+func (k *BLSPublicKey) Raw() ([]byte, error) {
+	return k.key.MarshalBinary()
+}
+
+func (k *BLSPublicKey) Type() pb.KeyType {
+	return KeyTypeBLS12381G2
+}
+
+func (k *BLSPublicKey) Equals(o crypto.Key) bool {
+	other, ok := o.(*BLSPublicKey)
+	if !ok {
+		return false
+	}
+
+	return k.key.Equal(other.key)
+}
+
+func (k *BLSPrivateKey) Sign(data []byte) ([]byte, error) {
+	return bls.Sign(k.key, data), nil
+}
+
+func (k *BLSPrivateKey) GetPublic() crypto.PubKey {
+	return &BLSPublicKey{key: k.key.PublicKey()}
+}
+
+func (k *BLSPrivateKey) Raw() ([]byte, error) {
+	return k.key.MarshalBinary()
+}
+
+func (k *BLSPrivateKey) Type() pb.KeyType {
+	return KeyTypeBLS12381G2
+}
+
+func (k *BLSPrivateKey) Equals(o crypto.Key) bool {
+	other, ok := o.(*BLSPrivateKey)
+	if !ok {
+		return false
+	}
+
+	a, err := k.Raw()
+	if err != nil {
+		return false
+	}
+	b, err := other.Raw()
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// NewBLSAnchor creates an anchor for a BLS public key.
+func NewBLSAnchor(did DID, pubk crypto.PubKey) (*BLSAnchor, error) {
+	key, ok := pubk.(*BLSPublicKey)
+	if !ok {
+		return nil, fmt.Errorf("key type %d is not a bls key: %w", pubk.Type(), ErrInvalidKeyType)
+	}
+
+	return &BLSAnchor{
+		PublicKeyAnchor: &PublicKeyAnchor{did: did, pubk: pubk},
+		key:             key,
+	}, nil
+}
+
+// Verify verifies sig over data with a single pairing check.
+func (a *BLSAnchor) Verify(data []byte, sig []byte) error {
+	if !bls.Verify(a.key.key, data, sig) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// AggregateBLSSignatures aggregates BLS signatures into one.
+func AggregateBLSSignatures(sigs [][]byte) ([]byte, error) {
+	agg, err := bls.Aggregate(blsGroup{}, sigs)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate signatures: %w", err)
+	}
+
+	return agg, nil
+}
+
+// AggregateVerify verifies an aggregate signature where anchors[i] signed
+// msgs[i]. The messages must be distinct, as required by the basic scheme
+// to rule out rogue-key attacks.
+func AggregateVerify(anchors []Anchor, msgs [][]byte, aggSig []byte) error {
+	if len(anchors) == 0 || len(anchors) != len(msgs) {
+		return fmt.Errorf("%d anchors for %d messages: %w", len(anchors), len(msgs), ErrInvalidSignature)
+	}
+
+	seen := make(map[string]struct{}, len(msgs))
+	for _, msg := range msgs {
+		if _, ok := seen[string(msg)]; ok {
+			return fmt.Errorf("duplicate message in aggregate: %w", ErrInvalidSignature)
+		}
+		seen[string(msg)] = struct{}{}
+	}
+
+	keys := make([]*bls.PublicKey[blsGroup], 0, len(anchors))
+	for _, anchor := range anchors {
+		key, ok := anchor.PublicKey().(*BLSPublicKey)
+		if !ok {
+			return fmt.Errorf("anchor %s is not a bls anchor: %w", anchor.DID(), ErrInvalidKeyType)
+		}
+		keys = append(keys, key.key)
+	}
+
+	if !bls.VerifyAggregate(keys, msgs, aggSig) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+//go:build !bls
+
+package did
+
+import (
+	"fmt"
+
+	"github.com/depinkit/crypto"
+)
+
+// errNoBLS is returned for BLS keys when built without the bls tag.
+var errNoBLS = fmt.Errorf("bls support requires building with -tags bls: %w", ErrInvalidKeyType)
+
+func unmarshalBLSPublicKey([]byte) (crypto.PubKey, error) {
+	return nil, errNoBLS
+}
+
+// AggregateVerify verifies an aggregate BLS signature; it always fails
+// unless built with the bls tag.
+func AggregateVerify([]Anchor, [][]byte, []byte) error {
+	return errNoBLS
+}
@@ -0,0 +1,30 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+//go:build !bls
+
+package did
+
+import (
+	"testing"
+
+	mb "github.com/multiformats/go-multibase"
+	varint "github.com/multiformats/go-varint"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBLSUnsupported(t *testing.T) {
+	data := append(varint.ToUvarint(multicodecKindBLS12381G2PubKey), make([]byte, 96)...)
+	enc, err := mb.Encode(mb.Base58BTC, data)
+	require.NoError(t, err)
+
+	_, err = ParseKeyURI(keyPrefix + ":" + enc)
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+
+	require.ErrorIs(t, AggregateVerify(nil, nil, nil), ErrInvalidKeyType)
+}
@@ -0,0 +1,93 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+//go:build bls
+
+package did
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBLSKeyDID(t *testing.T) {
+	privk, pubk, err := GenerateBLSKeyPair()
+	require.NoError(t, err)
+
+	did := FromPublicKey(pubk)
+	require.True(t, strings.HasPrefix(did.URI, "did:key:zUC7"), did.URI)
+
+	recovered, err := PublicKeyFromDID(did)
+	require.NoError(t, err)
+	require.True(t, pubk.Equals(recovered))
+
+	provider, err := ProviderFromPrivateKey(privk)
+	require.NoError(t, err)
+	require.Equal(t, did, provider.DID())
+
+	anchor, err := GetAnchorForDID(did)
+	require.NoError(t, err)
+
+	blsAnchor, err := NewBLSAnchor(did, anchor.PublicKey())
+	require.NoError(t, err)
+
+	msg := []byte("attestation")
+	sig, err := provider.Sign(msg)
+	require.NoError(t, err)
+	require.NoError(t, anchor.Verify(msg, sig))
+	require.NoError(t, blsAnchor.Verify(msg, sig))
+	require.ErrorIs(t, blsAnchor.Verify([]byte("other"), sig), ErrInvalidSignature)
+
+	doc, err := DocumentFromDID(did)
+	require.NoError(t, err)
+	require.Equal(t, Multikey, doc.VerificationMethod[0].Type)
+
+	_, err = UnmarshalBLSPublicKey([]byte{1, 2, 3})
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+}
+
+func TestBLSAggregateVerify(t *testing.T) {
+	const n = 4
+
+	anchors := make([]Anchor, n)
+	msgs := make([][]byte, n)
+	sigs := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		privk, pubk, err := GenerateBLSKeyPair()
+		require.NoError(t, err)
+
+		anchors[i], err = NewBLSAnchor(FromPublicKey(pubk), pubk)
+		require.NoError(t, err)
+
+		msgs[i] = []byte(fmt.Sprintf("attestation %d", i))
+		sigs[i], err = privk.Sign(msgs[i])
+		require.NoError(t, err)
+	}
+
+	agg, err := AggregateBLSSignatures(sigs)
+	require.NoError(t, err)
+	require.NoError(t, AggregateVerify(anchors, msgs, agg))
+
+	// swapped messages
+	swapped := [][]byte{msgs[1], msgs[0], msgs[2], msgs[3]}
+	require.ErrorIs(t, AggregateVerify(anchors, swapped, agg), ErrInvalidSignature)
+
+	// duplicate messages are rejected
+	dup := [][]byte{msgs[0], msgs[0], msgs[2], msgs[3]}
+	require.ErrorIs(t, AggregateVerify(anchors, dup, agg), ErrInvalidSignature)
+
+	require.ErrorIs(t, AggregateVerify(anchors[:3], msgs, agg), ErrInvalidSignature)
+
+	notBLS, err := AnchorFromPublicKey(mustPrivKey(t).GetPublic())
+	require.NoError(t, err)
+	mixed := []Anchor{notBLS, anchors[1], anchors[2], anchors[3]}
+	require.ErrorIs(t, AggregateVerify(mixed, msgs, agg), ErrInvalidKeyType)
+}
@@ -0,0 +1,82 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Build assembles did:<method>:<id>. The id is taken as unencoded: colons
+// separate method-specific-id segments, and any other octet that is not an
+// idchar (including "%") is percent-encoded.
+func Build(method string, id string) (DID, error) {
+	if !validMethodName(method) {
+		return DID{}, &DIDError{Op: "build", DID: method, Err: fmt.Errorf("%w: invalid method name %q", ErrInvalidDID, method)}
+	}
+
+	segments := strings.Split(id, ":")
+	for i, segment := range segments {
+		segments[i] = pctEncodeIDChars(segment)
+	}
+
+	return buildDID(method, segments)
+}
+
+// BuildWeb assembles a did:web DID for domain, which may include a port,
+// and an optional path; e.g. BuildWeb("example.com:3000", "user", "alice")
+// yields did:web:example.com%3A3000:user:alice.
+func BuildWeb(domain string, path ...string) (DID, error) {
+	if domain == "" || strings.ContainsAny(domain, "/?#") {
+		return DID{}, &DIDError{Op: "build", DID: domain, Err: fmt.Errorf("%w: invalid did:web domain %q", ErrInvalidDID, domain)}
+	}
+
+	segments := make([]string, 0, 1+len(path))
+	segments = append(segments, pctEncodeIDChars(strings.ToLower(domain)))
+	for _, p := range path {
+		if p == "" {
+			return DID{}, &DIDError{Op: "build", DID: domain, Err: fmt.Errorf("%w: empty did:web path segment", ErrInvalidDID)}
+		}
+		segments = append(segments, pctEncodeIDChars(p))
+	}
+
+	return buildDID("web", segments)
+}
+
+func buildDID(method string, segments []string) (DID, error) {
+	uri := "did:" + method + ":" + strings.Join(segments, ":")
+	if err := validateDID(uri); err != nil {
+		return DID{}, &DIDError{Op: "build", DID: uri, Err: err}
+	}
+
+	return DID{URI: uri}, nil
+}
+
+// pctEncodeIDChars percent-encodes every octet of s that is not an idchar
+// other than pct-encoded, i.e. ALPHA / DIGIT / "." / "-" / "_".
+func pctEncodeIDChars(s string) string {
+	const hex = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			b.WriteByte(c)
+		case c == '.' || c == '-' || c == '_':
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&0xf])
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,69 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuild(t *testing.T) {
+	cases := []struct {
+		method, id, want string
+	}{
+		{"example", "123456789abcdefghi", "did:example:123456789abcdefghi"},
+		{"example", "a:b", "did:example:a:b"},
+		{"example", "hello world", "did:example:hello%20world"},
+		{"example", "100%", "did:example:100%25"},
+		{"example", "café", "did:example:caf%C3%A9"},
+	}
+
+	for _, tc := range cases {
+		did, err := Build(tc.method, tc.id)
+		require.NoError(t, err, tc.id)
+		require.Equal(t, tc.want, did.URI)
+
+		parsed, err := FromString(did.URI)
+		require.NoError(t, err)
+		require.Equal(t, did, parsed)
+	}
+
+	for _, method := range []string{"", "Web", "we-b"} {
+		_, err := Build(method, "x")
+		require.ErrorIs(t, err, ErrInvalidDID, method)
+	}
+
+	for _, id := range []string{"", "a:"} {
+		_, err := Build("example", id)
+		require.ErrorIs(t, err, ErrInvalidDID, id)
+	}
+}
+
+func TestBuildWeb(t *testing.T) {
+	did, err := BuildWeb("example.com")
+	require.NoError(t, err)
+	require.Equal(t, "did:web:example.com", did.URI)
+
+	did, err = BuildWeb("Example.com:3000", "user", "alice")
+	require.NoError(t, err)
+	require.Equal(t, "did:web:example.com%3A3000:user:alice", did.URI)
+
+	did, err = BuildWeb("example.com", "a:b")
+	require.NoError(t, err)
+	require.Equal(t, "did:web:example.com:a%3Ab", did.URI)
+
+	for _, domain := range []string{"", "example.com/path"} {
+		_, err := BuildWeb(domain)
+		require.ErrorIs(t, err, ErrInvalidDID, domain)
+	}
+
+	_, err = BuildWeb("example.com", "")
+	require.ErrorIs(t, err, ErrInvalidDID)
+}
@@ -0,0 +1,60 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// cborEncMode is the Core Deterministic Encoding (RFC 8949 section 4.2.1),
+// so that map keys, and hence struct fields, are always in the same order.
+var cborEncMode = func() cbor.EncMode {
+	mode, err := cbor.CoreDetEncOptions().EncMode()
+	if err != nil {
+		panic(err)
+	}
+	return mode
+}()
+
+var (
+	_ cbor.Marshaler   = DID{}
+	_ cbor.Unmarshaler = (*DID)(nil)
+	_ cbor.Marshaler   = SignatureEnvelope{}
+)
+
+// MarshalCBOR encodes the DID as a CBOR text string.
+func (did DID) MarshalCBOR() ([]byte, error) {
+	return cborEncMode.Marshal(did.URI)
+}
+
+// UnmarshalCBOR decodes a DID from a CBOR text string, validating it as
+// FromString does.
+func (did *DID) UnmarshalCBOR(data []byte) error {
+	var s string
+	if err := cbor.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("decode did: %w", err)
+	}
+
+	parsed, err := FromString(s)
+	if err != nil {
+		return err
+	}
+
+	*did = parsed
+	return nil
+}
+
+// MarshalCBOR encodes the envelope as a CBOR map with deterministically
+// ordered keys.
+func (env SignatureEnvelope) MarshalCBOR() ([]byte, error) {
+	type envelope SignatureEnvelope
+	return cborEncMode.Marshal(envelope(env))
+}
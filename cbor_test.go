@@ -0,0 +1,62 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDIDCBOR(t *testing.T) {
+	did := DID{URI: "did:web:example.com"}
+
+	data, err := cbor.Marshal(did)
+	require.NoError(t, err)
+	require.Equal(t, append([]byte{0x73}, did.URI...), data, "DID must encode as a text string")
+
+	var decoded DID
+	require.NoError(t, cbor.Unmarshal(data, &decoded))
+	require.Equal(t, did, decoded)
+
+	bad, err := cbor.Marshal("not-a-did")
+	require.NoError(t, err)
+	require.ErrorIs(t, cbor.Unmarshal(bad, &decoded), ErrInvalidDID)
+
+	notText, err := cbor.Marshal(42)
+	require.NoError(t, err)
+	require.Error(t, cbor.Unmarshal(notText, &decoded))
+}
+
+func TestSignatureEnvelopeCBOR(t *testing.T) {
+	provider, err := ProviderFromPrivateKey(mustPrivKey(t))
+	require.NoError(t, err)
+
+	data := []byte("hello")
+	env, err := SignEnvelope(provider, data)
+	require.NoError(t, err)
+
+	encoded, err := cbor.Marshal(env)
+	require.NoError(t, err)
+	again, err := cbor.Marshal(env)
+	require.NoError(t, err)
+	require.Equal(t, encoded, again)
+
+	// map(3), keys in deterministic order: "alg", "did", "sig"
+	var fields map[string]cbor.RawMessage
+	require.NoError(t, cbor.Unmarshal(encoded, &fields))
+	require.Len(t, fields, 3)
+	require.Equal(t, []byte{0xa3, 0x63, 'a', 'l', 'g'}, encoded[:5])
+
+	var decoded SignatureEnvelope
+	require.NoError(t, cbor.Unmarshal(encoded, &decoded))
+	require.Equal(t, env, decoded)
+	require.NoError(t, VerifyEnvelope(NewTrustContext(), data, decoded))
+}
@@ -10,14 +10,29 @@ package did
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/libp2p/go-libp2p/core/crypto/pb"
+
 	"github.com/depinkit/crypto"
 )
 
-const anchorEntryTTL = time.Hour
+const (
+	anchorEntryTTL = time.Hour
+
+	// negativeEntryTTL is how long failed resolutions are cached by default.
+	negativeEntryTTL = 30 * time.Second
+
+	// maxResolveWorkers bounds concurrent resolutions in GetAnchors and
+	// Warm unless set with SetResolveConcurrency.
+	maxResolveWorkers = 8
+)
 
 // Anchor is a DID anchor that encapsulates a public key that can be used
 // for verification of signatures.
@@ -25,8 +40,18 @@ type Anchor interface {
 	DID() DID
 	Verify(data []byte, sig []byte) error
 	PublicKey() crypto.PubKey
+	// KeyType returns the anchor's key type, or KeyTypeNone if the anchor
+	// is not backed by a single key.
+	KeyType() pb.KeyType
+	// Algorithm returns the JOSE signature algorithm for the anchor's key
+	// (e.g. "EdDSA", "ES256K"), or "" if there is none.
+	Algorithm() string
 }
 
+// KeyTypeNone is the key type of anchors that are not backed by a single
+// key.
+const KeyTypeNone pb.KeyType = -1
+
 // Provider holds the private key material necessary to sign statements for
 // a DID.
 type Provider interface {
@@ -36,27 +61,110 @@ type Provider interface {
 	PrivateKey() (crypto.PrivKey, error)
 }
 
+// ContextSigner is implemented by providers that can sign with a
+// cancellable context; signing may block (e.g. on a hardware wallet) and
+// the context allows callers to bound it.
+type ContextSigner interface {
+	SignContext(ctx context.Context, data []byte) ([]byte, error)
+}
+
 type TrustContext interface {
 	Anchors() []DID
 	Providers() []DID
 	GetAnchor(did DID) (Anchor, error)
+	GetAnchorContext(resolveCtx context.Context, did DID) (Anchor, error)
+	GetAnchors(dids []DID) (map[DID]Anchor, map[DID]error)
 	GetProvider(did DID) (Provider, error)
 	AddAnchor(anchor Anchor)
 	AddProvider(provider Provider)
+	RemoveAnchor(did DID)
+	RemoveProvider(did DID)
 
 	Start(gcInterval time.Duration)
 	Stop()
+	// GC runs a single eviction pass synchronously, without requiring the
+	// background GC started by Start.
+	GC()
 }
 
+// anchorEntry tracks a cached anchor; permanent entries (did:key anchors
+// for the key the DID names, which can never change, and revoked anchors)
+// are never evicted.
 type anchorEntry struct {
-	anchor Anchor
-	expire time.Time
+	anchor    Anchor
+	expire    deadline
+	permanent bool
+}
+
+// providerEntry tracks a provider; a zero expire means the provider is
+// permanent.
+type providerEntry struct {
+	provider Provider
+	expire   deadline
+}
+
+// documentEntry caches the DID Document of an anchor.
+type documentEntry struct {
+	doc    *Document
+	expire deadline
+}
+
+// negativeEntry caches a failed resolution so that repeated lookups of an
+// unresolvable DID do not hit the network again until it expires.
+type negativeEntry struct {
+	err    error
+	expire deadline
+}
+
+func (e *providerEntry) expired(now deadline) bool {
+	return e.expire != 0 && e.expire.before(now)
+}
+
+// deadline is a point on the monotonic clock, as an offset from
+// monotonicEpoch. Unlike a time.Time it cannot lose its monotonic reading
+// (e.g. to Round or to a value built from wall-clock fields), so TTLs are
+// unaffected by wall-clock steps such as NTP adjustments.
+type deadline time.Duration
+
+func (d deadline) add(ttl time.Duration) deadline {
+	return d + deadline(ttl)
+}
+
+func (d deadline) before(other deadline) bool {
+	return d < other
+}
+
+var monotonicEpoch = time.Now()
+
+// monotonicNow is the clock used for trust context TTLs; tests replace it.
+var monotonicNow = func() deadline {
+	return deadline(time.Since(monotonicEpoch))
 }
 
 type BasicTrustContext struct {
 	mx        sync.Mutex
 	anchors   map[DID]*anchorEntry
-	providers map[DID]Provider
+	providers map[DID]*providerEntry
+	negative  map[DID]*negativeEntry
+	documents map[DID]*documentEntry
+
+	ttl         time.Duration
+	methodTTL   map[string]time.Duration
+	negativeTTL time.Duration
+	documentTTL time.Duration
+
+	resolveWorkers int
+
+	providerResolver ProviderResolver
+
+	onAnchorAdded   []func(DID)
+	onAnchorEvicted []func(DID)
+
+	cacheHits   atomic.Uint64
+	cacheMisses atomic.Uint64
+	docHits     atomic.Uint64
+	docMisses   atomic.Uint64
+	evictions   atomic.Uint64
 
 	stop func()
 }
@@ -64,9 +172,21 @@ type BasicTrustContext struct {
 var _ TrustContext = (*BasicTrustContext)(nil)
 
 func NewTrustContext() TrustContext {
+	return NewTrustContextWithTTL(anchorEntryTTL)
+}
+
+// NewTrustContextWithTTL creates a trust context whose cached anchors expire
+// after ttl unless a per-method TTL is set with SetMethodTTL.
+func NewTrustContextWithTTL(ttl time.Duration) TrustContext {
 	return &BasicTrustContext{
-		anchors:   make(map[DID]*anchorEntry),
-		providers: make(map[DID]Provider),
+		anchors:        make(map[DID]*anchorEntry),
+		providers:      make(map[DID]*providerEntry),
+		negative:       make(map[DID]*negativeEntry),
+		documents:      make(map[DID]*documentEntry),
+		ttl:            ttl,
+		methodTTL:      make(map[string]time.Duration),
+		negativeTTL:    negativeEntryTTL,
+		resolveWorkers: maxResolveWorkers,
 	}
 }
 
@@ -103,69 +223,542 @@ func (ctx *BasicTrustContext) Providers() []DID {
 	ctx.mx.Lock()
 	defer ctx.mx.Unlock()
 
+	now := monotonicNow()
 	result := make([]DID, 0, len(ctx.providers))
-	for provider := range ctx.providers {
+	for provider, entry := range ctx.providers {
+		if entry.expired(now) {
+			continue
+		}
 		result = append(result, provider)
 	}
 
 	return result
 }
 
+// AllAnchors returns a snapshot of the cached anchors, taken under a single
+// lock; order is unspecified.
+func (ctx *BasicTrustContext) AllAnchors() []Anchor {
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	result := make([]Anchor, 0, len(ctx.anchors))
+	for _, entry := range ctx.anchors {
+		result = append(result, entry.anchor)
+	}
+
+	return result
+}
+
+// AllProviders returns a snapshot of the unexpired providers, taken under a
+// single lock; order is unspecified.
+func (ctx *BasicTrustContext) AllProviders() []Provider {
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	now := monotonicNow()
+	result := make([]Provider, 0, len(ctx.providers))
+	for _, entry := range ctx.providers {
+		if entry.expired(now) {
+			continue
+		}
+		result = append(result, entry.provider)
+	}
+
+	return result
+}
+
 func (ctx *BasicTrustContext) GetAnchor(did DID) (Anchor, error) {
-	anchor, ok := ctx.getAnchor(did)
+	return ctx.GetAnchorContext(context.Background(), did)
+}
+
+// GetAnchorContext is GetAnchor with resolution of uncached anchors bounded
+// by resolveCtx. Cancelled resolutions are not negatively cached.
+func (ctx *BasicTrustContext) GetAnchorContext(resolveCtx context.Context, did DID) (Anchor, error) {
+	anchor, ok, err := ctx.getAnchor(did)
 	if ok {
-		return anchor, nil
+		return anchor, err
 	}
+	ctx.cacheMisses.Add(1)
 
-	anchor, err := GetAnchorForDID(did)
+	anchor, err = resolveAnchor(resolveCtx, ctx, did)
 	if err != nil {
-		return nil, fmt.Errorf("get anchor for did: %w", err)
+		err = fmt.Errorf("get anchor for did: %w", err)
+		if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+			ctx.addNegative(did, err)
+		}
+		return nil, err
 	}
 
 	ctx.AddAnchor(anchor)
 	return anchor, nil
 }
 
-func (ctx *BasicTrustContext) getAnchor(did DID) (Anchor, bool) {
+// GetAnchors resolves a batch of DIDs. Cached anchors are collected under a
+// single lock and cache misses are resolved concurrently; failures are
+// reported per DID.
+func (ctx *BasicTrustContext) GetAnchors(dids []DID) (map[DID]Anchor, map[DID]error) {
+	anchors := make(map[DID]Anchor, len(dids))
+	errs := make(map[DID]error)
+
+	var misses []DID
+	seen := make(map[DID]struct{}, len(dids))
+	ctx.mx.Lock()
+	now := monotonicNow()
+	for _, did := range dids {
+		if _, ok := seen[did]; ok {
+			continue
+		}
+		seen[did] = struct{}{}
+
+		if entry, ok := ctx.anchors[did.canonical()]; ok {
+			ctx.cacheHits.Add(1)
+			entry.expire = now.add(ctx.anchorTTL(did))
+			anchors[did] = entry.anchor
+			continue
+		}
+		misses = append(misses, did)
+	}
+	workers := ctx.resolveWorkers
+	ctx.mx.Unlock()
+
+	resolveAnchors(misses, workers, ctx.GetAnchor, anchors, errs)
+	return anchors, errs
+}
+
+// Warm resolves and caches the anchors of dids concurrently, e.g. to
+// pre-resolve did:web anchors at startup so that the first real lookup is
+// fast. Resolution is bounded by resolveCtx; failures are joined into the
+// returned error, in DID order.
+func (ctx *BasicTrustContext) Warm(resolveCtx context.Context, dids []DID) error {
+	seen := make(map[DID]struct{}, len(dids))
+	unique := make([]DID, 0, len(dids))
+	for _, did := range dids {
+		if _, ok := seen[did]; ok {
+			continue
+		}
+		seen[did] = struct{}{}
+		unique = append(unique, did)
+	}
+
+	ctx.mx.Lock()
+	workers := ctx.resolveWorkers
+	ctx.mx.Unlock()
+
+	anchors := make(map[DID]Anchor, len(unique))
+	errs := make(map[DID]error)
+	resolve := func(did DID) (Anchor, error) {
+		return ctx.GetAnchorContext(resolveCtx, did)
+	}
+	resolveAnchors(unique, workers, resolve, anchors, errs)
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	failed := make([]DID, 0, len(errs))
+	for did := range errs {
+		failed = append(failed, did)
+	}
+	slices.SortFunc(failed, func(a, b DID) int { return strings.Compare(a.URI, b.URI) })
+
+	joined := make([]error, 0, len(failed))
+	for _, did := range failed {
+		joined = append(joined, fmt.Errorf("warm %s: %w", did, errs[did]))
+	}
+
+	return errors.Join(joined...)
+}
+
+// SetResolveConcurrency bounds the number of concurrent resolutions in
+// GetAnchors and Warm; a non-positive n restores the default.
+func (ctx *BasicTrustContext) SetResolveConcurrency(n int) {
 	ctx.mx.Lock()
 	defer ctx.mx.Unlock()
 
-	entry, ok := ctx.anchors[did]
-	if ok {
-		entry.expire = time.Now().Add(anchorEntryTTL)
-		return entry.anchor, true
+	if n <= 0 {
+		n = maxResolveWorkers
 	}
+	ctx.resolveWorkers = n
+}
 
-	return nil, false
+// resolveAnchors resolves dids concurrently with at most workers
+// resolutions in flight, recording results in anchors and failures in
+// errs.
+func resolveAnchors(dids []DID, workers int, resolve func(DID) (Anchor, error), anchors map[DID]Anchor, errs map[DID]error) {
+	if len(dids) == 0 {
+		return
+	}
+
+	var (
+		wg    sync.WaitGroup
+		resMx sync.Mutex
+		work  = make(chan DID)
+	)
+
+	if workers <= 0 {
+		workers = maxResolveWorkers
+	}
+	workers = min(workers, len(dids))
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for did := range work {
+				anchor, err := resolve(did)
+
+				resMx.Lock()
+				if err != nil {
+					errs[did] = err
+				} else {
+					anchors[did] = anchor
+				}
+				resMx.Unlock()
+			}
+		}()
+	}
+
+	for _, did := range dids {
+		work <- did
+	}
+	close(work)
+	wg.Wait()
 }
 
-func (ctx *BasicTrustContext) GetProvider(did DID) (Provider, error) {
+// getAnchor looks did up in the anchor and negative caches; ok reports
+// whether either had an entry.
+func (ctx *BasicTrustContext) getAnchor(did DID) (Anchor, bool, error) {
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	now := monotonicNow()
+	key := did.canonical()
+	if entry, ok := ctx.anchors[key]; ok {
+		ctx.cacheHits.Add(1)
+		entry.expire = now.add(ctx.anchorTTL(did))
+		return entry.anchor, true, nil
+	}
+
+	if entry, ok := ctx.negative[key]; ok && !entry.expire.before(now) {
+		ctx.cacheHits.Add(1)
+		return nil, true, entry.err
+	}
+
+	return nil, false, nil
+}
+
+func (ctx *BasicTrustContext) addNegative(did DID, err error) {
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	if ctx.negativeTTL <= 0 {
+		return
+	}
+
+	ctx.negative[did.canonical()] = &negativeEntry{
+		err:    err,
+		expire: monotonicNow().add(ctx.negativeTTL),
+	}
+}
+
+// SetNegativeTTL sets how long failed anchor resolutions are cached; a
+// non-positive ttl disables negative caching.
+func (ctx *BasicTrustContext) SetNegativeTTL(ttl time.Duration) {
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	ctx.negativeTTL = ttl
+	if ttl <= 0 {
+		ctx.negative = make(map[DID]*negativeEntry)
+	}
+}
+
+// SetDocumentTTL enables caching of the DID Documents returned by
+// GetDocument for ttl, independently of the anchor TTL; a non-positive ttl
+// disables document caching, which is the default.
+func (ctx *BasicTrustContext) SetDocumentTTL(ttl time.Duration) {
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	ctx.documentTTL = ttl
+	if ttl <= 0 {
+		ctx.documents = make(map[DID]*documentEntry)
+	}
+}
+
+// GetDocument returns the DID Document of did's anchor, produced from its
+// public key for anchors not backed by a document. With SetDocumentTTL the
+// document is cached, so that e.g. Controllers and Dereference do not fetch
+// it again; the returned document is then shared and must not be modified.
+func (ctx *BasicTrustContext) GetDocument(did DID) (*Document, error) {
+	key := did.canonical()
+
+	ctx.mx.Lock()
+	if entry, ok := ctx.documents[key]; ok && !entry.expire.before(monotonicNow()) {
+		ctx.mx.Unlock()
+		ctx.docHits.Add(1)
+		return entry.doc, nil
+	}
+	ctx.mx.Unlock()
+	ctx.docMisses.Add(1)
+
+	anchor, err := ctx.GetAnchor(did)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := anchorDocument(anchor)
+	if err != nil {
+		return nil, fmt.Errorf("anchor document: %w", err)
+	}
+
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	if ctx.documentTTL > 0 {
+		ctx.documents[key] = &documentEntry{
+			doc:    doc,
+			expire: monotonicNow().add(ctx.documentTTL),
+		}
+	}
+
+	return doc, nil
+}
+
+// SetMethodTTL sets the anchor TTL for DIDs of the given method, overriding
+// the context TTL; e.g. did:web anchors may need to refresh sooner than
+// did:key ones. It applies to anchors added or refreshed afterwards.
+func (ctx *BasicTrustContext) SetMethodTTL(method string, ttl time.Duration) {
 	ctx.mx.Lock()
 	defer ctx.mx.Unlock()
 
-	provider, ok := ctx.providers[did]
-	if !ok {
+	ctx.methodTTL[method] = ttl
+}
+
+// anchorTTL returns the TTL for the DID's anchor; the caller holds the lock.
+func (ctx *BasicTrustContext) anchorTTL(did DID) time.Duration {
+	if ttl, ok := ctx.methodTTL[did.Method()]; ok {
+		return ttl
+	}
+
+	return ctx.ttl
+}
+
+// TrustContextStats is a snapshot of trust context counters; the cache
+// counters are cumulative since the context was created. CacheHits and
+// CacheMisses count anchor lookups; DocumentHits and DocumentMisses count
+// GetDocument lookups.
+type TrustContextStats struct {
+	Anchors        int
+	Providers      int
+	CacheHits      uint64
+	CacheMisses    uint64
+	Evictions      uint64
+	DocumentHits   uint64
+	DocumentMisses uint64
+}
+
+// Stats returns the current trust context counters.
+func (ctx *BasicTrustContext) Stats() TrustContextStats {
+	ctx.mx.Lock()
+	anchors := len(ctx.anchors)
+	providers := 0
+	now := monotonicNow()
+	for _, entry := range ctx.providers {
+		if !entry.expired(now) {
+			providers++
+		}
+	}
+	ctx.mx.Unlock()
+
+	return TrustContextStats{
+		Anchors:        anchors,
+		Providers:      providers,
+		CacheHits:      ctx.cacheHits.Load(),
+		CacheMisses:    ctx.cacheMisses.Load(),
+		Evictions:      ctx.evictions.Load(),
+		DocumentHits:   ctx.docHits.Load(),
+		DocumentMisses: ctx.docMisses.Load(),
+	}
+}
+
+// ProviderResolver materializes the provider for a DID on demand, e.g. by
+// unlocking a KMS key on first use.
+type ProviderResolver func(DID) (Provider, error)
+
+// SetProviderResolver sets the resolver GetProvider falls back to when no
+// provider has been added for a DID; resolved providers are cached like
+// ones added with AddProvider. A nil resolver disables the fallback.
+func (ctx *BasicTrustContext) SetProviderResolver(resolver ProviderResolver) {
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	ctx.providerResolver = resolver
+}
+
+func (ctx *BasicTrustContext) GetProvider(did DID) (Provider, error) {
+	key := did.canonical()
+
+	ctx.mx.Lock()
+	entry, ok := ctx.providers[key]
+	if ok && !entry.expired(monotonicNow()) {
+		ctx.mx.Unlock()
+		return entry.provider, nil
+	}
+	resolver := ctx.providerResolver
+	ctx.mx.Unlock()
+
+	if resolver == nil {
+		return nil, ErrNoProvider
+	}
+
+	// resolve outside the lock, as materializing a key may be slow
+	provider, err := resolver(did)
+	if err != nil {
+		return nil, fmt.Errorf("resolve provider for %s: %w", did, err)
+	}
+	if provider == nil {
 		return nil, ErrNoProvider
 	}
+	if provider.DID().canonical() != key {
+		return nil, fmt.Errorf("resolved provider for %s has DID %s: %w", did, provider.DID(), ErrNoProvider)
+	}
+
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	// keep the provider of a concurrent resolution or AddProvider
+	if entry, ok := ctx.providers[key]; ok && !entry.expired(monotonicNow()) {
+		return entry.provider, nil
+	}
+	ctx.providers[key] = &providerEntry{provider: provider}
 
 	return provider, nil
 }
 
+// AddAnchor caches anchor for its DID. A RevokedAnchor is permanent and is
+// not replaced by other anchors, including concurrently resolved ones, until
+// it is removed with RemoveAnchor.
 func (ctx *BasicTrustContext) AddAnchor(anchor Anchor) {
+	_, revoked := anchor.(*RevokedAnchor)
+	key := anchor.DID().canonical()
+
 	ctx.mx.Lock()
-	defer ctx.mx.Unlock()
+	if entry, ok := ctx.anchors[key]; ok && !revoked {
+		if _, ok := entry.anchor.(*RevokedAnchor); ok {
+			ctx.mx.Unlock()
+			return
+		}
+	}
+	delete(ctx.negative, key)
+	delete(ctx.documents, key)
+	ctx.anchors[key] = &anchorEntry{
+		anchor:    anchor,
+		expire:    monotonicNow().add(ctx.anchorTTL(anchor.DID())),
+		permanent: revoked || derivedKeyAnchor(anchor),
+	}
+	callbacks := ctx.onAnchorAdded
+	ctx.mx.Unlock()
 
-	ctx.anchors[anchor.DID()] = &anchorEntry{
-		anchor: anchor,
-		expire: time.Now().Add(anchorEntryTTL),
+	for _, fn := range callbacks {
+		fn(anchor.DID())
 	}
 }
 
+// derivedKeyAnchor reports whether anchor is a did:key anchor for the key
+// its DID names, which can never change; a did:key anchor carrying another
+// key expires like any other.
+func derivedKeyAnchor(anchor Anchor) bool {
+	if anchor.DID().Method() != "key" {
+		return false
+	}
+
+	pubk, err := PublicKeyFromDID(anchor.DID())
+	if err != nil {
+		return false
+	}
+
+	return publicKeysEqual(pubk, anchor.PublicKey())
+}
+
+// OnAnchorAdded registers a callback invoked, outside the context lock,
+// whenever an anchor is added or resolved into the cache.
+func (ctx *BasicTrustContext) OnAnchorAdded(fn func(DID)) {
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	ctx.onAnchorAdded = append(ctx.onAnchorAdded, fn)
+}
+
+// OnAnchorEvicted registers a callback invoked, outside the context lock,
+// whenever the GC evicts an expired anchor.
+func (ctx *BasicTrustContext) OnAnchorEvicted(fn func(DID)) {
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	ctx.onAnchorEvicted = append(ctx.onAnchorEvicted, fn)
+}
+
 func (ctx *BasicTrustContext) AddProvider(provider Provider) {
 	ctx.mx.Lock()
 	defer ctx.mx.Unlock()
 
-	ctx.providers[provider.DID()] = provider
+	ctx.providers[provider.DID().canonical()] = &providerEntry{provider: provider}
+}
+
+// AddProviderWithTTL adds a provider that is removed by the GC loop once
+// ttl has elapsed.
+func (ctx *BasicTrustContext) AddProviderWithTTL(provider Provider, ttl time.Duration) {
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	ctx.providers[provider.DID().canonical()] = &providerEntry{
+		provider: provider,
+		expire:   monotonicNow().add(ttl),
+	}
+}
+
+// RemoveAnchor drops the cached anchor for did, e.g. when its key has been
+// revoked, or lifts a RevokedAnchor; the next lookup resolves it again.
+// Removing an absent anchor is a no-op.
+func (ctx *BasicTrustContext) RemoveAnchor(did DID) {
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	delete(ctx.anchors, did.canonical())
+	delete(ctx.negative, did.canonical())
+	delete(ctx.documents, did.canonical())
+}
+
+// RemoveProvider drops the provider for did, e.g. when its key has been
+// compromised. Removing an absent provider is a no-op.
+func (ctx *BasicTrustContext) RemoveProvider(did DID) {
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	delete(ctx.providers, did.canonical())
+}
+
+// Clear drops all anchors, documents, cached resolution failures and
+// providers; the GC loop, TTLs and callbacks are left in place.
+func (ctx *BasicTrustContext) Clear() {
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	ctx.anchors = make(map[DID]*anchorEntry)
+	ctx.negative = make(map[DID]*negativeEntry)
+	ctx.documents = make(map[DID]*documentEntry)
+	ctx.providers = make(map[DID]*providerEntry)
+}
+
+// ClearAnchors drops all anchors, documents and cached resolution failures
+// but keeps the providers.
+func (ctx *BasicTrustContext) ClearAnchors() {
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	ctx.anchors = make(map[DID]*anchorEntry)
+	ctx.negative = make(map[DID]*negativeEntry)
+	ctx.documents = make(map[DID]*documentEntry)
 }
 
 func (ctx *BasicTrustContext) Start(gcInterval time.Duration) {
@@ -197,21 +790,77 @@ func (ctx *BasicTrustContext) gc(gcCtx context.Context, gcInterval time.Duration
 	for {
 		select {
 		case <-ticker.C:
-			ctx.gcAnchorEntries()
+			ctx.GC()
 		case <-gcCtx.Done():
 			return
 		}
 	}
 }
 
+// GC evicts expired anchors, documents, cached resolution failures and
+// providers, as the background GC does on every tick; e.g. short-lived
+// tools can call it before a snapshot instead of running Start.
+func (ctx *BasicTrustContext) GC() {
+	ctx.gcAnchorEntries()
+	ctx.gcNegativeEntries()
+	ctx.gcDocumentEntries()
+	ctx.gcProviderEntries()
+}
+
 func (ctx *BasicTrustContext) gcAnchorEntries() {
 	ctx.mx.Lock()
-	defer ctx.mx.Unlock()
-
-	now := time.Now()
+	var evicted []DID
+	now := monotonicNow()
 	for k, e := range ctx.anchors {
-		if e.expire.Before(now) {
+		if !e.permanent && e.expire.before(now) {
 			delete(ctx.anchors, k)
+			evicted = append(evicted, e.anchor.DID())
+		}
+	}
+	callbacks := ctx.onAnchorEvicted
+	ctx.mx.Unlock()
+
+	ctx.evictions.Add(uint64(len(evicted)))
+
+	for _, did := range evicted {
+		for _, fn := range callbacks {
+			fn(did)
+		}
+	}
+}
+
+func (ctx *BasicTrustContext) gcNegativeEntries() {
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	now := monotonicNow()
+	for k, e := range ctx.negative {
+		if e.expire.before(now) {
+			delete(ctx.negative, k)
+		}
+	}
+}
+
+func (ctx *BasicTrustContext) gcDocumentEntries() {
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	now := monotonicNow()
+	for k, e := range ctx.documents {
+		if e.expire.before(now) {
+			delete(ctx.documents, k)
+		}
+	}
+}
+
+func (ctx *BasicTrustContext) gcProviderEntries() {
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	now := monotonicNow()
+	for k, e := range ctx.providers {
+		if e.expired(now) {
+			delete(ctx.providers, k)
 		}
 	}
 }
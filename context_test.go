@@ -9,8 +9,11 @@
 package did
 
 import (
+	"context"
+	"errors"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -32,7 +35,7 @@ func TestTrustContext(t *testing.T) {
 
 	anchor2, err := ctx.GetAnchor(pubDID)
 	require.NoError(t, err, "get key anchor")
-	require.Equal(t, anchor2, anchor, "cached anchor must equal the initial")
+	require.True(t, anchor.(*PublicKeyAnchor).Equal(anchor2), "cached anchor must equal the initial")
 
 	provider, err := ProviderFromPrivateKey(privk)
 	require.NoError(t, err, "provider from public key")
@@ -52,19 +55,24 @@ func TestTrustContextGcRemovesExpiredAnchors(t *testing.T) {
 	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
 	require.NoError(t, err)
 
-	did := FromPublicKey(pubk)
+	did := DID{URI: "did:web:example.com"}
 	anchor := NewAnchor(did, pubk)
 	ctx.AddAnchor(anchor)
 
-	// Force-expire the entry then invoke the internal GC helper.
+	keyDID := FromPublicKey(pubk)
+	ctx.AddAnchor(NewAnchor(keyDID, pubk))
+
+	// Force-expire the entries then run a GC pass.
 	btc := ctx.(*BasicTrustContext)
 	btc.mx.Lock()
-	btc.anchors[did].expire = time.Now().Add(-time.Minute)
+	btc.anchors[did].expire = monotonicNow().add(-time.Minute)
+	btc.anchors[keyDID].expire = monotonicNow().add(-time.Minute)
 	btc.mx.Unlock()
 
-	btc.gcAnchorEntries()
+	ctx.GC()
 
-	require.Empty(t, ctx.Anchors(), "expired anchor should be purged")
+	require.Equal(t, []DID{keyDID}, ctx.Anchors(),
+		"expired anchor should be purged, key anchors are permanent")
 }
 
 func TestTrustContextGetProviderMissing(t *testing.T) {
@@ -77,6 +85,48 @@ func TestTrustContextGetProviderMissing(t *testing.T) {
 	require.ErrorIs(t, err, ErrNoProvider)
 }
 
+func TestTrustContextProviderResolver(t *testing.T) {
+	ctx := NewTrustContext().(*BasicTrustContext)
+
+	privk := mustPrivKey(t)
+	did := FromPublicKey(privk.GetPublic())
+	other := FromPublicKey(mustPrivKey(t).GetPublic())
+
+	calls := 0
+	ctx.SetProviderResolver(func(d DID) (Provider, error) {
+		calls++
+		switch d {
+		case did:
+			return NewProvider(did, privk), nil
+		case other:
+			return NewProvider(did, privk), nil // wrong DID
+		default:
+			return nil, ErrTODO
+		}
+	})
+
+	provider, err := ctx.GetProvider(did)
+	require.NoError(t, err)
+	require.Equal(t, did, provider.DID())
+
+	cached, err := ctx.GetProvider(did)
+	require.NoError(t, err)
+	require.Same(t, provider, cached)
+	require.Equal(t, 1, calls, "resolved provider should be cached")
+	require.Equal(t, []DID{did}, ctx.Providers())
+
+	_, err = ctx.GetProvider(other)
+	require.ErrorIs(t, err, ErrNoProvider)
+
+	unknown := FromPublicKey(mustPrivKey(t).GetPublic())
+	_, err = ctx.GetProvider(unknown)
+	require.ErrorIs(t, err, ErrTODO)
+
+	ctx.SetProviderResolver(nil)
+	_, err = ctx.GetProvider(unknown)
+	require.ErrorIs(t, err, ErrNoProvider)
+}
+
 func TestTrustContextConstructors(t *testing.T) {
 	privk, _, err := crypto.GenerateKeyPair(crypto.Ed25519)
 	require.NoError(t, err)
@@ -119,13 +169,13 @@ func TestTrustContextStartAndAutoGc(t *testing.T) {
 	// Create a disposable anchor and mark it expired.
 	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
 	require.NoError(t, err)
-	did := FromPublicKey(pubk)
+	did := DID{URI: "did:web:example.com"}
 	ctx.AddAnchor(NewAnchor(did, pubk))
 
 	// Force-expire it now.
 	btc := ctx.(*BasicTrustContext)
 	btc.mx.Lock()
-	btc.anchors[did].expire = time.Now().Add(-time.Minute)
+	btc.anchors[did].expire = monotonicNow().add(-time.Minute)
 	btc.mx.Unlock()
 
 	// Start GC with a very short interval.
@@ -152,7 +202,7 @@ func TestTrustContextGetAnchorRefreshesExpiry(t *testing.T) {
 	// Manually age the entry far in the past.
 	btc := ctx.(*BasicTrustContext)
 	btc.mx.Lock()
-	btc.anchors[did].expire = time.Now().Add(-time.Hour)
+	btc.anchors[did].expire = monotonicNow().add(-time.Hour)
 	btc.mx.Unlock()
 
 	// Calling GetAnchor again should *refresh* expire, making it >= now+TTL/2.
@@ -162,7 +212,7 @@ func TestTrustContextGetAnchorRefreshesExpiry(t *testing.T) {
 
 	btc.mx.Lock()
 	defer btc.mx.Unlock()
-	require.True(t, btc.anchors[did].expire.After(time.Now()),
+	require.True(t, monotonicNow().before(btc.anchors[did].expire),
 		"expiry timestamp should be pushed forward by GetAnchor")
 }
 
@@ -192,3 +242,482 @@ func TestTrustContextRestartAndDoubleStop(t *testing.T) {
 	require.LessOrEqual(t, runtime.NumGoroutine(), gBefore+1,
 		"GC goroutine should have exited")
 }
+
+func TestTrustContextProviderTTL(t *testing.T) {
+	ctx := NewTrustContext().(*BasicTrustContext)
+
+	permanent, err := ProviderFromPrivateKey(mustPrivKey(t))
+	require.NoError(t, err)
+	ephemeral, err := ProviderFromPrivateKey(mustPrivKey(t))
+	require.NoError(t, err)
+
+	ctx.AddProvider(permanent)
+	ctx.AddProviderWithTTL(ephemeral, time.Hour)
+
+	_, err = ctx.GetProvider(ephemeral.DID())
+	require.NoError(t, err)
+
+	// Force-expire the ephemeral provider.
+	ctx.mx.Lock()
+	ctx.providers[ephemeral.DID()].expire = monotonicNow().add(-time.Minute)
+	ctx.mx.Unlock()
+
+	_, err = ctx.GetProvider(ephemeral.DID())
+	require.ErrorIs(t, err, ErrNoProvider, "expired provider must not be returned")
+
+	ctx.GC()
+	require.Equal(t, []DID{permanent.DID()}, ctx.Providers())
+}
+
+func mustPrivKey(t *testing.T) crypto.PrivKey {
+	t.Helper()
+	privk, _, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	return privk
+}
+
+func TestTrustContextGetAnchors(t *testing.T) {
+	ctx := NewTrustContext()
+
+	var dids []DID
+	for i := 0; i < 20; i++ {
+		_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+		require.NoError(t, err)
+		dids = append(dids, FromPublicKey(pubk))
+	}
+
+	// pre-cache one anchor; the rest are resolved concurrently
+	cached, err := ctx.GetAnchor(dids[0])
+	require.NoError(t, err)
+
+	bad := DID{URI: "did:web:example.com"}
+	batch := append([]DID{bad, dids[0]}, dids...)
+
+	anchors, errs := ctx.GetAnchors(batch)
+	require.Len(t, anchors, len(dids))
+	require.Len(t, errs, 1)
+	require.ErrorIs(t, errs[bad], ErrNoAnchorMethod)
+	require.Same(t, cached, anchors[dids[0]])
+
+	for _, did := range dids {
+		require.Equal(t, did, anchors[did].DID())
+	}
+	require.Len(t, ctx.Anchors(), len(dids), "resolved anchors must be cached")
+}
+
+func TestTrustContextAnchorEvents(t *testing.T) {
+	ctx := NewTrustContext().(*BasicTrustContext)
+
+	var (
+		mx      sync.Mutex
+		added   []DID
+		evicted []DID
+	)
+	ctx.OnAnchorAdded(func(did DID) {
+		// callbacks run outside the lock, so calling back in must not deadlock
+		_ = ctx.Anchors()
+		mx.Lock()
+		added = append(added, did)
+		mx.Unlock()
+	})
+	ctx.OnAnchorEvicted(func(did DID) {
+		_ = ctx.Anchors()
+		mx.Lock()
+		evicted = append(evicted, did)
+		mx.Unlock()
+	})
+
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	keyDID := FromPublicKey(pubk)
+
+	_, err = ctx.GetAnchor(keyDID)
+	require.NoError(t, err)
+	require.Equal(t, []DID{keyDID}, added)
+
+	did := DID{URI: "did:web:example.com"}
+	ctx.AddAnchor(NewAnchor(did, pubk))
+	require.Equal(t, []DID{keyDID, did}, added)
+
+	ctx.mx.Lock()
+	ctx.anchors[did].expire = monotonicNow().add(-time.Minute)
+	ctx.mx.Unlock()
+
+	ctx.GC()
+	require.Equal(t, []DID{did}, evicted)
+}
+
+func TestTrustContextStats(t *testing.T) {
+	ctx := NewTrustContext().(*BasicTrustContext)
+	require.Equal(t, TrustContextStats{}, ctx.Stats())
+
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	did := FromPublicKey(pubk)
+
+	_, err = ctx.GetAnchor(did) // miss
+	require.NoError(t, err)
+	_, err = ctx.GetAnchor(did) // hit
+	require.NoError(t, err)
+	_, _ = ctx.GetAnchors([]DID{did}) // hit
+
+	prov, err := ProviderFromPrivateKey(mustPrivKey(t))
+	require.NoError(t, err)
+	ctx.AddProvider(prov)
+
+	webDID := DID{URI: "did:web:example.com"}
+	ctx.AddAnchor(NewAnchor(webDID, pubk))
+
+	ctx.mx.Lock()
+	ctx.anchors[webDID].expire = monotonicNow().add(-time.Minute)
+	ctx.mx.Unlock()
+	ctx.GC()
+
+	// expired providers awaiting GC are not counted, as in Providers
+	expired, err := ProviderFromPrivateKey(mustPrivKey(t))
+	require.NoError(t, err)
+	ctx.AddProviderWithTTL(expired, -time.Minute)
+	require.Len(t, ctx.Providers(), 1)
+
+	require.Equal(t, TrustContextStats{
+		Anchors:     1,
+		Providers:   1,
+		CacheHits:   2,
+		CacheMisses: 1,
+		Evictions:   1,
+	}, ctx.Stats())
+}
+
+func TestTrustContextConfigurableTTL(t *testing.T) {
+	ctx := NewTrustContextWithTTL(time.Minute).(*BasicTrustContext)
+	ctx.SetMethodTTL("web", 10*time.Second)
+
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	keyDID := FromPublicKey(pubk)
+	webDID := DID{URI: "did:web:example.com"}
+
+	before := monotonicNow()
+	ctx.AddAnchor(NewAnchor(keyDID, pubk))
+	ctx.AddAnchor(NewAnchor(webDID, pubk))
+
+	ctx.mx.Lock()
+	keyExpire := ctx.anchors[keyDID].expire
+	webExpire := ctx.anchors[webDID].expire
+	ctx.mx.Unlock()
+
+	require.InDelta(t, int64(before.add(time.Minute)), int64(keyExpire), float64(time.Second))
+	require.InDelta(t, int64(before.add(10*time.Second)), int64(webExpire), float64(time.Second))
+}
+
+func TestTrustContextNegativeCache(t *testing.T) {
+	var calls atomic.Int32
+	RegisterAnchorMethod("dead", func(DID) (Anchor, error) {
+		calls.Add(1)
+		return nil, errors.New("unreachable")
+	})
+	t.Cleanup(func() { UnregisterAnchorMethod("dead") })
+
+	ctx := NewTrustContext().(*BasicTrustContext)
+	did := DID{URI: "did:dead:example"}
+
+	_, err := ctx.GetAnchor(did)
+	require.Error(t, err)
+	_, err2 := ctx.GetAnchor(did)
+	require.Equal(t, err, err2)
+	require.EqualValues(t, 1, calls.Load())
+
+	// expired negative entries are retried and collected
+	ctx.SetNegativeTTL(time.Millisecond)
+	_, err = ctx.GetAnchor(did)
+	require.Error(t, err)
+	require.EqualValues(t, 1, calls.Load())
+
+	ctx.mx.Lock()
+	ctx.negative[did].expire = monotonicNow().add(-time.Second)
+	ctx.mx.Unlock()
+
+	_, err = ctx.GetAnchor(did)
+	require.Error(t, err)
+	require.EqualValues(t, 2, calls.Load())
+
+	time.Sleep(5 * time.Millisecond)
+	ctx.GC()
+	ctx.mx.Lock()
+	require.Empty(t, ctx.negative)
+	ctx.mx.Unlock()
+
+	// adding an anchor clears the negative entry
+	_, err = ctx.GetAnchor(did)
+	require.Error(t, err)
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	ctx.AddAnchor(NewAnchor(did, pubk))
+	anchor, err := ctx.GetAnchor(did)
+	require.NoError(t, err)
+	require.Equal(t, did, anchor.DID())
+
+	// disabled negative caching
+	ctx.SetNegativeTTL(0)
+	other := DID{URI: "did:dead:other"}
+	before := calls.Load()
+	_, _ = ctx.GetAnchor(other)
+	_, _ = ctx.GetAnchor(other)
+	require.Equal(t, before+2, calls.Load())
+}
+
+func TestTrustContextAllProvidersAndAnchors(t *testing.T) {
+	ctx := NewTrustContext().(*BasicTrustContext)
+
+	permanent, err := ProviderFromPrivateKey(mustPrivKey(t))
+	require.NoError(t, err)
+	ephemeral, err := ProviderFromPrivateKey(mustPrivKey(t))
+	require.NoError(t, err)
+
+	ctx.AddProvider(permanent)
+	ctx.AddProviderWithTTL(ephemeral, time.Hour)
+	ctx.AddAnchor(permanent.Anchor())
+
+	require.ElementsMatch(t, []Provider{permanent, ephemeral}, ctx.AllProviders())
+	require.Equal(t, []Anchor{permanent.Anchor()}, ctx.AllAnchors())
+
+	ctx.mx.Lock()
+	ctx.providers[ephemeral.DID()].expire = monotonicNow().add(-time.Minute)
+	ctx.mx.Unlock()
+
+	require.Equal(t, []Provider{permanent}, ctx.AllProviders(), "expired provider must not be listed")
+}
+
+func TestTrustContextRemove(t *testing.T) {
+	ctx := NewTrustContext()
+
+	provider, err := ProviderFromPrivateKey(mustPrivKey(t))
+	require.NoError(t, err)
+	ctx.AddProvider(provider)
+	ctx.AddAnchor(provider.Anchor())
+
+	ctx.RemoveProvider(provider.DID())
+	_, err = ctx.GetProvider(provider.DID())
+	require.ErrorIs(t, err, ErrNoProvider)
+
+	ctx.RemoveAnchor(provider.DID())
+	require.Empty(t, ctx.Anchors())
+
+	// removing absent entries is harmless
+	ctx.RemoveProvider(provider.DID())
+	ctx.RemoveAnchor(provider.DID())
+}
+
+func TestTrustContextClear(t *testing.T) {
+	ctx := NewTrustContext().(*BasicTrustContext)
+	ctx.Start(time.Hour)
+	defer ctx.Stop()
+
+	provider, err := ProviderFromPrivateKey(mustPrivKey(t))
+	require.NoError(t, err)
+	ctx.AddProvider(provider)
+	ctx.AddAnchor(provider.Anchor())
+
+	ctx.ClearAnchors()
+	require.Empty(t, ctx.Anchors())
+	require.Equal(t, []DID{provider.DID()}, ctx.Providers())
+
+	ctx.AddAnchor(provider.Anchor())
+	ctx.Clear()
+	require.Empty(t, ctx.Anchors())
+	require.Empty(t, ctx.Providers())
+
+	// the context stays usable after clearing
+	ctx.AddProvider(provider)
+	_, err = ctx.GetProvider(provider.DID())
+	require.NoError(t, err)
+
+	ctx.mx.Lock()
+	require.NotNil(t, ctx.stop, "GC loop must keep running")
+	ctx.mx.Unlock()
+}
+
+func TestTrustContextWarm(t *testing.T) {
+	var inFlight, peak, calls atomic.Int32
+	RegisterAnchorMethod("warm", func(did DID) (Anchor, error) {
+		calls.Add(1)
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		if did.Identifier() == "bad" {
+			return nil, errors.New("unreachable")
+		}
+		return NewAnchor(did, nil), nil
+	})
+	t.Cleanup(func() { UnregisterAnchorMethod("warm") })
+
+	ctx := NewTrustContext().(*BasicTrustContext)
+	ctx.SetResolveConcurrency(2)
+
+	var dids []DID
+	for i := 0; i < 6; i++ {
+		dids = append(dids, DID{URI: "did:warm:" + string(rune('a'+i))})
+	}
+	require.NoError(t, ctx.Warm(context.Background(), append(dids, dids[0])))
+	require.EqualValues(t, 6, calls.Load())
+	require.LessOrEqual(t, peak.Load(), int32(2))
+
+	// warmed anchors are served from the cache
+	for _, did := range dids {
+		_, err := ctx.GetAnchor(did)
+		require.NoError(t, err)
+	}
+	require.EqualValues(t, 6, calls.Load())
+
+	bad := DID{URI: "did:warm:bad"}
+	err := ctx.Warm(context.Background(), []DID{dids[0], bad})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), bad.URI)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = ctx.Warm(cancelled, []DID{{URI: "did:warm:late"}})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestTrustContextClockJump(t *testing.T) {
+	mono := monotonicNow()
+	origMono, origWall := monotonicNow, timeNow
+	t.Cleanup(func() { monotonicNow, timeNow = origMono, origWall })
+	monotonicNow = func() deadline { return mono }
+
+	ctx := NewTrustContextWithTTL(time.Minute).(*BasicTrustContext)
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	did := DID{URI: "did:web:example.com"}
+	ctx.AddAnchor(NewAnchor(did, pubk))
+	ctx.AddProviderWithTTL(NewProvider(did, mustPrivKey(t)), time.Minute)
+
+	// wall-clock steps in either direction do not expire or extend entries
+	for _, jump := range []time.Duration{24 * time.Hour, -24 * time.Hour} {
+		wall := time.Now().Add(jump).Round(0)
+		timeNow = func() time.Time { return wall }
+		ctx.GC()
+		require.Equal(t, []DID{did}, ctx.Anchors())
+		require.Equal(t, []DID{did}, ctx.Providers())
+	}
+
+	mono = mono.add(30 * time.Second)
+	ctx.GC()
+	require.Equal(t, []DID{did}, ctx.Anchors())
+
+	mono = mono.add(31 * time.Second)
+	ctx.GC()
+	require.Empty(t, ctx.Anchors())
+	require.Empty(t, ctx.Providers())
+}
+
+// fetchingDocumentAnchor counts the documents it produces, like a did:web
+// anchor fetching its document.
+type fetchingDocumentAnchor struct {
+	*PublicKeyAnchor
+	doc     *Document
+	fetches atomic.Int32
+}
+
+func (a *fetchingDocumentAnchor) Document() (*Document, error) {
+	a.fetches.Add(1)
+	return a.doc, nil
+}
+
+func TestTrustContextDocumentCache(t *testing.T) {
+	mono := monotonicNow()
+	orig := monotonicNow
+	t.Cleanup(func() { monotonicNow = orig })
+	monotonicNow = func() deadline { return mono }
+
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	did := DID{URI: "did:web:docs.example.com"}
+	doc, err := NewDocument(did, pubk)
+	require.NoError(t, err)
+	doc.Controller = []string{"did:web:controller.example.com"}
+	anchor := &fetchingDocumentAnchor{PublicKeyAnchor: &PublicKeyAnchor{did: did, pubk: pubk}, doc: doc}
+
+	ctx := NewTrustContextWithTTL(time.Hour).(*BasicTrustContext)
+	ctx.AddAnchor(anchor)
+
+	// documents are not cached by default
+	_, err = ctx.GetDocument(did)
+	require.NoError(t, err)
+	_, err = ctx.GetDocument(did)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), anchor.fetches.Load())
+
+	ctx.SetDocumentTTL(time.Minute)
+	got, err := ctx.GetDocument(did)
+	require.NoError(t, err)
+	require.Same(t, doc, got)
+
+	controllers, err := ctx.Controllers(did)
+	require.NoError(t, err)
+	require.Equal(t, []DID{{URI: "did:web:controller.example.com"}}, controllers)
+	vm, err := ctx.Dereference(doc.VerificationMethod[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, doc.VerificationMethod[0].ID, vm.ID)
+	require.Equal(t, int32(3), anchor.fetches.Load(), "cached document is reused")
+
+	// document lookups are counted apart from anchor lookups
+	stats := ctx.Stats()
+	require.Equal(t, uint64(2), stats.DocumentHits)
+	require.Equal(t, uint64(3), stats.DocumentMisses)
+	require.Equal(t, uint64(4), stats.CacheHits, "one per document miss and one for Controllers")
+	require.Zero(t, stats.CacheMisses)
+
+	// the document expires on its own TTL while the anchor stays cached
+	mono = mono.add(2 * time.Minute)
+	ctx.GC()
+	require.Equal(t, 1, ctx.Stats().Anchors)
+	_, err = ctx.GetDocument(did)
+	require.NoError(t, err)
+	require.Equal(t, int32(4), anchor.fetches.Load())
+
+	// replacing or removing the anchor drops its document
+	ctx.AddAnchor(anchor)
+	_, err = ctx.GetDocument(did)
+	require.NoError(t, err)
+	require.Equal(t, int32(5), anchor.fetches.Load())
+	ctx.RemoveAnchor(did)
+	_, err = ctx.GetDocument(did)
+	require.Error(t, err)
+
+	// anchors without a document get one from their key
+	keyDID := FromPublicKey(pubk)
+	got, err = ctx.GetDocument(keyDID)
+	require.NoError(t, err)
+	require.Equal(t, keyDID.URI, got.ID)
+}
+
+func TestTrustContextKeyAnchorPermanence(t *testing.T) {
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	_, otherPubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	did := FromPublicKey(pubk)
+
+	ctx := NewTrustContext().(*BasicTrustContext)
+	ctx.AddAnchor(NewAnchor(did, pubk))
+	require.True(t, ctx.anchors[did].permanent)
+
+	// a did:key anchor carrying a key other than the DID's expires
+	ctx.AddAnchor(NewAnchor(did, otherPubk))
+	require.False(t, ctx.anchors[did].permanent)
+
+	ctx.mx.Lock()
+	ctx.anchors[did].expire = monotonicNow().add(-time.Minute)
+	ctx.mx.Unlock()
+	ctx.GC()
+	require.Empty(t, ctx.Anchors())
+}
@@ -0,0 +1,88 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"fmt"
+)
+
+// DocumentAnchor is implemented by anchors backed by a DID Document.
+type DocumentAnchor interface {
+	Document() (*Document, error)
+}
+
+// Controllers resolves did and returns the controllers declared in its DID
+// Document. A DID whose document declares no controller, and any did:key,
+// is self-controlled and the DID itself is returned.
+func Controllers(did DID) ([]DID, error) {
+	anchor, err := GetAnchorForDID(did)
+	if err != nil {
+		return nil, fmt.Errorf("get anchor for did: %w", err)
+	}
+
+	return anchorControllers(anchor)
+}
+
+// Controllers is like the package-level Controllers, but uses the context's
+// cached anchor, and its cached document with SetDocumentTTL, so repeated
+// checks do not resolve the DID again.
+func (ctx *BasicTrustContext) Controllers(did DID) ([]DID, error) {
+	anchor, err := ctx.GetAnchor(did)
+	if err != nil {
+		return nil, fmt.Errorf("get anchor for did: %w", err)
+	}
+
+	if !hasControllers(anchor) {
+		return []DID{anchor.DID()}, nil
+	}
+
+	doc, err := ctx.GetDocument(did)
+	if err != nil {
+		return nil, err
+	}
+
+	return documentControllers(anchor.DID(), doc)
+}
+
+func anchorControllers(anchor Anchor) ([]DID, error) {
+	if !hasControllers(anchor) {
+		return []DID{anchor.DID()}, nil
+	}
+
+	doc, err := anchor.(DocumentAnchor).Document()
+	if err != nil {
+		return nil, fmt.Errorf("anchor document: %w", err)
+	}
+
+	return documentControllers(anchor.DID(), doc)
+}
+
+// hasControllers reports whether the anchor's document may declare
+// controllers; did:key and anchors without a document are self-controlled.
+func hasControllers(anchor Anchor) bool {
+	_, ok := anchor.(DocumentAnchor)
+	return ok && anchor.DID().Method() != "key"
+}
+
+func documentControllers(did DID, doc *Document) ([]DID, error) {
+	if len(doc.Controller) == 0 {
+		return []DID{did}, nil
+	}
+
+	controllers := make([]DID, 0, len(doc.Controller))
+	for _, uri := range doc.Controller {
+		controller, err := FromString(uri)
+		if err != nil {
+			return nil, fmt.Errorf("controller: %w", err)
+		}
+		controllers = append(controllers, controller)
+	}
+
+	return controllers, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+type controlledAnchor struct {
+	*PublicKeyAnchor
+	controllers []string
+}
+
+func (a *controlledAnchor) Document() (*Document, error) {
+	doc, err := a.PublicKeyAnchor.Document()
+	if err != nil {
+		return nil, err
+	}
+	doc.Controller = a.controllers
+	return doc, nil
+}
+
+func TestControllers(t *testing.T) {
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+
+	keyDID := FromPublicKey(pubk)
+	controllers, err := Controllers(keyDID)
+	require.NoError(t, err)
+	require.Equal(t, []DID{keyDID}, controllers)
+
+	webDID, err := FromString("did:web:example.com")
+	require.NoError(t, err)
+	anchor := &controlledAnchor{
+		PublicKeyAnchor: &PublicKeyAnchor{did: webDID, pubk: pubk},
+		controllers:     []string{keyDID.URI},
+	}
+
+	ctx := NewTrustContext().(*BasicTrustContext)
+	ctx.AddAnchor(anchor)
+
+	controllers, err = ctx.Controllers(webDID)
+	require.NoError(t, err)
+	require.Equal(t, []DID{keyDID}, controllers)
+
+	// a document without a controller is self-controlled
+	anchor.controllers = nil
+	controllers, err = ctx.Controllers(webDID)
+	require.NoError(t, err)
+	require.Equal(t, []DID{webDID}, controllers)
+
+	anchor.controllers = []string{"not-a-did"}
+	_, err = ctx.Controllers(webDID)
+	require.ErrorIs(t, err, ErrInvalidDID)
+}
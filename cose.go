@@ -0,0 +1,149 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"fmt"
+	"math/big"
+
+	"github.com/fxamacker/cbor/v2"
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+
+	"github.com/depinkit/crypto"
+)
+
+// COSE_Key labels and values (RFC 9052, RFC 9053).
+const (
+	coseKeyKty = 1
+	coseKeyAlg = 3
+	coseKeyCrv = -1
+	coseKeyX   = -2
+	coseKeyY   = -3
+
+	coseKtyEC2   = 2
+	coseAlgES256 = -7
+	coseCrvP256  = 1
+)
+
+// coseDecMode rejects duplicate map keys, so a key cannot carry two
+// different coordinates or algorithms.
+var coseDecMode = func() cbor.DecMode {
+	mode, err := cbor.DecOptions{DupMapKey: cbor.DupMapKeyEnforcedAPF}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+	return mode
+}()
+
+// FromCOSEKey decodes a CBOR COSE_Key, as returned in WebAuthn attested
+// credential data, and returns the public key with its did:key DID. Only
+// EC2 P-256 keys are supported; alg, if present, must be ES256. The y
+// coordinate may be given in full or, for compressed points, as its sign
+// bit.
+func FromCOSEKey(cose []byte) (DID, crypto.PubKey, error) {
+	var key map[int]cbor.RawMessage
+	if err := coseDecMode.Unmarshal(cose, &key); err != nil {
+		return DID{}, nil, fmt.Errorf("parse cose key: %w", err)
+	}
+
+	var kty, crv int
+	if err := coseKeyField(key, coseKeyKty, &kty); err != nil {
+		return DID{}, nil, err
+	}
+	if kty != coseKtyEC2 {
+		return DID{}, nil, fmt.Errorf("unsupported cose kty %d: %w", kty, ErrInvalidKeyType)
+	}
+
+	if _, ok := key[coseKeyAlg]; ok {
+		var alg int
+		if err := coseKeyField(key, coseKeyAlg, &alg); err != nil {
+			return DID{}, nil, err
+		}
+		if alg != coseAlgES256 {
+			return DID{}, nil, fmt.Errorf("unsupported cose alg %d: %w", alg, ErrAlgorithmMismatch)
+		}
+	}
+
+	if err := coseKeyField(key, coseKeyCrv, &crv); err != nil {
+		return DID{}, nil, err
+	}
+	if crv != coseCrvP256 {
+		return DID{}, nil, fmt.Errorf("unsupported cose crv %d: %w", crv, ErrInvalidKeyType)
+	}
+
+	var x []byte
+	if err := coseKeyField(key, coseKeyX, &x); err != nil {
+		return DID{}, nil, err
+	}
+	if len(x) != 32 {
+		return DID{}, nil, fmt.Errorf("invalid cose x coordinate length %d: %w", len(x), ErrInvalidKeyType)
+	}
+
+	pubk, err := coseP256Key(x, key[coseKeyY])
+	if err != nil {
+		return DID{}, nil, err
+	}
+
+	did := FromPublicKey(pubk)
+	if did.Empty() {
+		return DID{}, nil, ErrInvalidKeyType
+	}
+
+	return did, pubk, nil
+}
+
+// coseP256Key reconstructs a P-256 key from x and the encoded y, which is
+// either the 32-byte coordinate or the sign bit of a compressed point.
+func coseP256Key(x []byte, rawY cbor.RawMessage) (crypto.PubKey, error) {
+	if rawY == nil {
+		return nil, fmt.Errorf("missing cose key label %d: %w", coseKeyY, ErrInvalidKeyType)
+	}
+
+	curve := elliptic.P256()
+
+	var sign bool
+	if err := coseDecMode.Unmarshal(rawY, &sign); err == nil {
+		prefix := byte(0x02)
+		if sign {
+			prefix = 0x03
+		}
+		return unmarshalECDSACompressedKey(curve, append([]byte{prefix}, x...))
+	}
+
+	var y []byte
+	if err := coseDecMode.Unmarshal(rawY, &y); err != nil {
+		return nil, fmt.Errorf("decode cose key label %d: %w: %w", coseKeyY, ErrInvalidKeyType, err)
+	}
+	if len(y) != 32 {
+		return nil, fmt.Errorf("invalid cose y coordinate length %d: %w", len(y), ErrInvalidKeyType)
+	}
+
+	px, py := new(big.Int).SetBytes(x), new(big.Int).SetBytes(y)
+	if !curve.IsOnCurve(px, py) {
+		return nil, fmt.Errorf("cose point is not on P-256: %w", ErrInvalidKeyType)
+	}
+
+	return libp2p_crypto.ECDSAPublicKeyFromPubKey(ecdsa.PublicKey{Curve: curve, X: px, Y: py})
+}
+
+// coseKeyField decodes the required label of a COSE_Key into v.
+func coseKeyField(key map[int]cbor.RawMessage, label int, v any) error {
+	raw, ok := key[label]
+	if !ok {
+		return fmt.Errorf("missing cose key label %d: %w", label, ErrInvalidKeyType)
+	}
+
+	if err := coseDecMode.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("decode cose key label %d: %w: %w", label, ErrInvalidKeyType, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromCOSEKey(t *testing.T) {
+	std, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	expected, err := libp2p_crypto.ECDSAPublicKeyFromPubKey(std.PublicKey)
+	require.NoError(t, err)
+
+	x := std.X.FillBytes(make([]byte, 32))
+	y := std.Y.FillBytes(make([]byte, 32))
+	encode := func(key map[int]any) []byte {
+		data, err := cbor.Marshal(key)
+		require.NoError(t, err)
+		return data
+	}
+
+	for name, key := range map[string]map[int]any{
+		"uncompressed": {1: 2, 3: -7, -1: 1, -2: x, -3: y},
+		"compressed":   {1: 2, 3: -7, -1: 1, -2: x, -3: std.Y.Bit(0) == 1},
+		"no alg":       {1: 2, -1: 1, -2: x, -3: y},
+	} {
+		t.Run(name, func(t *testing.T) {
+			did, pubk, err := FromCOSEKey(encode(key))
+			require.NoError(t, err)
+			require.True(t, expected.Equals(pubk))
+			require.Equal(t, FromPublicKey(expected), did)
+			require.Equal(t, AlgES256, keyAlgorithm(pubk))
+		})
+	}
+
+	offCurve := append([]byte(nil), y...)
+	offCurve[31] ^= 1
+
+	for name, tc := range map[string]struct {
+		cose []byte
+		err  error
+	}{
+		"kty":       {encode(map[int]any{1: 1, -1: 1, -2: x, -3: y}), ErrInvalidKeyType},
+		"alg":       {encode(map[int]any{1: 2, 3: -8, -1: 1, -2: x, -3: y}), ErrAlgorithmMismatch},
+		"crv":       {encode(map[int]any{1: 2, 3: -7, -1: 2, -2: x, -3: y}), ErrInvalidKeyType},
+		"no y":      {encode(map[int]any{1: 2, 3: -7, -1: 1, -2: x}), ErrInvalidKeyType},
+		"short x":   {encode(map[int]any{1: 2, 3: -7, -1: 1, -2: x[1:], -3: y}), ErrInvalidKeyType},
+		"off curve": {encode(map[int]any{1: 2, 3: -7, -1: 1, -2: x, -3: offCurve}), ErrInvalidKeyType},
+		"y type":    {encode(map[int]any{1: 2, 3: -7, -1: 1, -2: x, -3: "y"}), ErrInvalidKeyType},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := FromCOSEKey(tc.cose)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+
+	// {1: 2, 1: 2}: duplicate labels are rejected
+	_, _, err = FromCOSEKey([]byte{0xa2, 0x01, 0x02, 0x01, 0x02})
+	require.Error(t, err)
+}
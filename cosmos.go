@@ -0,0 +1,327 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpECDSA "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/crypto/pb"
+	"golang.org/x/crypto/ripemd160" //nolint:staticcheck // Cosmos addresses are defined over RIPEMD-160
+
+	"github.com/depinkit/crypto"
+)
+
+const (
+	pkhNamespaceCosmos = "cosmos"
+
+	cosmosAddressSize = 20
+)
+
+// CosmosPKHAnchor verifies secp256k1 signatures for a Cosmos SDK did:pkh
+// account, did:pkh:cosmos:<chain id>:<bech32 address>. As with PKHAnchor,
+// the signer key is recovered from each signature and its address
+// compared; PublicKey is nil until a signature has been verified.
+type CosmosPKHAnchor struct {
+	did     DID
+	hrp     string
+	address []byte
+
+	mx   sync.Mutex
+	pubk crypto.PubKey
+}
+
+var _ Anchor = (*CosmosPKHAnchor)(nil)
+
+// NewCosmosPKHAnchor creates an anchor for a cosmos did:pkh DID.
+func NewCosmosPKHAnchor(did DID) (*CosmosPKHAnchor, error) {
+	namespace, _, address, ok := pkhParts(did)
+	if !ok || namespace != pkhNamespaceCosmos {
+		return nil, fmt.Errorf("%w: %s is not a cosmos did:pkh", ErrInvalidDID, did)
+	}
+
+	hrp, addr, err := decodeCosmosAddress(address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDID, err)
+	}
+
+	return &CosmosPKHAnchor{did: did, hrp: hrp, address: addr}, nil
+}
+
+// FromCosmosPublicKey returns the did:pkh:cosmos:<chainID> DID of the
+// account with the bech32 prefix hrp (e.g. "cosmos" or "osmo") for a
+// secp256k1 key.
+func FromCosmosPublicKey(pubk crypto.PubKey, hrp, chainID string) (DID, error) {
+	if pubk.Type() != crypto.Secp256k1 {
+		return DID{}, fmt.Errorf("key type %d is not a cosmos key: %w", pubk.Type(), ErrInvalidKeyType)
+	}
+	if chainID == "" || strings.Contains(chainID, ":") {
+		return DID{}, fmt.Errorf("%w: invalid cosmos chain id %q", ErrInvalidDID, chainID)
+	}
+
+	raw, err := pubk.Raw()
+	if err != nil {
+		return DID{}, fmt.Errorf("raw public key: %w", err)
+	}
+
+	address, err := bech32Encode(hrp, cosmosAddress(raw))
+	if err != nil {
+		return DID{}, fmt.Errorf("%w: %w", ErrInvalidDID, err)
+	}
+
+	return DID{URI: strings.Join([]string{"did", pkhMethod, pkhNamespaceCosmos, chainID, address}, ":")}, nil
+}
+
+func (a *CosmosPKHAnchor) DID() DID {
+	return a.did
+}
+
+func (a *CosmosPKHAnchor) Verify(data []byte, sig []byte) error {
+	_, err := a.VerifyRecover(data, sig)
+	return err
+}
+
+// VerifyRecover verifies a signature over the SHA-256 digest of data and
+// returns the signer key; the key is cached and returned by PublicKey
+// afterwards. The signature is either the 64-byte R || S form Cosmos SDK
+// uses or DER, as made by did:key secp256k1 providers; high-S signatures
+// are rejected.
+func (a *CosmosPKHAnchor) VerifyRecover(data []byte, sig []byte) (crypto.PubKey, error) {
+	var r, s secp256k1.ModNScalar
+	if len(sig) == 64 {
+		if r.SetByteSlice(sig[:32]) || s.SetByteSlice(sig[32:]) || r.IsZero() || s.IsZero() {
+			return nil, fmt.Errorf("signature scalar out of range: %w", ErrMalformedSignature)
+		}
+	} else {
+		parsed, err := secpECDSA.ParseDERSignature(sig)
+		if err != nil {
+			return nil, fmt.Errorf("parse signature: %w: %w", ErrMalformedSignature, err)
+		}
+		r, s = parsed.R(), parsed.S()
+	}
+
+	if s.IsOverHalfOrder() {
+		return nil, fmt.Errorf("non-canonical high-S signature: %w", ErrInvalidSignature)
+	}
+
+	hash := sha256.Sum256(data)
+
+	// the signature carries no recovery id, so try both candidates
+	compact := make([]byte, 65)
+	r.PutBytesUnchecked(compact[1:33])
+	s.PutBytesUnchecked(compact[33:])
+	for _, v := range []byte{27, 28} {
+		compact[0] = v
+		recovered, _, err := secpECDSA.RecoverCompact(compact, hash[:])
+		if err != nil {
+			continue
+		}
+
+		raw := recovered.SerializeCompressed()
+		if !bytes.Equal(cosmosAddress(raw), a.address) {
+			continue
+		}
+
+		pubk, err := libp2p_crypto.UnmarshalSecp256k1PublicKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("recovered public key: %w", err)
+		}
+
+		a.mx.Lock()
+		if a.pubk == nil {
+			a.pubk = pubk
+		}
+		a.mx.Unlock()
+
+		return pubk, nil
+	}
+
+	return nil, ErrInvalidSignature
+}
+
+// PublicKey returns the signer key recovered by the first successful
+// verification, or nil.
+func (a *CosmosPKHAnchor) PublicKey() crypto.PubKey {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	return a.pubk
+}
+
+func (a *CosmosPKHAnchor) KeyType() pb.KeyType {
+	return crypto.Secp256k1
+}
+
+func (a *CosmosPKHAnchor) Algorithm() string {
+	return AlgES256K
+}
+
+// cosmosAddress returns the 20-byte Cosmos SDK account address of a
+// compressed secp256k1 public key: RIPEMD160(SHA256(key)).
+func cosmosAddress(compressed []byte) []byte {
+	digest := sha256.Sum256(compressed)
+	hasher := ripemd160.New()
+	hasher.Write(digest[:])
+	return hasher.Sum(nil)
+}
+
+// decodeCosmosAddress decodes a bech32 account address into its prefix and
+// 20-byte address.
+func decodeCosmosAddress(address string) (string, []byte, error) {
+	hrp, addr, err := bech32Decode(address)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(addr) != cosmosAddressSize {
+		return "", nil, fmt.Errorf("cosmos address %q has length %d, expected %d", address, len(addr), cosmosAddressSize)
+	}
+
+	return hrp, addr, nil
+}
+
+// Bech32 (BIP-173) encoding.
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+var bech32Generator = [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+func bech32Polymod(values []byte) uint32 {
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i, g := range bech32Generator {
+			if (top>>i)&1 == 1 {
+				chk ^= g
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	out := make([]byte, 0, 2*len(hrp)+1)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
+
+// bech32Encode encodes data with the human-readable prefix hrp.
+func bech32Encode(hrp string, data []byte) (string, error) {
+	if hrp == "" || hrp != strings.ToLower(hrp) {
+		return "", fmt.Errorf("invalid bech32 prefix %q", hrp)
+	}
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", fmt.Errorf("invalid bech32 prefix %q", hrp)
+		}
+	}
+
+	values := convertBits(data, 8, 5, true)
+	polymod := bech32Polymod(append(append(bech32HRPExpand(hrp), values...), 0, 0, 0, 0, 0, 0)) ^ 1
+
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, v := range values {
+		sb.WriteByte(bech32Charset[v])
+	}
+	for i := 0; i < 6; i++ {
+		sb.WriteByte(bech32Charset[(polymod>>(5*(5-i)))&31])
+	}
+
+	return sb.String(), nil
+}
+
+// bech32Decode decodes and checksums a bech32 string, returning its
+// lowercased prefix and data.
+func bech32Decode(s string) (string, []byte, error) {
+	if len(s) > 90 {
+		return "", nil, fmt.Errorf("bech32 string too long")
+	}
+
+	lower := strings.ToLower(s)
+	if s != lower && s != strings.ToUpper(s) {
+		return "", nil, fmt.Errorf("mixed-case bech32 string %q", s)
+	}
+
+	sep := strings.LastIndexByte(lower, '1')
+	if sep < 1 || sep+7 > len(lower) {
+		return "", nil, fmt.Errorf("invalid bech32 separator in %q", s)
+	}
+
+	hrp := lower[:sep]
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", nil, fmt.Errorf("invalid bech32 prefix %q", hrp)
+		}
+	}
+
+	values := make([]byte, 0, len(lower)-sep-1)
+	for i := sep + 1; i < len(lower); i++ {
+		v := strings.IndexByte(bech32Charset, lower[i])
+		if v < 0 {
+			return "", nil, fmt.Errorf("invalid bech32 character %q", lower[i])
+		}
+		values = append(values, byte(v))
+	}
+
+	if bech32Polymod(append(bech32HRPExpand(hrp), values...)) != 1 {
+		return "", nil, fmt.Errorf("invalid bech32 checksum in %q", s)
+	}
+
+	data := convertBits(values[:len(values)-6], 5, 8, false)
+	if data == nil {
+		return "", nil, fmt.Errorf("invalid bech32 padding in %q", s)
+	}
+
+	return hrp, data, nil
+}
+
+// convertBits regroups data from groups of from bits into groups of to
+// bits. Without pad, leftover bits must be zero padding of less than a
+// group, or nil is returned.
+func convertBits(data []byte, from, to uint, pad bool) []byte {
+	var (
+		acc  uint32
+		bits uint
+		out  = make([]byte, 0, len(data)*int(from)/int(to)+1)
+		max  = uint32(1)<<to - 1
+	)
+	for _, v := range data {
+		acc = acc<<from | uint32(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&max))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(to-bits)&max))
+		}
+	} else if bits >= from || acc<<(to-bits)&max != 0 {
+		return nil
+	}
+
+	return out
+}
@@ -0,0 +1,148 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpECDSA "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+func TestFromCosmosPublicKey(t *testing.T) {
+	// the generator point, i.e. private key 1
+	var one [32]byte
+	one[31] = 1
+	pubk, err := libp2p_crypto.UnmarshalSecp256k1PublicKey(secp256k1.PrivKeyFromBytes(one[:]).PubKey().SerializeCompressed())
+	require.NoError(t, err)
+
+	did, err := FromCosmosPublicKey(pubk, "cosmos", "cosmoshub-4")
+	require.NoError(t, err)
+	require.Equal(t, "did:pkh:cosmos:cosmoshub-4:cosmos1w508d6qejxtdg4y5r3zarvary0c5xw7k6ah60c", did.URI)
+
+	_, edPub, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	_, err = FromCosmosPublicKey(edPub, "cosmos", "cosmoshub-4")
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+
+	_, err = FromCosmosPublicKey(pubk, "Cosmos", "cosmoshub-4")
+	require.ErrorIs(t, err, ErrInvalidDID)
+	_, err = FromCosmosPublicKey(pubk, "cosmos", "")
+	require.ErrorIs(t, err, ErrInvalidDID)
+}
+
+func TestCosmosPKHAnchor(t *testing.T) {
+	sk, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	privk, err := libp2p_crypto.UnmarshalSecp256k1PrivateKey(sk.Serialize())
+	require.NoError(t, err)
+
+	did, err := FromCosmosPublicKey(privk.GetPublic(), "osmo", "osmosis-1")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(did.URI, "did:pkh:cosmos:osmosis-1:osmo1"))
+
+	anchor, err := GetAnchorForDID(did)
+	require.NoError(t, err)
+	require.IsType(t, &CosmosPKHAnchor{}, anchor)
+	require.Nil(t, anchor.PublicKey())
+	require.Equal(t, AlgES256K, anchor.Algorithm())
+
+	data := []byte("cosmos sign doc")
+
+	// DER signatures, as made by did:key providers
+	sig, err := NewProvider(did, privk).Sign(data)
+	require.NoError(t, err)
+	require.NoError(t, anchor.Verify(data, sig))
+	require.True(t, anchor.PublicKey().Equals(privk.GetPublic()))
+
+	// 64-byte R || S signatures, as made by Cosmos SDK wallets
+	hash := sha256.Sum256(data)
+	parsed := secpECDSA.Sign(sk, hash[:])
+	r, s := parsed.R(), parsed.S()
+	compact := make([]byte, 64)
+	r.PutBytesUnchecked(compact[:32])
+	s.PutBytesUnchecked(compact[32:])
+	require.NoError(t, anchor.Verify(data, compact))
+
+	require.ErrorIs(t, anchor.Verify([]byte("other"), compact), ErrInvalidSignature)
+	require.ErrorIs(t, anchor.Verify(data, []byte("garbage")), ErrMalformedSignature)
+
+	var highS secp256k1.ModNScalar
+	highS.NegateVal(&s)
+	highS.PutBytesUnchecked(compact[32:])
+	require.ErrorIs(t, anchor.Verify(data, compact), ErrInvalidSignature)
+
+	other, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	otherSig := secpECDSA.Sign(other, hash[:])
+	require.ErrorIs(t, anchor.Verify(data, otherSig.Serialize()), ErrInvalidSignature)
+
+	// bech32 addresses are case-insensitive
+	upper := DID{URI: "did:pkh:cosmos:osmosis-1:" + strings.ToUpper(strings.TrimPrefix(did.URI, "did:pkh:cosmos:osmosis-1:"))}
+	require.True(t, did.CanonicalEqual(upper))
+	upperAnchor, err := GetAnchorForDID(upper)
+	require.NoError(t, err)
+	require.NoError(t, upperAnchor.Verify(data, sig))
+}
+
+func TestNewCosmosPKHAnchorInvalid(t *testing.T) {
+	for _, uri := range []string{
+		"did:pkh:eip155:1:0xab16a96d359ec26a11e2c2b3d8f8b8942d5bfcdb",
+		"did:pkh:cosmos:cosmoshub-4:cosmos1w508d6qejxtdg4y5r3zarvary0c5xw7k6ah60d", // checksum
+		"did:pkh:cosmos:cosmoshub-4:Cosmos1w508d6qejxtdg4y5r3zarvary0c5xw7k6ah60c", // mixed case
+		"did:pkh:cosmos:cosmoshub-4:a12uel5l",                                      // not 20 bytes
+	} {
+		_, err := NewCosmosPKHAnchor(DID{URI: uri})
+		require.ErrorIs(t, err, ErrInvalidDID, uri)
+	}
+}
+
+func TestBech32Vectors(t *testing.T) {
+	// BIP-173 test vectors
+	for _, s := range []string{
+		"A12UEL5L",
+		"a12uel5l",
+		"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+		"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+		"?1ezyfcl",
+	} {
+		_, _, err := bech32Decode(s)
+		require.NoError(t, err, s)
+	}
+
+	for _, s := range []string{
+		"\x201nwldj5",
+		"an84characterslonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1569pvx",
+		"pzry9x0s0muk",
+		"1pzry9x0s0muk",
+		"x1b4n0q5v",
+		"li1dgmt3",
+		"A1G7SGD8",
+		"10a06t8",
+		"1qzzfhee",
+	} {
+		_, _, err := bech32Decode(s)
+		require.Error(t, err, s)
+	}
+
+	data := []byte{0, 1, 2, 0xfe, 0xff}
+	encoded, err := bech32Encode("test", data)
+	require.NoError(t, err)
+	hrp, decoded, err := bech32Decode(encoded)
+	require.NoError(t, err)
+	require.Equal(t, "test", hrp)
+	require.Equal(t, data, decoded)
+}
@@ -0,0 +1,102 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dereference dereferences a DID URL with a fragment, such as
+// did:web:example.com#key-2, to the verification method it names: the DID
+// is resolved, its DID Document produced and the method with the matching
+// ID returned. Methods embedded in a verification relationship are found
+// too. DID URLs with a path or query are not supported.
+func Dereference(didURL string) (*VerificationMethod, error) {
+	return dereference(didURL, func(did DID) (*Document, error) {
+		anchor, err := GetAnchorForDID(did)
+		if err != nil {
+			return nil, err
+		}
+		return anchorDocument(anchor)
+	})
+}
+
+// Dereference is like the package-level Dereference, but uses the
+// context's cached anchor, and its cached document with SetDocumentTTL.
+func (ctx *BasicTrustContext) Dereference(didURL string) (*VerificationMethod, error) {
+	return dereference(didURL, ctx.GetDocument)
+}
+
+func dereference(didURL string, document func(DID) (*Document, error)) (*VerificationMethod, error) {
+	parsed, err := FromString(didURL)
+	if err != nil {
+		return nil, err
+	}
+
+	base, fragment, ok := strings.Cut(parsed.URI, "#")
+	if !ok || fragment == "" {
+		return nil, &DIDError{Op: "dereference", DID: didURL, Err: fmt.Errorf("%w: missing fragment", ErrInvalidDID)}
+	}
+	if strings.ContainsAny(base, "/?") {
+		return nil, &DIDError{Op: "dereference", DID: didURL, Err: fmt.Errorf("%w: unsupported DID URL path or query", ErrInvalidDID)}
+	}
+
+	doc, err := document(DID{URI: base})
+	if err != nil {
+		return nil, &DIDError{Op: "dereference", DID: didURL, Err: err}
+	}
+
+	vm, ok := doc.findMethod(doc.ID + "#" + fragment)
+	if !ok {
+		return nil, &DIDError{Op: "dereference", DID: didURL, Err: ErrNoVerificationMethod}
+	}
+
+	return vm, nil
+}
+
+// anchorDocument returns the anchor's DID Document, producing one from its
+// public key for anchors that are not backed by a document.
+func anchorDocument(anchor Anchor) (*Document, error) {
+	if docAnchor, ok := anchor.(DocumentAnchor); ok {
+		return docAnchor.Document()
+	}
+
+	pubk := anchor.PublicKey()
+	if pubk == nil {
+		return nil, fmt.Errorf("anchor for %s has no public key: %w", anchor.DID(), ErrInvalidKeyType)
+	}
+
+	return NewDocument(anchor.DID(), pubk)
+}
+
+// findMethod returns a copy of the verification method with the absolute
+// ID id, looking at verificationMethod first and then at methods embedded
+// in the verification relationships.
+func (doc *Document) findMethod(id string) (*VerificationMethod, bool) {
+	for _, vm := range doc.VerificationMethod {
+		if doc.absoluteID(vm.ID) == id {
+			return &vm, true
+		}
+	}
+
+	for _, rel := range []string{
+		RelationshipAuthentication,
+		RelationshipAssertionMethod,
+		RelationshipKeyAgreement,
+		RelationshipCapabilityInvocation,
+		RelationshipCapabilityDelegation,
+	} {
+		if vm, ok := doc.embedded[rel][id]; ok {
+			return &vm, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,87 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+type parsedDocumentAnchor struct {
+	*PublicKeyAnchor
+	doc *Document
+}
+
+func (a *parsedDocumentAnchor) Document() (*Document, error) {
+	return a.doc, nil
+}
+
+func TestDereference(t *testing.T) {
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	keyDID := FromPublicKey(pubk)
+	multibaseKey, err := keyMultibase(pubk)
+	require.NoError(t, err)
+
+	vm, err := Dereference(keyDID.URI + "#" + multibaseKey)
+	require.NoError(t, err)
+	require.Equal(t, keyDID.URI+"#"+multibaseKey, vm.ID)
+	require.Equal(t, Ed25519VerificationKey2020, vm.Type)
+	require.Equal(t, keyDID.URI, vm.Controller)
+	got, err := vm.PublicKey()
+	require.NoError(t, err)
+	require.True(t, pubk.Equals(got))
+
+	_, err = Dereference(keyDID.URI + "#key-9")
+	require.ErrorIs(t, err, ErrNoVerificationMethod)
+	_, err = Dereference(keyDID.URI)
+	require.ErrorIs(t, err, ErrInvalidDID)
+	_, err = Dereference(keyDID.URI + "/path#" + multibaseKey)
+	require.ErrorIs(t, err, ErrInvalidDID)
+
+	doc, err := ParseDocument([]byte(`{
+		"@context": ["https://www.w3.org/ns/did/v1"],
+		"id": "did:docs:example",
+		"verificationMethod": [{
+			"id": "#key-1",
+			"type": "Multikey",
+			"controller": "did:docs:example",
+			"publicKeyMultibase": "` + multibaseKey + `"
+		}],
+		"authentication": ["#key-1"],
+		"capabilityDelegation": [{
+			"id": "did:docs:example#key-2",
+			"type": "Multikey",
+			"controller": "did:docs:controller",
+			"publicKeyMultibase": "` + multibaseKey + `"
+		}]
+	}`))
+	require.NoError(t, err)
+	RegisterAnchorMethod("docs", func(did DID) (Anchor, error) {
+		return &parsedDocumentAnchor{PublicKeyAnchor: &PublicKeyAnchor{did: did, pubk: pubk}, doc: doc}, nil
+	})
+	t.Cleanup(func() { UnregisterAnchorMethod("docs") })
+
+	vm, err = Dereference("did:docs:example#key-1")
+	require.NoError(t, err)
+	require.Equal(t, "#key-1", vm.ID)
+	require.Equal(t, "did:docs:example", vm.Controller)
+
+	// embedded methods carry their own controller
+	vm, err = Dereference("did:docs:example#key-2")
+	require.NoError(t, err)
+	require.Equal(t, Multikey, vm.Type)
+	require.Equal(t, "did:docs:controller", vm.Controller)
+
+	_, err = Dereference("did:docs:example#key-3")
+	require.ErrorIs(t, err, ErrNoVerificationMethod)
+}
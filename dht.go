@@ -0,0 +1,245 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/routing"
+	varint "github.com/multiformats/go-varint"
+
+	"github.com/depinkit/crypto"
+)
+
+const (
+	// DHTNamespace is the DHT key namespace of DID records; see
+	// DHTRecordValidator.
+	DHTNamespace = "did"
+
+	dhtRecordPrefix   = "/" + DHTNamespace + "/"
+	dhtResolveTimeout = 30 * time.Second
+)
+
+// DHTRecord is the self-signed record published to the DHT for a DID.
+// PublicKey is the declared verification key, encoded as a did:key URI, Seq
+// orders the records of a DID, and Signature is a DID-bound signature (see
+// SignBound) over both.
+//
+// The signature only proves possession of the declared key, so records are
+// only accepted for methods whose DIDs are bound to their key: did:key and
+// did:peer DIDs must name the key, and eip155 did:pkh DIDs its address.
+type DHTRecord struct {
+	DID       string `json:"did"`
+	PublicKey string `json:"publicKey"`
+	Seq       uint64 `json:"seq"`
+	Signature []byte `json:"signature"`
+}
+
+// DHTResolver resolves DIDs from self-signed records stored in a libp2p
+// value store (e.g. the Kademlia DHT). A DHT rejects records in namespaces
+// it has no validator for, so DHTRecordValidator must be registered for
+// DHTNamespace, e.g. with the kad-dht option
+// dht.NamespacedValidator(did.DHTNamespace, did.DHTRecordValidator{}).
+type DHTResolver struct {
+	store   routing.ValueStore
+	timeout time.Duration
+}
+
+func NewDHTResolver(store routing.ValueStore) *DHTResolver {
+	return &DHTResolver{
+		store:   store,
+		timeout: dhtResolveTimeout,
+	}
+}
+
+// RegisterDHTResolver registers the resolver as the anchor method for the
+// given DID method, which must be one of key, peer or pkh.
+func RegisterDHTResolver(method string, r *DHTResolver) {
+	RegisterAnchorMethodContext(method, r.GetAnchorContext)
+}
+
+func dhtKey(did DID) string {
+	return dhtRecordPrefix + did.URI
+}
+
+// NewDHTRecord creates a record for the provider's DID, self-signed by the
+// provider's key. Its sequence number is the current Unix time in
+// nanoseconds, so that later records supersede earlier ones.
+func NewDHTRecord(p Provider) (*DHTRecord, error) {
+	pubk := p.Anchor().PublicKey()
+	keyURI := FormatKeyURI(pubk)
+	if keyURI == "" {
+		return nil, ErrInvalidKeyType
+	}
+
+	seq := uint64(timeNow().UnixNano())
+	sig, err := SignBound(p, dhtRecordPayload(seq, keyURI))
+	if err != nil {
+		return nil, fmt.Errorf("sign dht record: %w", err)
+	}
+
+	return &DHTRecord{
+		DID:       p.DID().URI,
+		PublicKey: keyURI,
+		Seq:       seq,
+		Signature: sig,
+	}, nil
+}
+
+// dhtRecordPayload frames the signed part of a record as
+// uvarint(seq) || keyURI.
+func dhtRecordPayload(seq uint64, keyURI string) []byte {
+	return append(varint.ToUvarint(seq), keyURI...)
+}
+
+// Publish stores a self-signed record for the provider in the DHT.
+func (r *DHTResolver) Publish(ctx context.Context, p Provider) error {
+	rec, err := NewDHTRecord(p)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshal dht record: %w", err)
+	}
+
+	if err := r.store.PutValue(ctx, dhtKey(p.DID()), data); err != nil {
+		return fmt.Errorf("put dht record: %w", err)
+	}
+
+	return nil
+}
+
+// GetAnchor fetches and verifies the record for did.
+func (r *DHTResolver) GetAnchor(did DID) (Anchor, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	return r.GetAnchorContext(ctx, did)
+}
+
+func (r *DHTResolver) GetAnchorContext(ctx context.Context, did DID) (Anchor, error) {
+	data, err := r.store.GetValue(ctx, dhtKey(did))
+	if err != nil {
+		return nil, fmt.Errorf("get dht record: %w", err)
+	}
+
+	_, anchor, err := verifyDHTRecord(did, data)
+	return anchor, err
+}
+
+// DHTRecordValidator validates DID records in the DHT; it implements the
+// record.Validator interface of go-libp2p-record.
+type DHTRecordValidator struct{}
+
+// Validate checks that value is a valid record for the DID in key.
+func (DHTRecordValidator) Validate(key string, value []byte) error {
+	uri, ok := strings.CutPrefix(key, dhtRecordPrefix)
+	if !ok {
+		return fmt.Errorf("dht key %q is not in the %s namespace: %w", key, DHTNamespace, ErrInvalidDID)
+	}
+
+	_, _, err := verifyDHTRecord(DID{URI: uri}, value)
+	return err
+}
+
+// Select returns the index of the valid record with the highest sequence
+// number. Ties are broken by the greater record bytes, so that the choice
+// does not depend on the order of values.
+func (v DHTRecordValidator) Select(key string, values [][]byte) (int, error) {
+	uri, ok := strings.CutPrefix(key, dhtRecordPrefix)
+	if !ok {
+		return 0, fmt.Errorf("dht key %q is not in the %s namespace: %w", key, DHTNamespace, ErrInvalidDID)
+	}
+
+	best, bestSeq := -1, uint64(0)
+	for i, value := range values {
+		rec, _, err := verifyDHTRecord(DID{URI: uri}, value)
+		if err != nil {
+			continue
+		}
+		if best < 0 || rec.Seq > bestSeq || (rec.Seq == bestSeq && bytes.Compare(value, values[best]) > 0) {
+			best, bestSeq = i, rec.Seq
+		}
+	}
+
+	if best < 0 {
+		return 0, fmt.Errorf("no valid dht record for %q: %w", key, ErrInvalidSignature)
+	}
+
+	return best, nil
+}
+
+// verifyDHTRecord parses and verifies the record data for did.
+func verifyDHTRecord(did DID, data []byte) (*DHTRecord, Anchor, error) {
+	var rec DHTRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, nil, fmt.Errorf("parse dht record: %w", err)
+	}
+
+	if rec.DID != did.URI {
+		return nil, nil, fmt.Errorf("dht record for %s does not match %s: %w", rec.DID, did, ErrInvalidDID)
+	}
+
+	pubk, err := ParseKeyURI(rec.PublicKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse dht record key: %w", err)
+	}
+
+	anchor, err := dhtRecordAnchor(did, pubk)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := VerifyBound(anchor, dhtRecordPayload(rec.Seq, rec.PublicKey), rec.Signature); err != nil {
+		return nil, nil, fmt.Errorf("verify dht record: %w", err)
+	}
+
+	return &rec, anchor, nil
+}
+
+// dhtRecordAnchor returns the anchor for a record declaring pubk for did,
+// failing if did is not bound to pubk; a record declaring another key is
+// forged.
+func dhtRecordAnchor(did DID, pubk crypto.PubKey) (Anchor, error) {
+	switch did.Method() {
+	case "key", peerMethod:
+		fromDID := PublicKeyFromDID
+		if did.Method() == peerMethod {
+			fromDID = PublicKeyFromPeerDID
+		}
+		want, err := fromDID(did)
+		if err != nil {
+			return nil, fmt.Errorf("dht record did: %w", err)
+		}
+		if !publicKeysEqual(want, pubk) {
+			return nil, fmt.Errorf("dht record key does not match %s: %w", did, ErrInvalidSignature)
+		}
+		return NewAnchor(did, pubk), nil
+
+	case pkhMethod:
+		address, err := didEthAddress(did)
+		if err != nil {
+			return nil, fmt.Errorf("dht record did: %w", err)
+		}
+		keyAddress, err := pubKeyEthAddress(pubk)
+		if err != nil || !bytes.Equal(address, keyAddress) {
+			return nil, fmt.Errorf("dht record key does not match %s: %w", did, ErrInvalidSignature)
+		}
+		return &PKHAnchor{did: did, address: address, pubk: pubk}, nil
+
+	default:
+		return nil, fmt.Errorf("dht records do not bind did:%s keys: %w", did.Method(), ErrNoAnchorMethod)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/routing"
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+// memValueStore is an in-memory value store that, like a DHT, validates
+// values under the DID namespace with DHTRecordValidator.
+type memValueStore struct {
+	mx     sync.Mutex
+	values map[string][]byte
+}
+
+var _ routing.ValueStore = (*memValueStore)(nil)
+
+func newMemValueStore() *memValueStore {
+	return &memValueStore{values: make(map[string][]byte)}
+}
+
+func (s *memValueStore) PutValue(_ context.Context, key string, value []byte, _ ...routing.Option) error {
+	if err := (DHTRecordValidator{}).Validate(key, value); err != nil {
+		return err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.values[key] = value
+	return nil
+}
+
+func (s *memValueStore) GetValue(_ context.Context, key string, _ ...routing.Option) ([]byte, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	value, ok := s.values[key]
+	if !ok {
+		return nil, routing.ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *memValueStore) SearchValue(ctx context.Context, key string, _ ...routing.Option) (<-chan []byte, error) {
+	ch := make(chan []byte, 1)
+	if value, err := s.GetValue(ctx, key); err == nil {
+		ch <- value
+	}
+	close(ch)
+	return ch, nil
+}
+
+func TestDHTResolver(t *testing.T) {
+	privk, pubk, err := crypto.GenerateKeyPair(crypto.Secp256k1)
+	require.NoError(t, err)
+	prov, err := ProviderFromPrivateKeyWithMethod(privk, pkhMethod)
+	require.NoError(t, err)
+	did := prov.DID()
+
+	store := newMemValueStore()
+	resolver := NewDHTResolver(store)
+	RegisterDHTResolver(pkhMethod, resolver)
+	t.Cleanup(func() { RegisterAnchorMethod(pkhMethod, makePKHAnchor) })
+
+	require.NoError(t, resolver.Publish(context.Background(), prov))
+
+	// the record supplies the key a did:pkh only names by its address
+	anchor, err := GetAnchorForDID(did)
+	require.NoError(t, err)
+	require.Equal(t, did, anchor.DID())
+	require.True(t, pubk.Equals(anchor.PublicKey()))
+
+	msg := []byte("hello")
+	sig, err := prov.Sign(msg)
+	require.NoError(t, err)
+	require.NoError(t, anchor.Verify(msg, sig))
+
+	// missing record
+	_, err = GetAnchorForDID(DID{URI: "did:pkh:eip155:1:0x0000000000000000000000000000000000000001"})
+	require.ErrorIs(t, err, routing.ErrNotFound)
+}
+
+func TestDHTResolverRejectsUnboundMethods(t *testing.T) {
+	privk, _, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+
+	// nothing binds a did:dht to its key, so anyone could publish it
+	did := DID{URI: "did:dht:alice"}
+	resolver := NewDHTResolver(newMemValueStore())
+	require.ErrorIs(t, resolver.Publish(context.Background(), NewProvider(did, privk)), ErrNoAnchorMethod)
+
+	// a did:pkh record must declare a key of that address
+	owner, _, err := crypto.GenerateKeyPair(crypto.Secp256k1)
+	require.NoError(t, err)
+	prov, err := ProviderFromPrivateKeyWithMethod(owner, pkhMethod)
+	require.NoError(t, err)
+	mismatched, _, err := crypto.GenerateKeyPair(crypto.Secp256k1)
+	require.NoError(t, err)
+	require.ErrorIs(t, resolver.Publish(context.Background(), NewProvider(prov.DID(), mismatched)), ErrInvalidSignature)
+}
+
+func TestDHTResolverRejectsForgedRecord(t *testing.T) {
+	privk, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	_, otherPubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+
+	did, err := FromPeerKey(pubk)
+	require.NoError(t, err)
+	rec, err := NewDHTRecord(NewProvider(did, privk))
+	require.NoError(t, err)
+
+	store := newMemValueStore()
+	resolver := NewDHTResolver(store)
+
+	// bump the sequence number: the signature no longer matches
+	rec.Seq++
+	data, err := json.Marshal(rec)
+	require.NoError(t, err)
+	require.ErrorIs(t, store.PutValue(context.Background(), dhtKey(did), data), ErrInvalidSignature)
+	store.values[dhtKey(did)] = data // a peer that skips validation
+
+	_, err = resolver.GetAnchor(did)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	// record published under a different DID
+	mallory, err := FromPeerKey(otherPubk)
+	require.NoError(t, err)
+	rec, err = NewDHTRecord(NewProvider(mallory, privk))
+	require.NoError(t, err)
+	data, err = json.Marshal(rec)
+	require.NoError(t, err)
+	require.ErrorIs(t, store.PutValue(context.Background(), dhtKey(did), data), ErrInvalidDID)
+	store.values[dhtKey(did)] = data
+
+	_, err = resolver.GetAnchor(did)
+	require.ErrorIs(t, err, ErrInvalidDID)
+}
+
+func TestDHTRecordValidator(t *testing.T) {
+	privk, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	did, err := FromPeerKey(pubk)
+	require.NoError(t, err)
+	prov := NewProvider(did, privk)
+
+	orig := timeNow
+	t.Cleanup(func() { timeNow = orig })
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	rec, err := NewDHTRecord(prov)
+	require.NoError(t, err)
+	older, err := json.Marshal(rec)
+	require.NoError(t, err)
+
+	now = now.Add(time.Second)
+	rec, err = NewDHTRecord(prov)
+	require.NoError(t, err)
+	newer, err := json.Marshal(rec)
+	require.NoError(t, err)
+
+	var v DHTRecordValidator
+	require.NoError(t, v.Validate(dhtKey(did), newer))
+	require.ErrorIs(t, v.Validate("/pk/"+did.URI, newer), ErrInvalidDID)
+	require.Error(t, v.Validate(dhtKey(did), []byte("not json")))
+
+	// the highest sequence number wins, whatever the order
+	i, err := v.Select(dhtKey(did), [][]byte{[]byte("not json"), older, newer})
+	require.NoError(t, err)
+	require.Equal(t, 2, i)
+	i, err = v.Select(dhtKey(did), [][]byte{newer, older})
+	require.NoError(t, err)
+	require.Equal(t, 0, i)
+
+	_, err = v.Select(dhtKey(did), [][]byte{[]byte("not json")})
+	require.Error(t, err)
+}
+
+func TestDHTResolverBindsKeyDIDs(t *testing.T) {
+	privk, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	_, victimPubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+
+	store := newMemValueStore()
+	resolver := NewDHTResolver(store)
+
+	// a record for a did:key by its own key resolves
+	own := FromPublicKey(pubk)
+	require.NoError(t, resolver.Publish(context.Background(), NewProvider(own, privk)))
+	anchor, err := resolver.GetAnchor(own)
+	require.NoError(t, err)
+	require.Equal(t, pubk, anchor.PublicKey())
+
+	// a self-signed record for someone else's did:key does not
+	victim := FromPublicKey(victimPubk)
+	require.ErrorIs(t, resolver.Publish(context.Background(), NewProvider(victim, privk)), ErrInvalidSignature)
+
+	rec, err := NewDHTRecord(NewProvider(victim, privk))
+	require.NoError(t, err)
+	data, err := json.Marshal(rec)
+	require.NoError(t, err)
+	store.values[dhtKey(victim)] = data
+
+	_, err = resolver.GetAnchor(victim)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+}
@@ -9,6 +9,7 @@
 package did
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"strings"
 )
@@ -21,6 +22,13 @@ func (did DID) Equal(other DID) bool {
 	return did.URI == other.URI
 }
 
+// EqualConstantTime compares the URIs in time that depends only on their
+// lengths, for comparing a presented DID against an authorized one without
+// leaking how much of the identifier matched.
+func (did DID) EqualConstantTime(other DID) bool {
+	return subtle.ConstantTimeCompare([]byte(did.URI), []byte(other.URI)) == 1
+}
+
 func (did DID) Empty() bool {
 	return did.URI == ""
 }
@@ -29,41 +37,173 @@ func (did DID) String() string {
 	return did.URI
 }
 
+// Method returns the method name: the substring between the first and the
+// second colon.
 func (did DID) Method() string {
-	parts := strings.Split(did.URI, ":")
-	if len(parts) == 3 {
-		return parts[1]
+	method, _, ok := did.split()
+	if !ok {
+		return ""
 	}
 
-	return ""
+	return method
 }
 
+// Identifier returns the method-specific-id: everything after the second
+// colon, which may itself contain colons (e.g. did:web with a port).
 func (did DID) Identifier() string {
-	parts := strings.Split(did.URI, ":")
-	if len(parts) == 3 {
-		return parts[2]
+	_, id, ok := did.split()
+	if !ok {
+		return ""
 	}
 
-	return ""
+	return id
+}
+
+// Fragment returns the DID URL fragment, without the "#", or "" if there
+// is none.
+func (did DID) Fragment() string {
+	_, fragment, _ := strings.Cut(did.URI, "#")
+	return fragment
 }
 
+// split scans the URI once for the first two colons; the parts are slices of
+// the URI, so the accessors do not allocate.
+func (did DID) split() (method, id string, ok bool) {
+	_, rest, ok := strings.Cut(did.URI, ":")
+	if !ok {
+		return "", "", false
+	}
+
+	return strings.Cut(rest, ":")
+}
+
+// FromString parses a DID, validating it against the DID Core ABNF:
+//
+//	did                = "did:" method-name ":" method-specific-id
+//	method-name        = 1*method-char
+//	method-specific-id = *( *idchar ":" ) 1*idchar
+//
+// A DID URL path, query or fragment following the DID is accepted but not
+// validated; use FromStringStrict to reject DID URLs. The empty string
+// yields the zero DID.
 func FromString(s string) (DID, error) {
 	if s != "" {
-		parts := strings.Split(s, ":")
-		if len(parts) != 3 {
-			return DID{},
-				fmt.Errorf("%w: %s", ErrInvalidDID, s)
+		if err := validateDID(s); err != nil {
+			return DID{}, &DIDError{Op: "parse", DID: s, Err: err}
 		}
+	}
 
-		for _, part := range parts {
-			if part == "" {
-				return DID{},
-					fmt.Errorf("%w: %s", ErrInvalidDID, s)
-			}
+	return DID{URI: s}, nil
+}
+
+// FromStringStrict parses a bare DID: unlike FromString it rejects the empty
+// string and DID URLs (anything with a path, query or fragment).
+func FromStringStrict(s string) (DID, error) {
+	if s == "" {
+		return DID{}, &DIDError{Op: "parse", Err: fmt.Errorf("%w: empty", ErrInvalidDID)}
+	}
+
+	if strings.ContainsAny(s, "/?#") {
+		return DID{}, &DIDError{Op: "parse", DID: s, Err: fmt.Errorf("%w: DID URL not allowed", ErrInvalidDID)}
+	}
+
+	return FromString(s)
+}
+
+// FromStringValidating is like FromString, but additionally decodes did:key
+// identifiers so that malformed keys are rejected at parse time. Other
+// methods cannot be checked offline and are only validated structurally.
+func FromStringValidating(s string) (DID, error) {
+	did, err := FromString(s)
+	if err != nil {
+		return DID{}, err
+	}
+
+	if did.Method() == "key" {
+		base := s
+		if end := strings.IndexAny(s, "/?#"); end >= 0 {
+			base = s[:end]
+		}
+		if _, err := parseKeyURI(base); err != nil {
+			return DID{}, &DIDError{Op: "parse", DID: s, Err: fmt.Errorf("%w: %w", ErrInvalidDID, err)}
 		}
+	}
 
-		// TODO validate parts according to spec: https://www.w3.org/TR/did-core/
+	return did, nil
+}
+
+func validateDID(s string) error {
+	base := s
+	if end := strings.IndexAny(s, "/?#"); end >= 0 {
+		base = s[:end]
 	}
 
-	return DID{URI: s}, nil
+	rest, ok := strings.CutPrefix(base, "did:")
+	if !ok {
+		return fmt.Errorf("%w: missing did scheme", ErrInvalidDID)
+	}
+
+	method, id, ok := strings.Cut(rest, ":")
+	if !ok {
+		return fmt.Errorf("%w: missing method-specific-id", ErrInvalidDID)
+	}
+
+	if !validMethodName(method) {
+		return fmt.Errorf("%w: invalid method name %q", ErrInvalidDID, method)
+	}
+
+	segments := strings.Split(id, ":")
+	if segments[len(segments)-1] == "" {
+		return fmt.Errorf("%w: empty method-specific-id segment", ErrInvalidDID)
+	}
+
+	for _, segment := range segments {
+		if !validIDChars(segment) {
+			return fmt.Errorf("%w: invalid method-specific-id segment %q", ErrInvalidDID, segment)
+		}
+	}
+
+	return nil
+}
+
+// validMethodName checks method-name = 1*method-char, where method-char is
+// %x61-7A / DIGIT.
+func validMethodName(method string) bool {
+	if method == "" {
+		return false
+	}
+
+	for i := 0; i < len(method); i++ {
+		c := method[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validIDChars checks that segment is *idchar, where
+// idchar = ALPHA / DIGIT / "." / "-" / "_" / pct-encoded.
+func validIDChars(segment string) bool {
+	for i := 0; i < len(segment); i++ {
+		c := segment[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.' || c == '-' || c == '_':
+		case c == '%':
+			if i+2 >= len(segment) || !isHexDigit(segment[i+1]) || !isHexDigit(segment[i+2]) {
+				return false
+			}
+			i += 2
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
 }
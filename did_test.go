@@ -13,6 +13,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
 )
 
 func TestDID(t *testing.T) {
@@ -57,9 +59,44 @@ func TestDID(t *testing.T) {
 	_, err = FromString(emptyPartDIDString)
 	assert.Error(t, err, "FromString should have failed for DID with empty parts")
 
-	// Test DID with more than 3 parts
+	// Test DID with colons in the method-specific-id
 	_, err = FromString(tooManyPartsDIDString)
-	assert.Error(t, err, "FromString should have failed for DID with more than 3 parts")
+	assert.NoError(t, err, "FromString should accept colons in the method-specific-id")
+}
+
+func TestDIDFromStringGrammar(t *testing.T) {
+	valid := []string{
+		"did:web:example.com%3A8443:user:alice",
+		"did:web:example.com:8443",
+		"did:example:a::b",
+		"did:example:abc_DEF-1.2",
+		"did:example:%20x",
+		"did:pkh:eip155:1:0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"did:example:123/path?query#fragment",
+	}
+	for _, s := range valid {
+		_, err := FromString(s)
+		assert.NoErrorf(t, err, "FromString(%q)", s)
+	}
+
+	invalid := map[string]string{
+		"did:example:has spaces": `"has spaces"`,
+		"did:example:ctl\x01":    `"ctl\x01"`,
+		"did:example:bad%zz":     `"bad%zz"`,
+		"did:example:trunc%2":    `"trunc%2"`,
+		"did:Example:123":        `method name "Example"`,
+		"did:example:":           "empty method-specific-id segment",
+		"did:example:123:":       "empty method-specific-id segment",
+		"did:example":            "missing method-specific-id",
+		"uri:example:123":        "missing did scheme",
+	}
+	for s, reason := range invalid {
+		_, err := FromString(s)
+		assert.ErrorIsf(t, err, ErrInvalidDID, "FromString(%q)", s)
+		if err != nil {
+			assert.Contains(t, err.Error(), reason)
+		}
+	}
 }
 
 // Test that an *empty string* is accepted and yields a zero-value DID.
@@ -99,3 +136,138 @@ func TestDIDEqualSymmetricAndReflexive(t *testing.T) {
 	assert.False(t, a.Equal(c))
 	assert.False(t, c.Equal(a))
 }
+
+func TestDIDEqualConstantTime(t *testing.T) {
+	a := DID{URI: "did:key:abc"}
+	assert.True(t, a.EqualConstantTime(DID{URI: "did:key:abc"}))
+	assert.False(t, a.EqualConstantTime(DID{URI: "did:key:abd"}))
+	assert.False(t, a.EqualConstantTime(DID{URI: "did:key:abcd"}))
+	assert.True(t, DID{}.EqualConstantTime(DID{}))
+}
+
+func TestDIDFromStringStrict(t *testing.T) {
+	valid := []string{
+		"did:example:123456789abcdefghi",
+		"did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK",
+		"did:web3:example.com",
+	}
+	for _, s := range valid {
+		d, err := FromStringStrict(s)
+		assert.NoErrorf(t, err, "strict parse of %q", s)
+		assert.Equal(t, s, d.String())
+	}
+
+	invalid := []string{
+		"",                          // empty
+		"did:example:123#key-1",     // fragment
+		"did:example:123/path",      // path
+		"did:example:123?service=x", // query
+		"did:Example:123",           // uppercase method
+		"did:ex-ample:123",          // non method-char
+		"invalid:did",               // structurally invalid
+	}
+	for _, s := range invalid {
+		_, err := FromStringStrict(s)
+		assert.ErrorIsf(t, err, ErrInvalidDID, "strict parse of %q", s)
+	}
+
+	// the lenient parser still accepts DID URLs
+	_, err := FromString("did:example:123#key-1")
+	assert.NoError(t, err)
+}
+
+// Method() and Identifier() handle method-specific-ids containing colons.
+func TestDIDMethodIdentifierWithColons(t *testing.T) {
+	d, err := FromString("did:web:example.com:8443:user:alice")
+	require.NoError(t, err)
+	assert.Equal(t, "web", d.Method())
+	assert.Equal(t, "example.com:8443:user:alice", d.Identifier())
+
+	d, err = FromString("did:pkh:eip155:1:0xabc")
+	require.NoError(t, err)
+	assert.Equal(t, "pkh", d.Method())
+	assert.Equal(t, "eip155:1:0xabc", d.Identifier())
+}
+
+func TestDIDErrorContext(t *testing.T) {
+	var didErr *DIDError
+
+	_, err := FromString("did:Bad:123")
+	require.ErrorIs(t, err, ErrInvalidDID)
+	require.ErrorAs(t, err, &didErr)
+	require.Equal(t, "parse", didErr.Op)
+	require.Equal(t, "did:Bad:123", didErr.DID)
+
+	_, err = GetAnchorForDID(DID{URI: "did:unknown:123"})
+	require.ErrorIs(t, err, ErrNoAnchorMethod)
+	require.ErrorAs(t, err, &didErr)
+	require.Equal(t, "resolve", didErr.Op)
+	require.Equal(t, "did:unknown:123", didErr.DID)
+	require.Contains(t, err.Error(), "did:unknown:123")
+
+	_, err = ParseKeyURI("did:key:z6MkgarbageZZZ")
+	require.ErrorAs(t, err, &didErr)
+	require.Equal(t, "parse key", didErr.Op)
+	require.Equal(t, "did:key:z6MkgarbageZZZ", didErr.DID)
+
+	_, err = FromStringStrict("did:example:123#frag")
+	require.ErrorIs(t, err, ErrInvalidDID)
+	require.ErrorAs(t, err, &didErr)
+	require.Equal(t, "did:example:123#frag", didErr.DID)
+}
+
+func TestDIDFromStringValidating(t *testing.T) {
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	keyDID := FromPublicKey(pubk)
+
+	did, err := FromStringValidating(keyDID.URI)
+	require.NoError(t, err)
+	require.Equal(t, keyDID, did)
+
+	_, err = FromStringValidating(keyDID.URI + "#" + keyDID.Identifier())
+	require.NoError(t, err)
+
+	// structurally valid, but not a key
+	_, err = FromString("did:key:garbage")
+	require.NoError(t, err)
+	_, err = FromStringValidating("did:key:garbage")
+	require.ErrorIs(t, err, ErrInvalidDID)
+
+	// other methods are only validated structurally
+	_, err = FromStringValidating("did:web:example.com")
+	require.NoError(t, err)
+	_, err = FromStringValidating("did:web:")
+	require.ErrorIs(t, err, ErrInvalidDID)
+}
+
+func TestDIDFragment(t *testing.T) {
+	require.Equal(t, "key-1", DID{URI: "did:web:example.com#key-1"}.Fragment())
+	require.Equal(t, "", DID{URI: "did:web:example.com"}.Fragment())
+	require.Equal(t, "", DID{URI: "did:web:example.com#"}.Fragment())
+	require.Equal(t, "", DID{}.Fragment())
+}
+
+func TestDIDAccessorsDoNotAllocate(t *testing.T) {
+	did := DID{URI: "did:web:example.com%3A8443:user:alice#key-1"}
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = did.Method()
+		_ = did.Identifier()
+		_ = did.Fragment()
+	})
+	require.Zero(t, allocs)
+
+	// canonical runs on every trust context lookup
+	pkh := DID{URI: "did:pkh:eip155:1:0xab16a96d359ec26a11e2c2b3d8f8b8942d5bfcdb"}
+	require.Zero(t, testing.AllocsPerRun(100, func() { _ = pkh.canonical() }))
+}
+
+func BenchmarkDIDAccessors(b *testing.B) {
+	did := DID{URI: "did:web:example.com%3A8443:user:alice#key-1"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = did.Method()
+		_ = did.Identifier()
+		_ = did.Fragment()
+	}
+}
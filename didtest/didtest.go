@@ -0,0 +1,96 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+// Package didtest provides in-memory providers and anchors for tests of
+// packages that depend on did.
+package didtest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/depinkit/crypto"
+
+	"github.com/depinkit/did"
+)
+
+// MockProvider is a did.Provider backed by an in-memory Ed25519 key. Its
+// Sign can be made to fail with SetSignError.
+type MockProvider struct {
+	did   did.DID
+	privk crypto.PrivKey
+
+	mx      sync.Mutex
+	signErr error
+}
+
+var _ did.Provider = (*MockProvider)(nil)
+
+// NewMockProvider creates a provider for d with a fresh Ed25519 key; if d is
+// empty, the key's did:key DID is used.
+func NewMockProvider(d did.DID) (*MockProvider, error) {
+	privk, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	if err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+
+	if d.Empty() {
+		d = did.FromPublicKey(pubk)
+	}
+
+	return &MockProvider{
+		did:   d,
+		privk: privk,
+	}, nil
+}
+
+// NewMockAnchor creates an anchor for d with a fresh Ed25519 key whose
+// private half is discarded; use a MockProvider's Anchor to verify the
+// provider's signatures.
+func NewMockAnchor(d did.DID) (did.Anchor, error) {
+	p, err := NewMockProvider(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Anchor(), nil
+}
+
+// SetSignError makes subsequent Sign calls fail with err; nil restores
+// signing.
+func (p *MockProvider) SetSignError(err error) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	p.signErr = err
+}
+
+func (p *MockProvider) DID() did.DID {
+	return p.did
+}
+
+func (p *MockProvider) Sign(data []byte) ([]byte, error) {
+	p.mx.Lock()
+	err := p.signErr
+	p.mx.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p.privk.Sign(data)
+}
+
+// Anchor returns an anchor that verifies the provider's signatures.
+func (p *MockProvider) Anchor() did.Anchor {
+	return did.NewAnchor(p.did, p.privk.GetPublic())
+}
+
+func (p *MockProvider) PrivateKey() (crypto.PrivKey, error) {
+	return p.privk, nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package didtest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/did"
+)
+
+func TestMockProvider(t *testing.T) {
+	d := did.DID{URI: "did:example:alice"}
+	p, err := NewMockProvider(d)
+	require.NoError(t, err)
+	require.Equal(t, d, p.DID())
+
+	data := []byte("hello")
+	sig, err := p.Sign(data)
+	require.NoError(t, err)
+	require.NoError(t, p.Anchor().Verify(data, sig))
+	require.Equal(t, d, p.Anchor().DID())
+
+	errSign := errors.New("device unplugged")
+	p.SetSignError(errSign)
+	_, err = p.Sign(data)
+	require.ErrorIs(t, err, errSign)
+	p.SetSignError(nil)
+	_, err = p.Sign(data)
+	require.NoError(t, err)
+
+	keyed, err := NewMockProvider(did.DID{})
+	require.NoError(t, err)
+	require.Equal(t, "key", keyed.DID().Method())
+
+	anchor, err := NewMockAnchor(d)
+	require.NoError(t, err)
+	require.Equal(t, d, anchor.DID())
+	require.ErrorIs(t, anchor.Verify(data, sig), did.ErrInvalidSignature)
+}
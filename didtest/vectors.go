@@ -0,0 +1,114 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package didtest
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpECDSA "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/depinkit/crypto"
+
+	"github.com/depinkit/did"
+)
+
+// KeyVector is a golden key-encoding vector. PrivHex is the raw private key
+// (libp2p Raw() form for Ed25519, the 32-byte scalar for secp256k1/Eth) and
+// SigHex is the deterministic signature over Message.
+type KeyVector struct {
+	KeyType     string `json:"keyType"`
+	PrivHex     string `json:"privHex"`
+	ExpectedDID string `json:"expectedDID"`
+	Message     string `json:"message"`
+	SigHex      string `json:"sigHex"`
+}
+
+// RunVectors loads the vectors at path and, for each, asserts that the key
+// formats to the expected DID, the DID parses back to the key, the
+// signature verifies and signing reproduces it.
+func RunVectors(t *testing.T, path string) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var vectors []KeyVector
+	require.NoError(t, json.Unmarshal(data, &vectors))
+	require.NotEmpty(t, vectors)
+
+	for i, v := range vectors {
+		t.Run(fmt.Sprintf("%s/%d", v.KeyType, i), func(t *testing.T) {
+			priv, err := hex.DecodeString(v.PrivHex)
+			require.NoError(t, err)
+			expectedSig, err := hex.DecodeString(v.SigHex)
+			require.NoError(t, err)
+			msg := []byte(v.Message)
+
+			pubk, sign := vectorKey(t, v.KeyType, priv)
+
+			// format
+			d := did.FromPublicKey(pubk)
+			require.Equal(t, v.ExpectedDID, d.URI)
+
+			// parse
+			parsed, err := did.FromString(v.ExpectedDID)
+			require.NoError(t, err)
+			recovered, err := did.PublicKeyFromDID(parsed)
+			require.NoError(t, err)
+			require.True(t, pubk.Equals(recovered))
+
+			// verify
+			anchor := did.NewAnchor(parsed, recovered)
+			require.NoError(t, anchor.Verify(msg, expectedSig))
+			require.Error(t, anchor.Verify(append(msg, 'x'), expectedSig))
+
+			sig, err := sign(msg)
+			require.NoError(t, err)
+			require.Equal(t, v.SigHex, hex.EncodeToString(sig))
+		})
+	}
+}
+
+func vectorKey(t *testing.T, keyType string, priv []byte) (crypto.PubKey, func([]byte) ([]byte, error)) {
+	switch keyType {
+	case "Ed25519":
+		privk, err := libp2p_crypto.UnmarshalEd25519PrivateKey(priv)
+		require.NoError(t, err)
+		return privk.GetPublic(), privk.Sign
+
+	case "Secp256k1":
+		privk, err := libp2p_crypto.UnmarshalSecp256k1PrivateKey(priv)
+		require.NoError(t, err)
+		return privk.GetPublic(), privk.Sign
+
+	case "Eth":
+		sk := secp256k1.PrivKeyFromBytes(priv)
+		pubk, err := crypto.UnmarshalEthPublicKey(sk.PubKey().SerializeCompressed())
+		require.NoError(t, err)
+		return pubk, func(msg []byte) ([]byte, error) {
+			hasher := sha3.NewLegacyKeccak256()
+			hasher.Write([]byte("\x19Ethereum Signed Message:\n"))
+			fmt.Fprintf(hasher, "%d", len(msg))
+			hasher.Write(msg)
+			return secpECDSA.Sign(sk, hasher.Sum(nil)).Serialize(), nil
+		}
+
+	default:
+		t.Fatalf("unknown vector key type %q", keyType)
+		return nil, nil
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"fmt"
+
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+
+	"github.com/depinkit/crypto"
+)
+
+const (
+	// ethDigestSize is the size of a Keccak-256 digest.
+	ethDigestSize = 32
+	// sha256DigestSize is the size of the SHA-256 digest libp2p signs for
+	// secp256k1 and ECDSA keys.
+	sha256DigestSize = 32
+)
+
+// DigestVerifier is implemented by anchors that can verify a signature
+// against a digest the caller has already computed, e.g. an EIP-712 hash,
+// instead of hashing the data again.
+type DigestVerifier interface {
+	VerifyDigest(digest []byte, sig []byte) error
+}
+
+var (
+	_ DigestVerifier = (*PublicKeyAnchor)(nil)
+	_ DigestVerifier = (*EthPersonalSignAnchor)(nil)
+	_ DigestVerifier = (*PKHAnchor)(nil)
+)
+
+// VerifyDigest verifies a DER ECDSA signature over the precomputed digest
+// Verify would compute: SHA-256 (SHA-384, SHA-512 for P-384, P-521 keys)
+// of the data, or the EIP-191 personal_sign hash for Eth keys. Ed25519 and
+// RSA keys do not sign digests and are rejected.
+func (a *PublicKeyAnchor) VerifyDigest(digest []byte, sig []byte) error {
+	size, err := digestSize(a.pubk)
+	if err != nil {
+		return err
+	}
+
+	if len(digest) != size {
+		return fmt.Errorf("digest length %d, expected %d: %w", len(digest), size, ErrInvalidSignature)
+	}
+
+	if err := checkSignatureSize(a.pubk, sig); err != nil {
+		return err
+	}
+
+	return verifyDigest(a.pubk, digest, sig)
+}
+
+// VerifyDigest verifies a 65-byte R || S || V wallet signature over a
+// 32-byte Keccak-256 digest, such as the EIP-191 personal_sign hash or an
+// EIP-712 typed data hash.
+func (a *EthPersonalSignAnchor) VerifyDigest(digest []byte, sig []byte) error {
+	if len(digest) != ethDigestSize {
+		return fmt.Errorf("digest length %d, expected %d: %w", len(digest), ethDigestSize, ErrInvalidSignature)
+	}
+
+	recovered, err := recoverEthKey(digest, sig)
+	if err != nil {
+		return err
+	}
+
+	if !recovered.IsEqual(a.key) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// VerifyDigest verifies a 65-byte R || S || V wallet signature over a
+// 32-byte Keccak-256 digest, checking that the account signed it.
+func (a *PKHAnchor) VerifyDigest(digest []byte, sig []byte) error {
+	if len(digest) != ethDigestSize {
+		return fmt.Errorf("digest length %d, expected %d: %w", len(digest), ethDigestSize, ErrInvalidSignature)
+	}
+
+	_, err := a.verifyRecoverHash(digest, sig)
+	return err
+}
+
+// digestSize returns the size of the digest Verify signs for the key.
+func digestSize(pubk crypto.PubKey) (int, error) {
+	if newHash, ok := ecdsaCurveHash(pubk); ok {
+		return newHash().Size(), nil
+	}
+
+	switch pubk.Type() {
+	case crypto.Secp256k1, libp2p_crypto.ECDSA:
+		return sha256DigestSize, nil
+	case crypto.Eth:
+		return ethDigestSize, nil
+	default:
+		return 0, fmt.Errorf("digest verification not supported for key type %d: %w", pubk.Type(), ErrInvalidKeyType)
+	}
+}
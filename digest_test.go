@@ -0,0 +1,72 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpECDSA "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+func TestVerifyDigest(t *testing.T) {
+	data := []byte("hello world")
+	digest := sha256.Sum256(data)
+
+	privk, pubk, err := crypto.GenerateKeyPair(crypto.Secp256k1)
+	require.NoError(t, err)
+	sig, err := privk.Sign(data)
+	require.NoError(t, err)
+
+	anchor := NewAnchor(FromPublicKey(pubk), pubk).(DigestVerifier)
+	require.NoError(t, anchor.VerifyDigest(digest[:], sig))
+
+	other := sha256.Sum256([]byte("other"))
+	require.ErrorIs(t, anchor.VerifyDigest(other[:], sig), ErrInvalidSignature)
+	require.ErrorIs(t, anchor.VerifyDigest(digest[:31], sig), ErrInvalidSignature)
+
+	_, edPub, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	err = NewAnchor(FromPublicKey(edPub), edPub).(DigestVerifier).VerifyDigest(digest[:], make([]byte, 64))
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+}
+
+func TestVerifyDigestEth(t *testing.T) {
+	sk, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	pubk, err := crypto.UnmarshalEthPublicKey(sk.PubKey().SerializeCompressed())
+	require.NoError(t, err)
+
+	// a digest that is signed as is, e.g. an EIP-712 hash
+	digest := keccak256([]byte("typed data"))
+	compact := secpECDSA.SignCompact(sk, digest, false)
+	sig := append(compact[1:], compact[0])
+
+	anchor, err := NewEthPersonalSignAnchor(FromPublicKey(pubk), pubk)
+	require.NoError(t, err)
+	require.NoError(t, anchor.(DigestVerifier).VerifyDigest(digest, sig))
+	require.ErrorIs(t, anchor.(DigestVerifier).VerifyDigest(digest[1:], sig), ErrInvalidSignature)
+	require.Error(t, anchor.Verify(digest, sig), "Verify hashes the data again")
+
+	pkh, err := NewPKHAnchor(ethAddressDID(ethAddress(sk.PubKey()), 1))
+	require.NoError(t, err)
+	require.NoError(t, pkh.VerifyDigest(digest, sig))
+	require.Equal(t, sk.PubKey().SerializeCompressed(), must(pkh.PublicKey().Raw()))
+	require.ErrorIs(t, pkh.VerifyDigest(keccak256([]byte("other")), sig), ErrInvalidSignature)
+
+	// Eth keys in did:key anchors verify DER signatures over the
+	// personal_sign hash
+	der := secpECDSA.Sign(sk, ethPersonalHash([]byte("msg"))).Serialize()
+	keyAnchor := NewAnchor(FromPublicKey(pubk), pubk).(DigestVerifier)
+	require.NoError(t, keyAnchor.VerifyDigest(ethPersonalHash([]byte("msg")), der))
+}
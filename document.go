@@ -0,0 +1,480 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+
+	"github.com/depinkit/crypto"
+)
+
+const (
+	didContextV1         = "https://www.w3.org/ns/did/v1"
+	ed25519Context2020   = "https://w3id.org/security/suites/ed25519-2020/v1"
+	secp256k1Context2019 = "https://w3id.org/security/suites/secp256k1-2019/v1"
+	multikeyContext      = "https://w3id.org/security/multikey/v1"
+
+	Ed25519VerificationKey2020        = "Ed25519VerificationKey2020"
+	EcdsaSecp256k1VerificationKey2019 = "EcdsaSecp256k1VerificationKey2019"
+	Multikey                          = "Multikey"
+)
+
+// Verification relationships, as named in DID Documents.
+const (
+	RelationshipAuthentication       = "authentication"
+	RelationshipAssertionMethod      = "assertionMethod"
+	RelationshipKeyAgreement         = "keyAgreement"
+	RelationshipCapabilityInvocation = "capabilityInvocation"
+	RelationshipCapabilityDelegation = "capabilityDelegation"
+)
+
+// Document is a W3C DID Document.
+type Document struct {
+	Context              []string             `json:"@context"`
+	ID                   string               `json:"id"`
+	Controller           []string             `json:"controller,omitempty"`
+	VerificationMethod   []VerificationMethod `json:"verificationMethod,omitempty"`
+	Authentication       []string             `json:"authentication,omitempty"`
+	AssertionMethod      []string             `json:"assertionMethod,omitempty"`
+	KeyAgreement         []string             `json:"keyAgreement,omitempty"`
+	CapabilityInvocation []string             `json:"capabilityInvocation,omitempty"`
+	CapabilityDelegation []string             `json:"capabilityDelegation,omitempty"`
+
+	// embedded holds verification methods embedded in a relationship rather
+	// than referenced, keyed by relationship and then absolute ID; they
+	// are only valid for that relationship.
+	embedded map[string]map[string]VerificationMethod
+}
+
+// VerificationMethod is a verification method entry in a DID Document.
+type VerificationMethod struct {
+	ID                 string `json:"id"`
+	Type               string `json:"type"`
+	Controller         string `json:"controller"`
+	PublicKeyMultibase string `json:"publicKeyMultibase,omitempty"`
+	PublicKeyJwk       *JWK   `json:"publicKeyJwk,omitempty"`
+}
+
+// DocumentFromDID resolves the anchor for did and produces its DID Document.
+func DocumentFromDID(did DID) (*Document, error) {
+	anchor, err := GetAnchorForDID(did)
+	if err != nil {
+		return nil, fmt.Errorf("get anchor for did: %w", err)
+	}
+
+	pubk := anchor.PublicKey()
+	if pubk == nil {
+		return nil, fmt.Errorf("anchor for %s has no public key: %w", did, ErrInvalidKeyType)
+	}
+
+	return NewDocument(anchor.DID(), pubk)
+}
+
+// Document produces the DID Document for the anchor.
+func (a *PublicKeyAnchor) Document() (*Document, error) {
+	return NewDocument(a.did, a.pubk)
+}
+
+// NewDocument produces a DID Document for did with pubk as its signing
+// verification method, referenced for authentication, assertion and
+// capability invocation and delegation. An Ed25519 key also gets its derived
+// X25519 key as the keyAgreement method.
+func NewDocument(did DID, pubk crypto.PubKey) (*Document, error) {
+	return newDocument(did, []crypto.PubKey{pubk})
+}
+
+// newDocument produces a DID Document listing each key as a verification
+// method, numbered key-1, key-2, ... in order (did:key uses the multibase
+// key as the fragment instead). Signing keys are referenced by every
+// signature relationship and X25519 keys by keyAgreement; the X25519 keys
+// derived from Ed25519 keys follow the given keys.
+func newDocument(did DID, pubks []crypto.PubKey) (*Document, error) {
+	doc := &Document{
+		Context: []string{didContextV1},
+		ID:      did.URI,
+	}
+
+	var derived []crypto.PubKey
+	for _, pubk := range pubks {
+		if pubk.Type() != crypto.Ed25519 {
+			continue
+		}
+		xkey, _, err := keyAgreementKey(did, pubk)
+		if err != nil {
+			return nil, err
+		}
+		derived = append(derived, xkey)
+	}
+
+	for i, pubk := range append(slices.Clone(pubks), derived...) {
+		vmID, err := doc.addVerificationMethod(did, pubk, i+1)
+		if err != nil {
+			return nil, err
+		}
+
+		if pubk.Type() == KeyTypeX25519 {
+			doc.KeyAgreement = append(doc.KeyAgreement, vmID)
+			continue
+		}
+		doc.Authentication = append(doc.Authentication, vmID)
+		doc.AssertionMethod = append(doc.AssertionMethod, vmID)
+		doc.CapabilityInvocation = append(doc.CapabilityInvocation, vmID)
+		doc.CapabilityDelegation = append(doc.CapabilityDelegation, vmID)
+	}
+
+	return doc, nil
+}
+
+// addVerificationMethod appends pubk as the n-th verification method and
+// returns its ID.
+func (doc *Document) addVerificationMethod(did DID, pubk crypto.PubKey, n int) (string, error) {
+	vmType, suiteContext, err := verificationMethodType(pubk)
+	if err != nil {
+		return "", err
+	}
+
+	multibaseKey, err := keyMultibase(pubk)
+	if err != nil {
+		return "", err
+	}
+	vmID := verificationMethodID(did, multibaseKey, n)
+
+	if !slices.Contains(doc.Context, suiteContext) {
+		doc.Context = append(doc.Context, suiteContext)
+	}
+	doc.VerificationMethod = append(doc.VerificationMethod, VerificationMethod{
+		ID:                 vmID,
+		Type:               vmType,
+		Controller:         did.URI,
+		PublicKeyMultibase: multibaseKey,
+	})
+
+	return vmID, nil
+}
+
+// keyMultibase returns the multicodec-prefixed, multibase-encoded key, as
+// used in did:key identifiers and publicKeyMultibase.
+func keyMultibase(pubk crypto.PubKey) (string, error) {
+	keyURI := FormatKeyURI(pubk)
+	if keyURI == "" {
+		return "", ErrInvalidKeyType
+	}
+
+	return strings.TrimPrefix(keyURI, keyPrefix+":"), nil
+}
+
+// verificationMethodID returns the DID URL of the n-th verification method;
+// did:key uses the multibase key as the fragment, per the did:key spec.
+func verificationMethodID(did DID, multibaseKey string, n int) string {
+	fragment := fmt.Sprintf("key-%d", n)
+	if did.Method() == "key" {
+		fragment = multibaseKey
+	}
+
+	return did.URI + "#" + fragment
+}
+
+// verificationMethodType returns the verification method type and its
+// JSON-LD context for the key's multicodec.
+func verificationMethodType(pubk crypto.PubKey) (string, string, error) {
+	switch pubk.Type() {
+	case crypto.Ed25519: // multicodecKindEd25519PubKey
+		return Ed25519VerificationKey2020, ed25519Context2020, nil
+	case crypto.Secp256k1, crypto.Eth: // multicodecKindSecp256k1PubKey, multicodecKindEthPubKey
+		return EcdsaSecp256k1VerificationKey2019, secp256k1Context2019, nil
+	case libp2p_crypto.ECDSA: // multicodecKindP256PubKey, multicodecKindP384PubKey, multicodecKindP521PubKey
+		return Multikey, multikeyContext, nil
+	case libp2p_crypto.RSA, KeyTypeBLS12381G2, KeyTypeX25519: // multicodecKindRSAPubKey, multicodecKindBLS12381G2PubKey, multicodecKindX25519PubKey
+		return Multikey, multikeyContext, nil
+	default:
+		return "", "", fmt.Errorf("no verification method for key type %d: %w", pubk.Type(), ErrInvalidKeyType)
+	}
+}
+
+// ParseDocument parses a DID Document. Verification relationships may
+// reference verification methods by (absolute or relative) DID URL or embed
+// them.
+func ParseDocument(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse did document: %w", err)
+	}
+
+	if _, err := FromString(doc.ID); err != nil || doc.ID == "" {
+		return nil, fmt.Errorf("did document id %q: %w", doc.ID, ErrInvalidDID)
+	}
+
+	return &doc, nil
+}
+
+// documentJSON is the wire form of a Document, with relationships that mix
+// references and embedded verification methods.
+type documentJSON struct {
+	Context              []string             `json:"@context"`
+	ID                   string               `json:"id"`
+	Controller           []string             `json:"controller,omitempty"`
+	VerificationMethod   []VerificationMethod `json:"verificationMethod,omitempty"`
+	Authentication       []json.RawMessage    `json:"authentication,omitempty"`
+	AssertionMethod      []json.RawMessage    `json:"assertionMethod,omitempty"`
+	KeyAgreement         []json.RawMessage    `json:"keyAgreement,omitempty"`
+	CapabilityInvocation []json.RawMessage    `json:"capabilityInvocation,omitempty"`
+	CapabilityDelegation []json.RawMessage    `json:"capabilityDelegation,omitempty"`
+}
+
+func (doc *Document) MarshalJSON() ([]byte, error) {
+	out := documentJSON{
+		Context:            doc.Context,
+		ID:                 doc.ID,
+		Controller:         doc.Controller,
+		VerificationMethod: doc.VerificationMethod,
+	}
+
+	for rel, dst := range out.relationships() {
+		for _, ref := range *doc.relationship(rel) {
+			var entry any = ref
+			if vm, ok := doc.embedded[rel][doc.absoluteID(ref)]; ok {
+				entry = vm
+			}
+
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return nil, err
+			}
+			*dst = append(*dst, data)
+		}
+	}
+
+	return json.Marshal(out)
+}
+
+func (doc *Document) UnmarshalJSON(data []byte) error {
+	var in documentJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	*doc = Document{
+		Context:            in.Context,
+		ID:                 in.ID,
+		Controller:         in.Controller,
+		VerificationMethod: in.VerificationMethod,
+	}
+
+	for rel, src := range in.relationships() {
+		refs := doc.relationship(rel)
+		for _, entry := range *src {
+			var ref string
+			if err := json.Unmarshal(entry, &ref); err == nil {
+				*refs = append(*refs, ref)
+				continue
+			}
+
+			var vm VerificationMethod
+			if err := json.Unmarshal(entry, &vm); err != nil {
+				return fmt.Errorf("%s entry: %w", rel, err)
+			}
+			if vm.ID == "" {
+				return fmt.Errorf("%s entry: embedded verification method without id", rel)
+			}
+
+			if doc.embedded == nil {
+				doc.embedded = make(map[string]map[string]VerificationMethod)
+			}
+			if doc.embedded[rel] == nil {
+				doc.embedded[rel] = make(map[string]VerificationMethod)
+			}
+			doc.embedded[rel][doc.absoluteID(vm.ID)] = vm
+			*refs = append(*refs, vm.ID)
+		}
+	}
+
+	return nil
+}
+
+func (in *documentJSON) relationships() map[string]*[]json.RawMessage {
+	return map[string]*[]json.RawMessage{
+		RelationshipAuthentication:       &in.Authentication,
+		RelationshipAssertionMethod:      &in.AssertionMethod,
+		RelationshipKeyAgreement:         &in.KeyAgreement,
+		RelationshipCapabilityInvocation: &in.CapabilityInvocation,
+		RelationshipCapabilityDelegation: &in.CapabilityDelegation,
+	}
+}
+
+// relationship returns the references listed under rel, or nil if rel is
+// not a verification relationship.
+func (doc *Document) relationship(rel string) *[]string {
+	switch rel {
+	case RelationshipAuthentication:
+		return &doc.Authentication
+	case RelationshipAssertionMethod:
+		return &doc.AssertionMethod
+	case RelationshipKeyAgreement:
+		return &doc.KeyAgreement
+	case RelationshipCapabilityInvocation:
+		return &doc.CapabilityInvocation
+	case RelationshipCapabilityDelegation:
+		return &doc.CapabilityDelegation
+	default:
+		return nil
+	}
+}
+
+// absoluteID resolves a relative DID URL ("#key-1") against the document.
+func (doc *Document) absoluteID(id string) string {
+	if strings.HasPrefix(id, "#") {
+		return doc.ID + id
+	}
+
+	return id
+}
+
+// KeysForRelationship returns anchors for the verification methods listed
+// under rel; both referenced and embedded methods are resolved.
+func (doc *Document) KeysForRelationship(rel string) ([]Anchor, error) {
+	refs := doc.relationship(rel)
+	if refs == nil {
+		return nil, fmt.Errorf("unknown verification relationship %q", rel)
+	}
+
+	anchors := make([]Anchor, 0, len(*refs))
+	for _, ref := range *refs {
+		anchor, err := doc.resolveMethod(rel, ref)
+		if err != nil {
+			return nil, err
+		}
+		anchors = append(anchors, anchor)
+	}
+
+	return anchors, nil
+}
+
+// KeyForRelationship returns the anchor for the verification method with
+// the given fragment (with or without the leading "#"), provided it is
+// listed under rel.
+func (doc *Document) KeyForRelationship(rel string, fragment string) (Anchor, error) {
+	refs := doc.relationship(rel)
+	if refs == nil {
+		return nil, fmt.Errorf("unknown verification relationship %q", rel)
+	}
+
+	want := doc.ID + "#" + strings.TrimPrefix(fragment, "#")
+	for _, ref := range *refs {
+		if doc.absoluteID(ref) == want {
+			return doc.resolveMethod(rel, ref)
+		}
+	}
+
+	return nil, fmt.Errorf("%s not listed under %s: %w", want, rel, ErrNoVerificationMethod)
+}
+
+// VerifyFor verifies sig over data and checks that the signing key is
+// authorized for the verification relationship rel: for anchors backed by a
+// DID Document the signature must verify with one of the methods listed
+// under rel, so e.g. an assertionMethod-only key cannot authenticate. The
+// single key of a did:key, and of anchors without a document, is
+// implicitly authorized for every relationship.
+func VerifyFor(anchor Anchor, rel string, data, sig []byte) error {
+	if (&Document{}).relationship(rel) == nil {
+		return fmt.Errorf("unknown verification relationship %q", rel)
+	}
+
+	docAnchor, ok := anchor.(DocumentAnchor)
+	if !ok || anchor.DID().Method() == "key" {
+		return anchor.Verify(data, sig)
+	}
+
+	doc, err := docAnchor.Document()
+	if err != nil {
+		return fmt.Errorf("anchor document: %w", err)
+	}
+
+	keys := doc.resolvableKeys(rel)
+	if len(keys) == 0 {
+		return fmt.Errorf("%s has no %s methods: %w", anchor.DID(), rel, ErrNoVerificationMethod)
+	}
+
+	for _, key := range keys {
+		if err := key.Verify(data, sig); err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no %s method of %s verifies the signature: %w", rel, anchor.DID(), ErrInvalidSignature)
+}
+
+// AuthenticationKeys returns anchors for the authentication methods,
+// skipping any that cannot be resolved.
+func (doc *Document) AuthenticationKeys() []Anchor {
+	return doc.resolvableKeys(RelationshipAuthentication)
+}
+
+// AssertionKeys returns anchors for the assertionMethod methods, skipping
+// any that cannot be resolved.
+func (doc *Document) AssertionKeys() []Anchor {
+	return doc.resolvableKeys(RelationshipAssertionMethod)
+}
+
+func (doc *Document) resolvableKeys(rel string) []Anchor {
+	var anchors []Anchor
+	for _, ref := range *doc.relationship(rel) {
+		anchor, err := doc.resolveMethod(rel, ref)
+		if err != nil {
+			log.Debugf("skipping %s method %s: %s", rel, ref, err)
+			continue
+		}
+		anchors = append(anchors, anchor)
+	}
+
+	return anchors
+}
+
+// resolveMethod finds the verification method ref, embedded under rel or
+// in verificationMethod, and returns its anchor.
+func (doc *Document) resolveMethod(rel, ref string) (Anchor, error) {
+	id := doc.absoluteID(ref)
+	if vm, ok := doc.embedded[rel][id]; ok {
+		return vm.anchor(doc.ID)
+	}
+
+	for _, vm := range doc.VerificationMethod {
+		if doc.absoluteID(vm.ID) == id {
+			return vm.anchor(doc.ID)
+		}
+	}
+
+	return nil, fmt.Errorf("%s: %w", id, ErrNoVerificationMethod)
+}
+
+// PublicKey decodes the verification method's key from publicKeyMultibase
+// or publicKeyJwk.
+func (vm *VerificationMethod) PublicKey() (crypto.PubKey, error) {
+	switch {
+	case vm.PublicKeyMultibase != "":
+		return parseKeyURI(keyPrefix + ":" + vm.PublicKeyMultibase)
+	case vm.PublicKeyJwk != nil:
+		return vm.PublicKeyJwk.PublicKey()
+	default:
+		return nil, fmt.Errorf("verification method %s has no public key: %w", vm.ID, ErrInvalidKeyType)
+	}
+}
+
+func (vm *VerificationMethod) anchor(subject string) (Anchor, error) {
+	pubk, err := vm.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("verification method %s: %w", vm.ID, err)
+	}
+
+	return NewAnchor(DID{URI: subject}, pubk), nil
+}
@@ -0,0 +1,257 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+func TestDocumentFromDIDKey(t *testing.T) {
+	cases := []struct {
+		keyType int
+		vmType  string
+	}{
+		{crypto.Ed25519, Ed25519VerificationKey2020},
+		{crypto.Secp256k1, EcdsaSecp256k1VerificationKey2019},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.vmType, func(t *testing.T) {
+			_, pubk, err := crypto.GenerateKeyPair(tc.keyType)
+			require.NoError(t, err)
+
+			did := FromPublicKey(pubk)
+			doc, err := DocumentFromDID(did)
+			require.NoError(t, err)
+
+			require.Equal(t, did.URI, doc.ID)
+
+			vm := doc.VerificationMethod[0]
+			require.Equal(t, tc.vmType, vm.Type)
+			require.Equal(t, did.URI, vm.Controller)
+			require.Equal(t, did.URI+"#"+did.Identifier(), vm.ID)
+			require.Equal(t, did.Identifier(), vm.PublicKeyMultibase)
+			require.Equal(t, []string{vm.ID}, doc.Authentication)
+			require.Equal(t, []string{vm.ID}, doc.AssertionMethod)
+			require.Equal(t, []string{vm.ID}, doc.CapabilityInvocation)
+			require.Equal(t, []string{vm.ID}, doc.CapabilityDelegation)
+
+			if tc.keyType != crypto.Ed25519 {
+				require.Len(t, doc.VerificationMethod, 1)
+				require.Empty(t, doc.KeyAgreement)
+				return
+			}
+
+			// the derived X25519 key is the keyAgreement method
+			require.Len(t, doc.VerificationMethod, 2)
+			_, xID, err := KeyAgreementKey(did)
+			require.NoError(t, err)
+			require.Equal(t, xID, doc.VerificationMethod[1].ID)
+			require.Equal(t, []string{xID}, doc.KeyAgreement)
+		})
+	}
+}
+
+func TestAnchorDocumentJSON(t *testing.T) {
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+
+	did := DID{URI: "did:web:example.com"}
+	anchor := NewAnchor(did, pubk).(*PublicKeyAnchor)
+
+	doc, err := anchor.Document()
+	require.NoError(t, err)
+	require.Equal(t, "did:web:example.com#key-1", doc.VerificationMethod[0].ID)
+
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &raw))
+	for _, field := range []string{"@context", "id", "verificationMethod", "authentication", "assertionMethod"} {
+		require.Contains(t, raw, field)
+	}
+	require.True(t, strings.HasPrefix(doc.VerificationMethod[0].PublicKeyMultibase, "z"))
+}
+
+func TestDocumentMultikey(t *testing.T) {
+	_, rsaPub, err := libp2p_crypto.GenerateRSAKeyPair(2048, rand.Reader)
+	require.NoError(t, err)
+	xkey, _, err := KeyAgreementKey(FromPublicKey(mustPrivKey(t).GetPublic()))
+	require.NoError(t, err)
+
+	for _, pubk := range []crypto.PubKey{rsaPub, xkey} {
+		doc, err := NewDocument(DID{URI: "did:web:example.com"}, pubk)
+		require.NoError(t, err)
+		require.Equal(t, Multikey, doc.VerificationMethod[0].Type)
+		require.Contains(t, doc.Context, multikeyContext)
+	}
+}
+
+func TestDocumentUnsupportedKey(t *testing.T) {
+	_, err := NewDocument(DID{URI: "did:web:example.com"}, bogusKey{})
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+}
+
+func TestParseDocumentRelationships(t *testing.T) {
+	_, authPub, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	_, assertPub, err := crypto.GenerateKeyPair(crypto.Secp256k1)
+	require.NoError(t, err)
+	_, delegatePub, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+
+	authKey, err := keyMultibase(authPub)
+	require.NoError(t, err)
+	assertKey, err := keyMultibase(assertPub)
+	require.NoError(t, err)
+	delegateJWK, err := publicKeyJWK(delegatePub)
+	require.NoError(t, err)
+	jwkData, err := json.Marshal(delegateJWK)
+	require.NoError(t, err)
+
+	data := []byte(`{
+		"@context": ["https://www.w3.org/ns/did/v1"],
+		"id": "did:web:example.com",
+		"verificationMethod": [
+			{"id": "#key-1", "type": "Ed25519VerificationKey2020", "controller": "did:web:example.com", "publicKeyMultibase": "` + authKey + `"},
+			{"id": "did:web:example.com#key-3", "type": "JsonWebKey2020", "controller": "did:web:example.com", "publicKeyJwk": ` + string(jwkData) + `}
+		],
+		"authentication": ["#key-1"],
+		"assertionMethod": [
+			{"id": "#key-2", "type": "EcdsaSecp256k1VerificationKey2019", "controller": "did:web:example.com", "publicKeyMultibase": "` + assertKey + `"}
+		],
+		"keyAgreement": [
+			{"id": "#key-4", "type": "X25519KeyAgreementKey2020", "controller": "did:web:example.com", "publicKeyMultibase": "z6LSj72tK8brWgZja8NLRwPigth2T9QRiG1uH9oKZuKjdh9p"}
+		],
+		"capabilityDelegation": ["did:web:example.com#key-3"]
+	}`)
+
+	doc, err := ParseDocument(data)
+	require.NoError(t, err)
+
+	auth := doc.AuthenticationKeys()
+	require.Len(t, auth, 1)
+	require.True(t, authPub.Equals(auth[0].PublicKey()))
+	require.Equal(t, "did:web:example.com", auth[0].DID().URI)
+
+	// embedded methods resolve only under their relationship
+	anchor, err := doc.KeyForRelationship(RelationshipAssertionMethod, "key-2")
+	require.NoError(t, err)
+	require.True(t, assertPub.Equals(anchor.PublicKey()))
+	_, err = doc.KeyForRelationship(RelationshipAuthentication, "#key-2")
+	require.ErrorIs(t, err, ErrNoVerificationMethod)
+
+	anchor, err = doc.KeyForRelationship(RelationshipCapabilityDelegation, "#key-3")
+	require.NoError(t, err)
+	require.True(t, delegatePub.Equals(anchor.PublicKey()))
+
+	agreement, err := doc.KeysForRelationship(RelationshipKeyAgreement)
+	require.NoError(t, err)
+	require.Len(t, agreement, 1)
+	require.Equal(t, KeyTypeX25519, agreement[0].PublicKey().Type())
+
+	invocation, err := doc.KeysForRelationship(RelationshipCapabilityInvocation)
+	require.NoError(t, err)
+	require.Empty(t, invocation)
+
+	_, err = doc.KeysForRelationship("bogus")
+	require.Error(t, err)
+
+	// embedded methods survive a round trip
+	encoded, err := json.Marshal(doc)
+	require.NoError(t, err)
+	again, err := ParseDocument(encoded)
+	require.NoError(t, err)
+	anchor, err = again.KeyForRelationship(RelationshipAssertionMethod, "key-2")
+	require.NoError(t, err)
+	require.True(t, assertPub.Equals(anchor.PublicKey()))
+}
+
+func TestParseDocumentInvalid(t *testing.T) {
+	_, err := ParseDocument([]byte(`{"id": "not-a-did"}`))
+	require.ErrorIs(t, err, ErrInvalidDID)
+
+	_, err = ParseDocument([]byte(`{"id": "did:web:example.com", "authentication": [42]}`))
+	require.Error(t, err)
+
+	doc, err := ParseDocument([]byte(`{"id": "did:web:example.com", "authentication": ["#missing"]}`))
+	require.NoError(t, err)
+	require.Empty(t, doc.AuthenticationKeys())
+	_, err = doc.KeysForRelationship(RelationshipAuthentication)
+	require.ErrorIs(t, err, ErrNoVerificationMethod)
+}
+
+func TestVerifyFor(t *testing.T) {
+	authPriv, authPub, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	assertPriv, assertPub, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	authKey, err := keyMultibase(authPub)
+	require.NoError(t, err)
+	assertKey, err := keyMultibase(assertPub)
+	require.NoError(t, err)
+
+	doc, err := ParseDocument([]byte(`{
+		"@context": ["https://www.w3.org/ns/did/v1"],
+		"id": "did:docs:example",
+		"verificationMethod": [
+			{"id": "#key-1", "type": "Multikey", "controller": "did:docs:example", "publicKeyMultibase": "` + authKey + `"},
+			{"id": "#key-2", "type": "Multikey", "controller": "did:docs:example", "publicKeyMultibase": "` + assertKey + `"}
+		],
+		"authentication": ["#key-1"],
+		"assertionMethod": ["#key-2"]
+	}`))
+	require.NoError(t, err)
+	did := DID{URI: "did:docs:example"}
+	anchor := &parsedDocumentAnchor{PublicKeyAnchor: &PublicKeyAnchor{did: did, pubk: authPub}, doc: doc}
+
+	data := []byte("challenge")
+	authSig, err := authPriv.Sign(data)
+	require.NoError(t, err)
+	assertSig, err := assertPriv.Sign(data)
+	require.NoError(t, err)
+
+	require.NoError(t, VerifyFor(anchor, RelationshipAuthentication, data, authSig))
+	require.NoError(t, VerifyFor(anchor, RelationshipAssertionMethod, data, assertSig))
+	require.ErrorIs(t, VerifyFor(anchor, RelationshipAuthentication, data, assertSig), ErrInvalidSignature)
+	require.ErrorIs(t, VerifyFor(anchor, RelationshipAssertionMethod, data, authSig), ErrInvalidSignature)
+	require.ErrorIs(t, VerifyFor(anchor, RelationshipCapabilityInvocation, data, authSig), ErrNoVerificationMethod)
+	require.Error(t, VerifyFor(anchor, "bogus", data, authSig))
+
+	// a did:key key is authorized for every relationship
+	keyAnchor := NewAnchor(FromPublicKey(authPub), authPub)
+	for _, rel := range []string{RelationshipAuthentication, RelationshipAssertionMethod, RelationshipKeyAgreement, RelationshipCapabilityInvocation, RelationshipCapabilityDelegation} {
+		require.NoError(t, VerifyFor(keyAnchor, rel, data, authSig), rel)
+	}
+	require.ErrorIs(t, VerifyFor(keyAnchor, RelationshipAuthentication, data, assertSig), ErrInvalidSignature)
+
+	// did:peer and did:web key documents authorize the key for every
+	// signature relationship; keyAgreement only lists the X25519 key
+	peerDID, err := FromPeerKey(authPub)
+	require.NoError(t, err)
+	peerAnchor, err := GetAnchorForDID(peerDID)
+	require.NoError(t, err)
+	webAnchor := NewAnchor(DID{URI: "did:web:example.com"}, authPub)
+	for _, anchor := range []Anchor{peerAnchor, webAnchor} {
+		for _, rel := range []string{RelationshipAuthentication, RelationshipAssertionMethod, RelationshipCapabilityInvocation, RelationshipCapabilityDelegation} {
+			require.NoError(t, VerifyFor(anchor, rel, data, authSig), "%s %s", anchor.DID(), rel)
+		}
+		require.ErrorIs(t, VerifyFor(anchor, RelationshipKeyAgreement, data, authSig), ErrInvalidSignature)
+		require.ErrorIs(t, VerifyFor(anchor, RelationshipCapabilityInvocation, data, assertSig), ErrInvalidSignature)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/depinkit/crypto"
+)
+
+// SigningDomain selects the pre-hash a secp256k1 key signs, so that one key
+// can sign for several chains.
+type SigningDomain int
+
+const (
+	// SigningDomainLibp2p signs SHA-256(data), the libp2p default.
+	SigningDomainLibp2p SigningDomain = iota
+	// SigningDomainBitcoin signs SHA-256(SHA-256(data)).
+	SigningDomainBitcoin
+	// SigningDomainEthereum signs Keccak-256(data).
+	SigningDomainEthereum
+)
+
+func (d SigningDomain) String() string {
+	switch d {
+	case SigningDomainLibp2p:
+		return "libp2p"
+	case SigningDomainBitcoin:
+		return "bitcoin"
+	case SigningDomainEthereum:
+		return "ethereum"
+	default:
+		return fmt.Sprintf("SigningDomain(%d)", int(d))
+	}
+}
+
+// hasher returns the pre-hash of the domain.
+func (d SigningDomain) hasher() (Hasher, error) {
+	switch d {
+	case SigningDomainLibp2p:
+		return func(data []byte) []byte {
+			digest := sha256.Sum256(data)
+			return digest[:]
+		}, nil
+	case SigningDomainBitcoin:
+		return func(data []byte) []byte {
+			first := sha256.Sum256(data)
+			digest := sha256.Sum256(first[:])
+			return digest[:]
+		}, nil
+	case SigningDomainEthereum:
+		return func(data []byte) []byte {
+			return keccak256(data)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown signing domain %s", d)
+	}
+}
+
+// NewProviderWithDomain creates a provider for did that signs the domain
+// pre-hash of the data with the secp256k1 key privk; see SigningDomain.
+// Libp2p domain signatures are identical to those of NewProvider.
+func NewProviderWithDomain(did DID, privk crypto.PrivKey, domain SigningDomain) (Provider, error) {
+	if privk.Type() != crypto.Secp256k1 {
+		return nil, fmt.Errorf("signing domains require a secp256k1 key, got key type %d: %w", privk.Type(), ErrInvalidKeyType)
+	}
+
+	hasher, err := domain.hasher()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewProviderWithHasher(did, privk, hasher)
+}
+
+// NewAnchorWithDomain creates an anchor for did that verifies signatures
+// made by NewProviderWithDomain in the same domain.
+func NewAnchorWithDomain(did DID, pubk crypto.PubKey, domain SigningDomain) (Anchor, error) {
+	if pubk.Type() != crypto.Secp256k1 && pubk.Type() != crypto.Eth {
+		return nil, fmt.Errorf("signing domains require a secp256k1 key, got key type %d: %w", pubk.Type(), ErrInvalidKeyType)
+	}
+
+	hasher, err := domain.hasher()
+	if err != nil {
+		return nil, err
+	}
+
+	return &HasherAnchor{
+		PublicKeyAnchor: &PublicKeyAnchor{did: did, pubk: pubk},
+		hasher:          hasher,
+	}, nil
+}
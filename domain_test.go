@@ -0,0 +1,103 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpECDSA "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+func TestSigningDomains(t *testing.T) {
+	privk, pubk, err := crypto.GenerateKeyPair(crypto.Secp256k1)
+	require.NoError(t, err)
+	did := FromPublicKey(pubk)
+	raw, err := pubk.Raw()
+	require.NoError(t, err)
+	key, err := secp256k1.ParsePubKey(raw)
+	require.NoError(t, err)
+
+	data := []byte("multi-chain message")
+	once := sha256.Sum256(data)
+	twice := sha256.Sum256(once[:])
+	digests := map[SigningDomain][]byte{
+		SigningDomainLibp2p:   once[:],
+		SigningDomainBitcoin:  twice[:],
+		SigningDomainEthereum: keccak256(data),
+	}
+
+	sigs := map[SigningDomain][]byte{}
+	for domain, digest := range digests {
+		provider, err := NewProviderWithDomain(did, privk, domain)
+		require.NoError(t, err, domain)
+
+		sig, err := provider.Sign(data)
+		require.NoError(t, err, domain)
+		require.NoError(t, provider.Anchor().Verify(data, sig), domain)
+
+		anchor, err := NewAnchorWithDomain(did, pubk, domain)
+		require.NoError(t, err, domain)
+		require.NoError(t, anchor.Verify(data, sig), domain)
+		require.ErrorIs(t, anchor.Verify([]byte("other"), sig), ErrInvalidSignature, domain)
+
+		parsed, err := secpECDSA.ParseDERSignature(sig)
+		require.NoError(t, err, domain)
+		require.True(t, parsed.Verify(digest, key), domain)
+
+		sigs[domain] = sig
+	}
+
+	// signatures do not cross domains
+	for domain := range digests {
+		anchor, err := NewAnchorWithDomain(did, pubk, domain)
+		require.NoError(t, err)
+		for other, sig := range sigs {
+			if other != domain {
+				require.ErrorIs(t, anchor.Verify(data, sig), ErrInvalidSignature, "%s signature in %s", other, domain)
+			}
+		}
+	}
+
+	// the libp2p domain matches the default provider
+	require.NoError(t, NewAnchor(did, pubk).Verify(data, sigs[SigningDomainLibp2p]))
+	sig, err := NewProvider(did, privk).Sign(data)
+	require.NoError(t, err)
+	anchor, err := NewAnchorWithDomain(did, pubk, SigningDomainLibp2p)
+	require.NoError(t, err)
+	require.NoError(t, anchor.Verify(data, sig))
+}
+
+func TestSigningDomainErrors(t *testing.T) {
+	privk, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	did := FromPublicKey(pubk)
+
+	_, err = NewProviderWithDomain(did, privk, SigningDomainEthereum)
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+	_, err = NewAnchorWithDomain(did, pubk, SigningDomainEthereum)
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+
+	privk, pubk, err = crypto.GenerateKeyPair(crypto.Secp256k1)
+	require.NoError(t, err)
+	_, err = NewProviderWithDomain(did, privk, SigningDomain(7))
+	require.Error(t, err)
+	require.Equal(t, "SigningDomain(7)", SigningDomain(7).String())
+	require.Equal(t, "bitcoin", SigningDomainBitcoin.String())
+
+	// Eth public keys are secp256k1 keys
+	eth, err := crypto.UnmarshalEthPublicKey(must(pubk.Raw()))
+	require.NoError(t, err)
+	_, err = NewAnchorWithDomain(did, eth, SigningDomainEthereum)
+	require.NoError(t, err)
+}
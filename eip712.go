@@ -0,0 +1,430 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/depinkit/crypto"
+)
+
+const eip712DomainType = "EIP712Domain"
+
+// TypedData is an EIP-712 typed structured data payload, as passed to
+// eth_signTypedData_v4.
+type TypedData struct {
+	Types       map[string][]TypedDataField `json:"types"`
+	PrimaryType string                      `json:"primaryType"`
+	Domain      map[string]interface{}      `json:"domain"`
+	Message     map[string]interface{}      `json:"message"`
+}
+
+// TypedDataField is a member of an EIP-712 struct type.
+type TypedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ParseTypedData decodes a JSON EIP-712 payload; numbers are kept exact.
+func ParseTypedData(data []byte) (*TypedData, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var td TypedData
+	if err := dec.Decode(&td); err != nil {
+		return nil, fmt.Errorf("decode typed data: %w", err)
+	}
+
+	if _, ok := td.Types[eip712DomainType]; !ok {
+		return nil, fmt.Errorf("typed data has no %s type", eip712DomainType)
+	}
+	if _, ok := td.Types[td.PrimaryType]; !ok {
+		return nil, fmt.Errorf("unknown primary type %q", td.PrimaryType)
+	}
+
+	return &td, nil
+}
+
+// Hash returns the EIP-712 signing hash:
+// keccak256(0x19 0x01 || domainSeparator || hashStruct(message)).
+func (td *TypedData) Hash() ([]byte, error) {
+	domain, err := td.hashStruct(eip712DomainType, td.Domain)
+	if err != nil {
+		return nil, fmt.Errorf("hash domain: %w", err)
+	}
+
+	msg, err := td.hashStruct(td.PrimaryType, td.Message)
+	if err != nil {
+		return nil, fmt.Errorf("hash message: %w", err)
+	}
+
+	return keccak256([]byte{0x19, 0x01}, domain, msg), nil
+}
+
+// VerifyTypedData verifies a 65-byte R || S || V wallet signature over an
+// EIP-712 JSON payload, checking that the recovered address belongs to did.
+// did may be an eip155 did:pkh or a did:key with a secp256k1/Eth key.
+func VerifyTypedData(did DID, typedData []byte, sig []byte) error {
+	td, err := ParseTypedData(typedData)
+	if err != nil {
+		return err
+	}
+
+	hash, err := td.Hash()
+	if err != nil {
+		return err
+	}
+
+	recovered, err := recoverEthKey(hash, sig)
+	if err != nil {
+		return err
+	}
+
+	addr, err := didEthAddress(did)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(addr, ethAddress(recovered)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// didEthAddress returns the Ethereum address that controls did.
+func didEthAddress(did DID) ([]byte, error) {
+	if namespace, _, address, ok := pkhParts(did); ok {
+		if namespace != pkhNamespaceEIP155 {
+			return nil, fmt.Errorf("did:pkh namespace %q is not eip155: %w", namespace, ErrInvalidDID)
+		}
+		addr, err := parseEthAddress(address)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidDID, err)
+		}
+		return addr, nil
+	}
+
+	pubk, err := PublicKeyFromDID(did)
+	if err != nil {
+		return nil, fmt.Errorf("public key from did: %w", err)
+	}
+
+	return pubKeyEthAddress(pubk)
+}
+
+// pubKeyEthAddress returns the Ethereum address of a secp256k1 or Eth key.
+func pubKeyEthAddress(pubk crypto.PubKey) ([]byte, error) {
+	switch pubk.Type() {
+	case crypto.Secp256k1, crypto.Eth:
+	default:
+		return nil, fmt.Errorf("key type %d is not an ethereum key: %w", pubk.Type(), ErrInvalidKeyType)
+	}
+
+	raw, err := pubk.Raw()
+	if err != nil {
+		return nil, fmt.Errorf("raw public key: %w", err)
+	}
+
+	key, err := secp256k1.ParsePubKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+
+	return ethAddress(key), nil
+}
+
+func (td *TypedData) hashStruct(typ string, data map[string]interface{}) ([]byte, error) {
+	enc, err := td.encodeData(typ, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return keccak256(enc), nil
+}
+
+// encodeData encodes typeHash || enc(field)* for a struct value.
+func (td *TypedData) encodeData(typ string, data map[string]interface{}) ([]byte, error) {
+	fields := td.Types[typ]
+	enc := make([]byte, 0, 32*(len(fields)+1))
+	enc = append(enc, keccak256([]byte(td.encodeType(typ)))...)
+
+	for _, field := range fields {
+		value, ok := data[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s: missing field %q", typ, field.Name)
+		}
+
+		word, err := td.encodeValue(field.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", typ, field.Name, err)
+		}
+		enc = append(enc, word...)
+	}
+
+	return enc, nil
+}
+
+// encodeType encodes the struct type followed by its referenced struct
+// types in alphabetical order, e.g. "Mail(Person from,Person to)Person(...)".
+func (td *TypedData) encodeType(typ string) string {
+	deps := make(map[string]struct{})
+	td.findDependencies(typ, deps)
+	delete(deps, typ)
+
+	sorted := make([]string, 0, len(deps))
+	for dep := range deps {
+		sorted = append(sorted, dep)
+	}
+	sort.Strings(sorted)
+
+	var b strings.Builder
+	for _, t := range append([]string{typ}, sorted...) {
+		b.WriteString(t)
+		b.WriteByte('(')
+		for i, field := range td.Types[t] {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(field.Type)
+			b.WriteByte(' ')
+			b.WriteString(field.Name)
+		}
+		b.WriteByte(')')
+	}
+
+	return b.String()
+}
+
+func (td *TypedData) findDependencies(typ string, found map[string]struct{}) {
+	typ = baseType(typ)
+	if _, ok := found[typ]; ok {
+		return
+	}
+	if _, ok := td.Types[typ]; !ok {
+		return
+	}
+
+	found[typ] = struct{}{}
+	for _, field := range td.Types[typ] {
+		td.findDependencies(field.Type, found)
+	}
+}
+
+// baseType strips all array suffixes from a type.
+func baseType(typ string) string {
+	if i := strings.IndexByte(typ, '['); i >= 0 {
+		return typ[:i]
+	}
+	return typ
+}
+
+// encodeValue encodes a single value as a 32-byte word; dynamic values,
+// arrays and structs are encoded as their hash.
+func (td *TypedData) encodeValue(typ string, value interface{}) ([]byte, error) {
+	if strings.HasSuffix(typ, "]") {
+		return td.encodeArray(typ, value)
+	}
+
+	if _, ok := td.Types[typ]; ok {
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected object for %s, got %T", typ, value)
+		}
+		return td.hashStruct(typ, data)
+	}
+
+	switch {
+	case typ == "string":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", value)
+		}
+		return keccak256([]byte(s)), nil
+
+	case typ == "bytes":
+		b, err := decodeHexValue(value)
+		if err != nil {
+			return nil, err
+		}
+		return keccak256(b), nil
+
+	case typ == "bool":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected bool, got %T", value)
+		}
+		word := make([]byte, 32)
+		if b {
+			word[31] = 1
+		}
+		return word, nil
+
+	case typ == "address":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected address string, got %T", value)
+		}
+		addr, err := parseEthAddress(s)
+		if err != nil {
+			return nil, err
+		}
+		word := make([]byte, 32)
+		copy(word[12:], addr)
+		return word, nil
+
+	case strings.HasPrefix(typ, "bytes"):
+		n, err := strconv.Atoi(typ[len("bytes"):])
+		if err != nil || n < 1 || n > 32 {
+			return nil, fmt.Errorf("unknown type %q", typ)
+		}
+		b, err := decodeHexValue(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != n {
+			return nil, fmt.Errorf("expected %d bytes for %s, got %d", n, typ, len(b))
+		}
+		word := make([]byte, 32)
+		copy(word, b)
+		return word, nil
+
+	case strings.HasPrefix(typ, "uint"):
+		return encodeInteger(typ, typ[len("uint"):], false, value)
+
+	case strings.HasPrefix(typ, "int"):
+		return encodeInteger(typ, typ[len("int"):], true, value)
+	}
+
+	return nil, fmt.Errorf("unknown type %q", typ)
+}
+
+func (td *TypedData) encodeArray(typ string, value interface{}) ([]byte, error) {
+	i := strings.LastIndexByte(typ, '[')
+	if i < 0 {
+		return nil, fmt.Errorf("unknown type %q", typ)
+	}
+	elemType, size := typ[:i], typ[i+1:len(typ)-1]
+
+	elems, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected array for %s, got %T", typ, value)
+	}
+
+	if size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil {
+			return nil, fmt.Errorf("unknown type %q", typ)
+		}
+		if len(elems) != n {
+			return nil, fmt.Errorf("expected %d elements for %s, got %d", n, typ, len(elems))
+		}
+	}
+
+	enc := make([]byte, 0, 32*len(elems))
+	for j, elem := range elems {
+		word, err := td.encodeValue(elemType, elem)
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %w", j, err)
+		}
+		enc = append(enc, word...)
+	}
+
+	return keccak256(enc), nil
+}
+
+// encodeInteger encodes an intN/uintN value, given as a JSON number or a
+// decimal or 0x-prefixed hex string, as a 32-byte two's complement word.
+func encodeInteger(typ, bits string, signed bool, value interface{}) ([]byte, error) {
+	size := 256
+	if bits != "" {
+		var err error
+		size, err = strconv.Atoi(bits)
+		if err != nil || size < 8 || size > 256 || size%8 != 0 {
+			return nil, fmt.Errorf("unknown type %q", typ)
+		}
+	}
+
+	var s string
+	switch v := value.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		return nil, fmt.Errorf("expected integer for %s, got %T", typ, value)
+	}
+
+	n, ok := new(big.Int), false
+	if hexStr, isHex := strings.CutPrefix(s, "0x"); isHex {
+		n, ok = n.SetString(hexStr, 16)
+	} else {
+		n, ok = n.SetString(s, 10)
+	}
+	if !ok {
+		return nil, fmt.Errorf("invalid integer %q for %s", s, typ)
+	}
+
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(size))
+	if signed {
+		limit.Rsh(limit, 1)
+		if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+			return nil, fmt.Errorf("integer %s overflows %s", n, typ)
+		}
+	} else if n.Sign() < 0 || n.Cmp(limit) >= 0 {
+		return nil, fmt.Errorf("integer %s overflows %s", n, typ)
+	}
+
+	if n.Sign() < 0 {
+		n.Add(n, new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+
+	return n.FillBytes(make([]byte, 32)), nil
+}
+
+func decodeHexValue(value interface{}) ([]byte, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected hex string, got %T", value)
+	}
+
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("decode hex: %w", err)
+	}
+
+	return b, nil
+}
+
+func parseEthAddress(s string) ([]byte, error) {
+	addr, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(s), "0x"))
+	if err != nil || len(addr) != 20 {
+		return nil, fmt.Errorf("invalid ethereum address %q", s)
+	}
+
+	return addr, nil
+}
+
+func keccak256(data ...[]byte) []byte {
+	hasher := sha3.NewLegacyKeccak256()
+	for _, b := range data {
+		hasher.Write(b)
+	}
+	return hasher.Sum(nil)
+}
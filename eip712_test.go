@@ -0,0 +1,169 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpECDSA "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+// the example from the EIP-712 specification
+const eip712MailExample = `{
+  "types": {
+    "EIP712Domain": [
+      {"name": "name", "type": "string"},
+      {"name": "version", "type": "string"},
+      {"name": "chainId", "type": "uint256"},
+      {"name": "verifyingContract", "type": "address"}
+    ],
+    "Person": [
+      {"name": "name", "type": "string"},
+      {"name": "wallet", "type": "address"}
+    ],
+    "Mail": [
+      {"name": "from", "type": "Person"},
+      {"name": "to", "type": "Person"},
+      {"name": "contents", "type": "string"}
+    ]
+  },
+  "primaryType": "Mail",
+  "domain": {
+    "name": "Ether Mail",
+    "version": "1",
+    "chainId": 1,
+    "verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+  },
+  "message": {
+    "from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+    "to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+    "contents": "Hello, Bob!"
+  }
+}`
+
+func TestTypedDataHashSpecExample(t *testing.T) {
+	td, err := ParseTypedData([]byte(eip712MailExample))
+	require.NoError(t, err)
+
+	require.Equal(t, "Mail(Person from,Person to,string contents)Person(string name,address wallet)", td.encodeType("Mail"))
+
+	domain, err := td.hashStruct(eip712DomainType, td.Domain)
+	require.NoError(t, err)
+	require.Equal(t, "f2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f", hex.EncodeToString(domain))
+
+	msg, err := td.hashStruct(td.PrimaryType, td.Message)
+	require.NoError(t, err)
+	require.Equal(t, "c52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e", hex.EncodeToString(msg))
+
+	hash, err := td.Hash()
+	require.NoError(t, err)
+	require.Equal(t, "be609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2", hex.EncodeToString(hash))
+
+	// signed with keccak256("cow"), per the specification
+	sig, err := hex.DecodeString("4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d" +
+		"07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b91562" + "1c")
+	require.NoError(t, err)
+
+	did := DID{URI: "did:pkh:eip155:1:0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"}
+	require.NoError(t, VerifyTypedData(did, []byte(eip712MailExample), sig))
+
+	other := DID{URI: "did:pkh:eip155:1:0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"}
+	require.ErrorIs(t, VerifyTypedData(other, []byte(eip712MailExample), sig), ErrInvalidSignature)
+}
+
+const eip712NestedExample = `{
+  "types": {
+    "EIP712Domain": [
+      {"name": "name", "type": "string"},
+      {"name": "chainId", "type": "uint256"}
+    ],
+    "Item": [
+      {"name": "id", "type": "uint64"},
+      {"name": "delta", "type": "int32"},
+      {"name": "tags", "type": "string[]"},
+      {"name": "digest", "type": "bytes32"}
+    ],
+    "Order": [
+      {"name": "items", "type": "Item[]"},
+      {"name": "grid", "type": "uint8[2][]"},
+      {"name": "payload", "type": "bytes"},
+      {"name": "final", "type": "bool"}
+    ]
+  },
+  "primaryType": "Order",
+  "domain": {"name": "Shop", "chainId": "0x89"},
+  "message": {
+    "items": [
+      {"id": "18446744073709551615", "delta": -7, "tags": ["a", "b"], "digest": "0x0000000000000000000000000000000000000000000000000000000000000001"},
+      {"id": 2, "delta": 3, "tags": [], "digest": "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"}
+    ],
+    "grid": [[1, 2], [3, 4]],
+    "payload": "0xdeadbeef",
+    "final": true
+  }
+}`
+
+func TestVerifyTypedDataNested(t *testing.T) {
+	td, err := ParseTypedData([]byte(eip712NestedExample))
+	require.NoError(t, err)
+	require.Equal(t, "Order(Item[] items,uint8[2][] grid,bytes payload,bool final)Item(uint64 id,int32 delta,string[] tags,bytes32 digest)", td.encodeType("Order"))
+
+	hash, err := td.Hash()
+	require.NoError(t, err)
+
+	sk, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	compact := secpECDSA.SignCompact(sk, hash, false)
+	sig := append(compact[1:], compact[0])
+
+	pubk, err := crypto.UnmarshalEthPublicKey(sk.PubKey().SerializeCompressed())
+	require.NoError(t, err)
+	require.NoError(t, VerifyTypedData(FromPublicKey(pubk), []byte(eip712NestedExample), sig))
+
+	pkh := DID{URI: "did:pkh:eip155:137:0x" + hex.EncodeToString(ethAddress(sk.PubKey()))}
+	require.NoError(t, VerifyTypedData(pkh, []byte(eip712NestedExample), sig))
+
+	_, edPubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	require.ErrorIs(t, VerifyTypedData(FromPublicKey(edPubk), []byte(eip712NestedExample), sig), ErrInvalidKeyType)
+}
+
+func TestTypedDataInvalid(t *testing.T) {
+	types := `"types": {"EIP712Domain": [], "T": [{"name": "v", "type": %q}]}, "primaryType": "T", "domain": {}`
+	cases := map[string]string{
+		"uint8 overflow":    `{` + fmt.Sprintf(types, "uint8") + `, "message": {"v": 256}}`,
+		"negative uint":     `{` + fmt.Sprintf(types, "uint256") + `, "message": {"v": -1}}`,
+		"int8 overflow":     `{` + fmt.Sprintf(types, "int8") + `, "message": {"v": -129}}`,
+		"short bytes4":      `{` + fmt.Sprintf(types, "bytes4") + `, "message": {"v": "0x0102"}}`,
+		"bad address":       `{` + fmt.Sprintf(types, "address") + `, "message": {"v": "0x1234"}}`,
+		"fixed array size":  `{` + fmt.Sprintf(types, "bool[2]") + `, "message": {"v": [true]}}`,
+		"unknown type":      `{` + fmt.Sprintf(types, "float") + `, "message": {"v": 1}}`,
+		"missing field":     `{` + fmt.Sprintf(types, "bool") + `, "message": {}}`,
+		"wrong value type":  `{` + fmt.Sprintf(types, "string") + `, "message": {"v": 1}}`,
+		"bad integer width": `{` + fmt.Sprintf(types, "uint7") + `, "message": {"v": 1}}`,
+	}
+
+	for name, payload := range cases {
+		td, err := ParseTypedData([]byte(payload))
+		require.NoError(t, err, name)
+		_, err = td.Hash()
+		require.Error(t, err, name)
+	}
+
+	_, err := ParseTypedData([]byte(`{"types": {"T": []}, "primaryType": "T"}`))
+	require.Error(t, err)
+	_, err = ParseTypedData([]byte(`{"types": {"EIP712Domain": []}, "primaryType": "T"}`))
+	require.Error(t, err)
+}
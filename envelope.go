@@ -0,0 +1,59 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"fmt"
+)
+
+// SignatureEnvelope is a self-describing signature: it names the signer and
+// the JOSE algorithm alongside the raw signature bytes, so a verifier does
+// not need to know the key type in advance.
+type SignatureEnvelope struct {
+	DID DID    `json:"did" cbor:"did"`
+	Alg string `json:"alg" cbor:"alg"`
+	Sig []byte `json:"sig" cbor:"sig"`
+}
+
+// SignEnvelope signs data with the provider and wraps the signature in an
+// envelope. Providers whose anchor has no single algorithm (e.g. did:multi)
+// cannot produce envelopes.
+func SignEnvelope(p Provider, data []byte) (SignatureEnvelope, error) {
+	alg := p.Anchor().Algorithm()
+	if alg == "" {
+		return SignatureEnvelope{}, fmt.Errorf("no signature algorithm for %s: %w", p.DID(), ErrInvalidKeyType)
+	}
+
+	sig, err := p.Sign(data)
+	if err != nil {
+		return SignatureEnvelope{}, fmt.Errorf("sign: %w", err)
+	}
+
+	return SignatureEnvelope{
+		DID: p.DID(),
+		Alg: alg,
+		Sig: sig,
+	}, nil
+}
+
+// VerifyEnvelope resolves the anchor for env.DID in the trust context,
+// checks that env.Alg matches the anchor's algorithm and verifies the
+// signature over data.
+func VerifyEnvelope(ctx TrustContext, data []byte, env SignatureEnvelope) error {
+	anchor, err := ctx.GetAnchor(env.DID)
+	if err != nil {
+		return fmt.Errorf("get anchor: %w", err)
+	}
+
+	if alg := anchor.Algorithm(); alg == "" || alg != env.Alg {
+		return fmt.Errorf("envelope algorithm %q does not match anchor algorithm %q: %w", env.Alg, alg, ErrAlgorithmMismatch)
+	}
+
+	return anchor.Verify(data, env.Sig)
+}
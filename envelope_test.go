@@ -0,0 +1,47 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+func TestSignatureEnvelope(t *testing.T) {
+	data := []byte("hello world")
+
+	for _, kt := range []int{crypto.Ed25519, crypto.Secp256k1} {
+		privk, _, err := crypto.GenerateKeyPair(kt)
+		require.NoError(t, err)
+		provider, err := ProviderFromPrivateKey(privk)
+		require.NoError(t, err)
+
+		env, err := SignEnvelope(provider, data)
+		require.NoError(t, err)
+		require.Equal(t, provider.DID(), env.DID)
+		require.Equal(t, provider.Anchor().Algorithm(), env.Alg)
+
+		raw, err := json.Marshal(env)
+		require.NoError(t, err)
+		var decoded SignatureEnvelope
+		require.NoError(t, json.Unmarshal(raw, &decoded))
+		require.Equal(t, env, decoded)
+
+		ctx := NewTrustContext()
+		require.NoError(t, VerifyEnvelope(ctx, data, decoded))
+		require.ErrorIs(t, VerifyEnvelope(ctx, []byte("other"), decoded), ErrInvalidSignature)
+
+		decoded.Alg = AlgES256
+		require.ErrorIs(t, VerifyEnvelope(ctx, data, decoded), ErrAlgorithmMismatch)
+	}
+}
@@ -10,15 +10,46 @@ package did
 
 import (
 	"errors"
+	"fmt"
 )
 
 var (
-	ErrInvalidDID       = errors.New("invalid DID")
-	ErrInvalidKeyType   = errors.New("invalid key type")
-	ErrInvalidSignature = errors.New("signature verification failed")
-	ErrNoProvider       = errors.New("no provider")
-	ErrNoAnchorMethod   = errors.New("no anchor method")
-	ErrHardwareKey      = errors.New("hardware key")
+	ErrInvalidDID           = errors.New("invalid DID")
+	ErrInvalidKeyType       = errors.New("invalid key type")
+	ErrInvalidSignature     = errors.New("signature verification failed")
+	ErrMalformedSignature   = errors.New("malformed signature")
+	ErrSignatureExpired     = errors.New("signature expired")
+	ErrAlgorithmMismatch    = errors.New("signature algorithm mismatch")
+	ErrNoProvider           = errors.New("no provider")
+	ErrNoAnchorMethod       = errors.New("no anchor method")
+	ErrHardwareKey          = errors.New("hardware key")
+	ErrMultiKey             = errors.New("multi key")
+	ErrLedgerCommand        = errors.New("ledger command failed")
+	ErrNoVerificationMethod = errors.New("no verification method")
+	ErrEncryptedKey         = errors.New("encrypted private key")
+	ErrRateLimited          = errors.New("rate limited")
+	ErrRevoked              = errors.New("revoked")
 
 	ErrTODO = errors.New("TODO")
 )
+
+// DIDError records the operation and the DID (or raw string) that failed;
+// it unwraps to the underlying error, so errors.Is against the sentinels
+// still works.
+type DIDError struct {
+	Op  string
+	DID string
+	Err error
+}
+
+func (e *DIDError) Error() string {
+	if e.DID == "" {
+		return fmt.Sprintf("%s: %s", e.Op, e.Err)
+	}
+
+	return fmt.Sprintf("%s %s: %s", e.Op, e.DID, e.Err)
+}
+
+func (e *DIDError) Unwrap() error {
+	return e.Err
+}
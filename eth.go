@@ -0,0 +1,243 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpECDSA "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/crypto/pb"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/depinkit/crypto"
+)
+
+const (
+	ethSignMagic = "\x19Ethereum Signed Message:\n"
+
+	// ethSignatureSize is the size of a wallet signature: R || S || V.
+	ethSignatureSize = 65
+)
+
+// EthPersonalSignAnchor verifies signatures produced by Ethereum wallets
+// with personal_sign (EIP-191): the signature is the 65-byte R || S || V
+// form over the Keccak-256 hash of the prefixed message.
+type EthPersonalSignAnchor struct {
+	*PublicKeyAnchor
+	key *secp256k1.PublicKey
+}
+
+// NewEthPersonalSignAnchor creates a personal_sign anchor for a secp256k1
+// or Eth public key.
+func NewEthPersonalSignAnchor(did DID, pubk crypto.PubKey) (Anchor, error) {
+	switch pubk.Type() {
+	case crypto.Secp256k1, crypto.Eth:
+	default:
+		return nil, fmt.Errorf("key type %d is not an ethereum key: %w", pubk.Type(), ErrInvalidKeyType)
+	}
+
+	raw, err := pubk.Raw()
+	if err != nil {
+		return nil, fmt.Errorf("raw public key: %w", err)
+	}
+
+	key, err := secp256k1.ParsePubKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+
+	return &EthPersonalSignAnchor{
+		PublicKeyAnchor: &PublicKeyAnchor{did: did, pubk: pubk},
+		key:             key,
+	}, nil
+}
+
+// Verify verifies a personal_sign signature over data; V may be either
+// 27/28 or a raw recovery id of 0/1.
+func (a *EthPersonalSignAnchor) Verify(data []byte, sig []byte) error {
+	recovered, err := recoverEthKey(ethPersonalHash(data), sig)
+	if err != nil {
+		return err
+	}
+
+	if !recovered.IsEqual(a.key) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// VerifyRecover verifies a personal_sign signature over data and returns
+// the recovered signer key, which is the anchor's key.
+func (a *EthPersonalSignAnchor) VerifyRecover(data []byte, sig []byte) (crypto.PubKey, error) {
+	if err := a.Verify(data, sig); err != nil {
+		return nil, err
+	}
+
+	return a.pubk, nil
+}
+
+// PKHAnchor verifies personal_sign signatures for an Ethereum (eip155)
+// did:pkh account. The DID only names an address, so the signer key is
+// recovered from each signature and its address compared; PublicKey is nil
+// until a signature has been verified.
+type PKHAnchor struct {
+	did     DID
+	address []byte
+
+	mx   sync.Mutex
+	pubk crypto.PubKey
+}
+
+var (
+	_ Anchor = (*PKHAnchor)(nil)
+	_ Anchor = (*EthPersonalSignAnchor)(nil)
+)
+
+// NewPKHAnchor creates an anchor for an eip155 did:pkh DID.
+func NewPKHAnchor(did DID) (*PKHAnchor, error) {
+	if did.Method() != pkhMethod {
+		return nil, fmt.Errorf("%w: %s is not a did:pkh", ErrInvalidDID, did)
+	}
+
+	address, err := didEthAddress(did)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PKHAnchor{did: did, address: address}, nil
+}
+
+func makePKHAnchor(did DID) (Anchor, error) {
+	if namespace, _, _, ok := pkhParts(did); ok && namespace == pkhNamespaceCosmos {
+		anchor, err := NewCosmosPKHAnchor(did)
+		if err != nil {
+			return nil, err
+		}
+		return anchor, nil
+	}
+
+	anchor, err := NewPKHAnchor(did)
+	if err != nil {
+		return nil, err
+	}
+
+	return anchor, nil
+}
+
+func (a *PKHAnchor) DID() DID {
+	return a.did
+}
+
+func (a *PKHAnchor) Verify(data []byte, sig []byte) error {
+	_, err := a.VerifyRecover(data, sig)
+	return err
+}
+
+// VerifyRecover verifies a personal_sign signature over data and returns
+// the recovered signer key; the key is cached and returned by PublicKey
+// afterwards.
+func (a *PKHAnchor) VerifyRecover(data []byte, sig []byte) (crypto.PubKey, error) {
+	return a.verifyRecoverHash(ethPersonalHash(data), sig)
+}
+
+// verifyRecoverHash recovers the signer of a wallet signature over hash and
+// checks it against the account address, caching the key.
+func (a *PKHAnchor) verifyRecoverHash(hash []byte, sig []byte) (crypto.PubKey, error) {
+	recovered, err := recoverEthKey(hash, sig)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(ethAddress(recovered), a.address) {
+		return nil, ErrInvalidSignature
+	}
+
+	pubk, err := libp2p_crypto.UnmarshalSecp256k1PublicKey(recovered.SerializeCompressed())
+	if err != nil {
+		return nil, fmt.Errorf("recovered public key: %w", err)
+	}
+
+	a.mx.Lock()
+	if a.pubk == nil {
+		a.pubk = pubk
+	}
+	a.mx.Unlock()
+
+	return pubk, nil
+}
+
+// PublicKey returns the signer key recovered by the first successful
+// verification, or nil.
+func (a *PKHAnchor) PublicKey() crypto.PubKey {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	return a.pubk
+}
+
+func (a *PKHAnchor) KeyType() pb.KeyType {
+	return crypto.Secp256k1
+}
+
+func (a *PKHAnchor) Algorithm() string {
+	return AlgES256K
+}
+
+// recoverEthKey recovers the signing key from a 65-byte R || S || V wallet
+// signature over hash. V may be either 27/28 or a raw recovery id of 0/1;
+// high-S signatures are rejected.
+func recoverEthKey(hash, sig []byte) (*secp256k1.PublicKey, error) {
+	if len(sig) != ethSignatureSize {
+		return nil, fmt.Errorf("signature length %d, expected %d: %w", len(sig), ethSignatureSize, ErrMalformedSignature)
+	}
+
+	v := sig[64]
+	if v < 27 {
+		v += 27
+	}
+	if v != 27 && v != 28 {
+		return nil, fmt.Errorf("invalid recovery byte %d: %w", sig[64], ErrMalformedSignature)
+	}
+
+	var s secp256k1.ModNScalar
+	s.SetByteSlice(sig[32:64])
+	if s.IsOverHalfOrder() {
+		return nil, fmt.Errorf("non-canonical high-S signature: %w", ErrInvalidSignature)
+	}
+
+	// decred expects the compact form V || R || S
+	compact := make([]byte, ethSignatureSize)
+	compact[0] = v
+	copy(compact[1:], sig[:64])
+
+	recovered, _, err := secpECDSA.RecoverCompact(compact, hash)
+	if err != nil {
+		return nil, fmt.Errorf("recover public key: %w: %w", ErrInvalidSignature, err)
+	}
+
+	return recovered, nil
+}
+
+// ethAddress returns the 20-byte Ethereum address of a public key.
+func ethAddress(key *secp256k1.PublicKey) []byte {
+	return keccak256(key.SerializeUncompressed()[1:])[12:]
+}
+
+// ethPersonalHash computes the EIP-191 personal_sign hash of data.
+func ethPersonalHash(data []byte) []byte {
+	hasher := sha3.NewLegacyKeccak256()
+	fmt.Fprintf(hasher, "%s%d", ethSignMagic, len(data))
+	hasher.Write(data)
+	return hasher.Sum(nil)
+}
@@ -0,0 +1,129 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpECDSA "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+// personalSign mimics a wallet's personal_sign: R || S || V with V = 27/28.
+func personalSign(sk *secp256k1.PrivateKey, data []byte) []byte {
+	compact := secpECDSA.SignCompact(sk, ethPersonalHash(data), false)
+	return append(compact[1:], compact[0])
+}
+
+func TestEthPersonalSignAnchor(t *testing.T) {
+	sk, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	pubk, err := crypto.UnmarshalEthPublicKey(sk.PubKey().SerializeCompressed())
+	require.NoError(t, err)
+
+	anchor, err := NewEthPersonalSignAnchor(FromPublicKey(pubk), pubk)
+	require.NoError(t, err)
+	require.Equal(t, AlgES256K, anchor.Algorithm())
+
+	msg := []byte("hello from metamask")
+	sig := personalSign(sk, msg)
+	require.NoError(t, anchor.Verify(msg, sig))
+
+	// recovery id form of V
+	raw := append([]byte(nil), sig...)
+	raw[64] -= 27
+	require.NoError(t, anchor.Verify(msg, raw))
+
+	require.ErrorIs(t, anchor.Verify([]byte("tampered"), sig), ErrInvalidSignature)
+	// structurally bad signatures are malformed rather than invalid
+	err = anchor.Verify(msg, sig[:64])
+	require.ErrorIs(t, err, ErrMalformedSignature)
+	require.NotErrorIs(t, err, ErrInvalidSignature)
+
+	bad := append([]byte(nil), sig...)
+	bad[64] = 35
+	err = anchor.Verify(msg, bad)
+	require.ErrorIs(t, err, ErrMalformedSignature)
+	require.NotErrorIs(t, err, ErrInvalidSignature)
+
+	other, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	require.ErrorIs(t, anchor.Verify(msg, personalSign(other, msg)), ErrInvalidSignature)
+
+	// high-S variant with flipped recovery id recovers the same key
+	var s secp256k1.ModNScalar
+	s.SetByteSlice(sig[32:64])
+	s.Negate()
+	sb := s.Bytes()
+	high := append([]byte(nil), sig...)
+	copy(high[32:64], sb[:])
+	high[64] = 55 - high[64] // 27 <-> 28
+	require.ErrorIs(t, anchor.Verify(msg, high), ErrInvalidSignature)
+
+	_, edPubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	_, err = NewEthPersonalSignAnchor(FromPublicKey(edPubk), edPubk)
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+}
+
+func TestPKHAnchorVerifyRecover(t *testing.T) {
+	sk, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	did := ethAddressDID(ethAddress(sk.PubKey()), 1)
+
+	resolved, err := GetAnchorForDID(did)
+	require.NoError(t, err)
+	anchor := resolved.(*PKHAnchor)
+	require.Nil(t, anchor.PublicKey(), "no key before a signature is verified")
+
+	msg := []byte("sign in with ethereum")
+	other, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	_, err = anchor.VerifyRecover(msg, personalSign(other, msg))
+	require.ErrorIs(t, err, ErrInvalidSignature)
+	require.Nil(t, anchor.PublicKey())
+
+	pubk, err := anchor.VerifyRecover(msg, personalSign(sk, msg))
+	require.NoError(t, err)
+	require.Equal(t, sk.PubKey().SerializeCompressed(), must(pubk.Raw()))
+	require.True(t, pubk.Equals(anchor.PublicKey()), "recovered key is cached")
+
+	require.NoError(t, anchor.Verify(msg, personalSign(sk, msg)))
+	require.ErrorIs(t, anchor.Verify([]byte("tampered"), personalSign(sk, msg)), ErrInvalidSignature)
+
+	_, err = NewPKHAnchor(DID{URI: "did:pkh:solana:4sGjMW1sUnHzSxGspuhpqLDx6wiyjNtZ:CKg5d12Jhpej1JqtmxLJgaFqqeYjxgPqToJ4LBdvG9Ev"})
+	require.ErrorIs(t, err, ErrInvalidDID)
+	_, err = NewPKHAnchor(FromPublicKey(pubk))
+	require.ErrorIs(t, err, ErrInvalidDID)
+}
+
+func TestEthPersonalSignAnchorVerifyRecover(t *testing.T) {
+	sk, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	pubk, err := crypto.UnmarshalEthPublicKey(sk.PubKey().SerializeCompressed())
+	require.NoError(t, err)
+
+	anchor, err := NewEthPersonalSignAnchor(FromPublicKey(pubk), pubk)
+	require.NoError(t, err)
+
+	msg := []byte("hello")
+	recovered, err := anchor.(*EthPersonalSignAnchor).VerifyRecover(msg, personalSign(sk, msg))
+	require.NoError(t, err)
+	require.True(t, pubk.Equals(recovered))
+}
+
+func must(b []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,308 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/crypto/pb"
+
+	"github.com/depinkit/crypto"
+)
+
+const (
+	ethrMethod         = "ethr"
+	ethrDefaultNetwork = "mainnet"
+
+	// EthrRegistryAddress is the address of the ERC-1056 registry deployed
+	// on mainnet and most public networks.
+	EthrRegistryAddress = "0xdca7ef03e98e0dc2b855be647c39abe984fcf21b"
+)
+
+// ERC-1056 registry functions and the delegate type for signing keys.
+var (
+	ethrSelectorIdentityOwner = ethSelector("identityOwner(address)")
+	ethrSelectorChanged       = ethSelector("changed(address)")
+	ethrSelectorValidDelegate = ethSelector("validDelegate(address,bytes32,address)")
+
+	ethrDelegateVeriKey = ethBytes32("veriKey")
+)
+
+// EthRPCClient is an Ethereum JSON-RPC client; CallContext has the
+// signature of go-ethereum's rpc.Client, so one can be used directly.
+type EthRPCClient interface {
+	CallContext(ctx context.Context, result any, method string, args ...any) error
+}
+
+type ethrNetwork struct {
+	client   EthRPCClient
+	registry []byte
+}
+
+var (
+	ethrNetworksMx sync.RWMutex
+	ethrNetworks   = map[string]ethrNetwork{}
+)
+
+// RegisterEthrNetwork sets the JSON-RPC client and ERC-1056 registry used
+// to resolve did:ethr DIDs on network, the network segment of the DID
+// ("mainnet" for DIDs without one, or e.g. "sepolia" or "0xaa36a7"). An
+// empty registry selects EthrRegistryAddress; a nil client removes the
+// network.
+func RegisterEthrNetwork(network string, client EthRPCClient, registry string) error {
+	if registry == "" {
+		registry = EthrRegistryAddress
+	}
+
+	addr, err := parseEthAddress(registry)
+	if err != nil {
+		return fmt.Errorf("registry: %w", err)
+	}
+
+	ethrNetworksMx.Lock()
+	defer ethrNetworksMx.Unlock()
+
+	if client == nil {
+		delete(ethrNetworks, network)
+		return nil
+	}
+	ethrNetworks[network] = ethrNetwork{client: client, registry: addr}
+
+	return nil
+}
+
+// EthrAnchor verifies personal_sign signatures for a did:ethr identity
+// backed by an ERC-1056 registry: the signer key is recovered from each
+// signature and accepted if its address is the identity's current owner or
+// a valid veriKey delegate. Identities without registry changes are owned
+// by their own address and verify like did:pkh accounts, without further
+// registry calls.
+type EthrAnchor struct {
+	did      DID
+	identity []byte
+	owner    []byte
+	changed  bool
+	network  ethrNetwork
+
+	mx   sync.Mutex
+	pubk crypto.PubKey
+}
+
+var _ Anchor = (*EthrAnchor)(nil)
+
+func makeEthrAnchor(ctx context.Context, did DID) (Anchor, error) {
+	anchor, err := NewEthrAnchor(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+
+	return anchor, nil
+}
+
+// NewEthrAnchor resolves the owner of a did:ethr identity, either an
+// address or a compressed secp256k1 public key, from the registry of its
+// network; see RegisterEthrNetwork.
+func NewEthrAnchor(ctx context.Context, did DID) (*EthrAnchor, error) {
+	network, identity, pubk, err := ethrParts(did)
+	if err != nil {
+		return nil, err
+	}
+
+	ethrNetworksMx.RLock()
+	net, ok := ethrNetworks[network]
+	ethrNetworksMx.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no ethereum client for did:ethr network %q: %w", network, ErrNoAnchorMethod)
+	}
+
+	anchor := &EthrAnchor{
+		did:      did,
+		identity: identity,
+		owner:    identity,
+		network:  net,
+		pubk:     pubk,
+	}
+
+	changed, err := anchor.call(ctx, ethrSelectorChanged, ethAddressWord(identity))
+	if err != nil {
+		return nil, err
+	}
+	anchor.changed = new(big.Int).SetBytes(changed).Sign() != 0
+
+	if anchor.changed {
+		owner, err := anchor.call(ctx, ethrSelectorIdentityOwner, ethAddressWord(identity))
+		if err != nil {
+			return nil, err
+		}
+		anchor.owner = owner[12:]
+
+		// the identity key no longer signs once ownership moved
+		if !bytes.Equal(anchor.owner, identity) {
+			anchor.pubk = nil
+		}
+	}
+
+	return anchor, nil
+}
+
+// ethrParts parses did:ethr:[<network>:]<address or public key>.
+func ethrParts(did DID) (network string, identity []byte, pubk crypto.PubKey, err error) {
+	if did.Method() != ethrMethod {
+		return "", nil, nil, fmt.Errorf("%w: %s is not a did:ethr", ErrInvalidDID, did)
+	}
+
+	id := did.Identifier()
+	network = ethrDefaultNetwork
+	if n, rest, ok := strings.Cut(id, ":"); ok {
+		network, id = n, rest
+	}
+
+	raw, err := hex.DecodeString(strings.TrimPrefix(id, "0x"))
+	if err != nil || !strings.HasPrefix(id, "0x") {
+		return "", nil, nil, fmt.Errorf("%w: invalid did:ethr identifier %q", ErrInvalidDID, id)
+	}
+
+	switch len(raw) {
+	case 20:
+		return network, raw, nil, nil
+
+	case 33:
+		key, err := secp256k1.ParsePubKey(raw)
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("%w: invalid did:ethr public key: %w", ErrInvalidDID, err)
+		}
+		pubk, err := libp2p_crypto.UnmarshalSecp256k1PublicKey(raw)
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("%w: invalid did:ethr public key: %w", ErrInvalidDID, err)
+		}
+		return network, ethAddress(key), pubk, nil
+
+	default:
+		return "", nil, nil, fmt.Errorf("%w: invalid did:ethr identifier %q", ErrInvalidDID, id)
+	}
+}
+
+func (a *EthrAnchor) DID() DID {
+	return a.did
+}
+
+func (a *EthrAnchor) Verify(data []byte, sig []byte) error {
+	_, err := a.VerifyRecover(data, sig)
+	return err
+}
+
+// VerifyRecover verifies a personal_sign signature over data and returns
+// the recovered signer key. Signers other than the owner are checked
+// against the registry's veriKey delegates, which makes a registry call.
+func (a *EthrAnchor) VerifyRecover(data []byte, sig []byte) (crypto.PubKey, error) {
+	recovered, err := recoverEthKey(ethPersonalHash(data), sig)
+	if err != nil {
+		return nil, err
+	}
+
+	signer := ethAddress(recovered)
+	switch {
+	case bytes.Equal(signer, a.owner):
+	case a.changed:
+		valid, err := a.call(context.Background(), ethrSelectorValidDelegate,
+			ethAddressWord(a.identity), ethrDelegateVeriKey, ethAddressWord(signer))
+		if err != nil {
+			return nil, err
+		}
+		if new(big.Int).SetBytes(valid).Sign() == 0 {
+			return nil, ErrInvalidSignature
+		}
+	default:
+		return nil, ErrInvalidSignature
+	}
+
+	pubk, err := libp2p_crypto.UnmarshalSecp256k1PublicKey(recovered.SerializeCompressed())
+	if err != nil {
+		return nil, fmt.Errorf("recovered public key: %w", err)
+	}
+
+	if bytes.Equal(signer, a.owner) {
+		a.mx.Lock()
+		if a.pubk == nil {
+			a.pubk = pubk
+		}
+		a.mx.Unlock()
+	}
+
+	return pubk, nil
+}
+
+// PublicKey returns the owner key, known from a public key DID or recovered
+// by a verified owner signature, or nil.
+func (a *EthrAnchor) PublicKey() crypto.PubKey {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	return a.pubk
+}
+
+func (a *EthrAnchor) KeyType() pb.KeyType {
+	return crypto.Secp256k1
+}
+
+func (a *EthrAnchor) Algorithm() string {
+	return AlgES256K
+}
+
+// call makes an eth_call to the registry and returns the 32-byte result.
+func (a *EthrAnchor) call(ctx context.Context, selector []byte, args ...[]byte) ([]byte, error) {
+	data := append([]byte(nil), selector...)
+	for _, arg := range args {
+		data = append(data, arg...)
+	}
+
+	msg := map[string]string{
+		"to":   "0x" + hex.EncodeToString(a.network.registry),
+		"data": "0x" + hex.EncodeToString(data),
+	}
+
+	var result string
+	if err := a.network.client.CallContext(ctx, &result, "eth_call", msg, "latest"); err != nil {
+		return nil, fmt.Errorf("ethr registry call: %w", err)
+	}
+
+	word, err := hex.DecodeString(strings.TrimPrefix(result, "0x"))
+	if err != nil || len(word) != 32 {
+		return nil, fmt.Errorf("ethr registry call: unexpected result %q", result)
+	}
+
+	return word, nil
+}
+
+// ethSelector returns the 4-byte ABI selector of a function signature.
+func ethSelector(signature string) []byte {
+	return keccak256([]byte(signature))[:4]
+}
+
+// ethAddressWord ABI-encodes an address as a left-padded 32-byte word.
+func ethAddressWord(addr []byte) []byte {
+	word := make([]byte, 32)
+	copy(word[32-len(addr):], addr)
+	return word
+}
+
+// ethBytes32 ABI-encodes a short string as a right-padded bytes32.
+func ethBytes32(s string) []byte {
+	word := make([]byte, 32)
+	copy(word, s)
+	return word
+}
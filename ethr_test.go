@@ -0,0 +1,150 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEthrRegistry is an ERC-1056 registry served over a fake JSON-RPC
+// client.
+type fakeEthrRegistry struct {
+	registry  string
+	owners    map[string][]byte
+	delegates map[string][]byte
+	calls     int
+}
+
+func (r *fakeEthrRegistry) CallContext(_ context.Context, result any, method string, args ...any) error {
+	r.calls++
+	if method != "eth_call" || len(args) != 2 || args[1] != "latest" {
+		return fmt.Errorf("unexpected call %s %v", method, args)
+	}
+
+	msg := args[0].(map[string]string)
+	if msg["to"] != r.registry {
+		return fmt.Errorf("unexpected contract %s", msg["to"])
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(msg["data"], "0x"))
+	if err != nil {
+		return err
+	}
+
+	identity := hex.EncodeToString(data[4+12 : 4+32])
+	word := make([]byte, 32)
+	switch {
+	case bytes.Equal(data[:4], ethrSelectorChanged):
+		if _, ok := r.owners[identity]; ok {
+			word[31] = 1
+		}
+	case bytes.Equal(data[:4], ethrSelectorIdentityOwner):
+		copy(word[12:], r.owners[identity])
+	case bytes.Equal(data[:4], ethrSelectorValidDelegate):
+		if !bytes.Equal(data[4+32:4+64], ethrDelegateVeriKey) {
+			return fmt.Errorf("unexpected delegate type %x", data[4+32:4+64])
+		}
+		if bytes.Equal(r.delegates[identity], data[4+64+12:]) {
+			word[31] = 1
+		}
+	default:
+		return fmt.Errorf("unexpected selector %x", data[:4])
+	}
+
+	*result.(*string) = "0x" + hex.EncodeToString(word)
+	return nil
+}
+
+func TestEthrSelectors(t *testing.T) {
+	require.Equal(t, "8733d4e8", hex.EncodeToString(ethrSelectorIdentityOwner))
+	require.Equal(t, "f96d0f9f", hex.EncodeToString(ethrSelectorChanged))
+	require.Equal(t, "622b2a3c", hex.EncodeToString(ethrSelectorValidDelegate))
+}
+
+func TestEthrAnchor(t *testing.T) {
+	identity, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	owner, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	delegate, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	rotated, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	rotatedAddr := hex.EncodeToString(ethAddress(rotated.PubKey()))
+	registry := &fakeEthrRegistry{
+		registry:  EthrRegistryAddress,
+		owners:    map[string][]byte{rotatedAddr: ethAddress(owner.PubKey())},
+		delegates: map[string][]byte{rotatedAddr: ethAddress(delegate.PubKey())},
+	}
+	require.NoError(t, RegisterEthrNetwork("mainnet", registry, ""))
+	t.Cleanup(func() { require.NoError(t, RegisterEthrNetwork("mainnet", nil, "")) })
+
+	msg := []byte("hello ethr")
+	require.Equal(t, ResolutionNetwork, Resolution(DID{URI: "did:ethr:0x" + rotatedAddr}))
+
+	// no registry changes: the identity address owns itself
+	plain := DID{URI: "did:ethr:0x" + hex.EncodeToString(ethAddress(identity.PubKey()))}
+	anchor, err := GetAnchorForDID(plain)
+	require.NoError(t, err)
+	require.Equal(t, 1, registry.calls)
+	require.Nil(t, anchor.PublicKey())
+	require.NoError(t, anchor.Verify(msg, personalSign(identity, msg)))
+	require.NotNil(t, anchor.PublicKey())
+	require.ErrorIs(t, anchor.Verify(msg, personalSign(owner, msg)), ErrInvalidSignature)
+	require.Equal(t, 1, registry.calls, "unchanged identities need no delegate lookups")
+
+	// public key identifiers carry the key
+	keyDID := DID{URI: "did:ethr:0x" + hex.EncodeToString(identity.PubKey().SerializeCompressed())}
+	anchor, err = GetAnchorForDID(keyDID)
+	require.NoError(t, err)
+	require.NotNil(t, anchor.PublicKey())
+	require.NoError(t, anchor.Verify(msg, personalSign(identity, msg)))
+
+	// ownership moved: the former key no longer signs, the owner and the
+	// veriKey delegate do
+	anchor, err = GetAnchorForDID(DID{URI: "did:ethr:0x" + rotatedAddr})
+	require.NoError(t, err)
+	require.ErrorIs(t, anchor.Verify(msg, personalSign(rotated, msg)), ErrInvalidSignature)
+	require.NoError(t, anchor.Verify(msg, personalSign(owner, msg)))
+	require.NoError(t, anchor.Verify(msg, personalSign(delegate, msg)))
+	require.Equal(t, owner.PubKey().SerializeCompressed(), must(anchor.PublicKey().Raw()))
+}
+
+func TestEthrAnchorNetworks(t *testing.T) {
+	sk, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	addr := "0x" + hex.EncodeToString(ethAddress(sk.PubKey()))
+
+	_, err = GetAnchorForDID(DID{URI: "did:ethr:sepolia:" + addr})
+	require.ErrorIs(t, err, ErrNoAnchorMethod)
+
+	registry := &fakeEthrRegistry{registry: "0x03d5003bf0e79c5f5223588f347eba39afbc3818"}
+	require.NoError(t, RegisterEthrNetwork("sepolia", registry, registry.registry))
+	t.Cleanup(func() { require.NoError(t, RegisterEthrNetwork("sepolia", nil, "")) })
+
+	anchor, err := GetAnchorForDID(DID{URI: "did:ethr:sepolia:" + addr})
+	require.NoError(t, err)
+	msg := []byte("hello sepolia")
+	require.NoError(t, anchor.Verify(msg, personalSign(sk, msg)))
+
+	require.Error(t, RegisterEthrNetwork("bad", registry, "0x1234"))
+
+	for _, uri := range []string{"did:ethr:sepolia:1234", "did:ethr:sepolia:0x1234", "did:ethr:sepolia:0xzz"} {
+		_, err := GetAnchorForDID(DID{URI: uri})
+		require.ErrorIs(t, err, ErrInvalidDID, uri)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpECDSA "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/crypto/pb"
+
+	"github.com/depinkit/crypto"
+)
+
+// Hasher transforms a message into the digest that is signed, e.g.
+// Keccak-256 for Ethereum counterparties.
+type Hasher func(data []byte) []byte
+
+// HasherProvider signs the Hasher digest of the data instead of the data.
+// ECDSA keys sign the digest as is, without the SHA-256 libp2p applies;
+// Ed25519 keys sign the digest as the message.
+type HasherProvider struct {
+	*PrivateKeyProvider
+	hasher Hasher
+}
+
+// HasherAnchor verifies HasherProvider signatures made with the same Hasher.
+type HasherAnchor struct {
+	*PublicKeyAnchor
+	hasher Hasher
+}
+
+var (
+	_ Provider      = (*HasherProvider)(nil)
+	_ ContextSigner = (*HasherProvider)(nil)
+	_ Anchor        = (*HasherAnchor)(nil)
+)
+
+// NewProviderWithHasher creates a provider for did that signs hasher(data)
+// with privk.
+func NewProviderWithHasher(did DID, privk crypto.PrivKey, hasher Hasher) (Provider, error) {
+	if hasher == nil {
+		return nil, fmt.Errorf("nil hasher")
+	}
+
+	if err := checkDigestKeyType(privk.Type()); err != nil {
+		return nil, err
+	}
+
+	return &HasherProvider{
+		PrivateKeyProvider: &PrivateKeyProvider{did: did, privk: privk},
+		hasher:             hasher,
+	}, nil
+}
+
+// NewAnchorWithHasher creates an anchor for did that verifies signatures
+// over hasher(data) with pubk.
+func NewAnchorWithHasher(did DID, pubk crypto.PubKey, hasher Hasher) (Anchor, error) {
+	if hasher == nil {
+		return nil, fmt.Errorf("nil hasher")
+	}
+
+	if err := checkDigestKeyType(pubk.Type()); err != nil {
+		return nil, err
+	}
+
+	return &HasherAnchor{
+		PublicKeyAnchor: &PublicKeyAnchor{did: did, pubk: pubk},
+		hasher:          hasher,
+	}, nil
+}
+
+func (p *HasherProvider) Sign(data []byte) ([]byte, error) {
+	return signDigest(p.privk, p.hasher(data))
+}
+
+func (p *HasherProvider) SignContext(_ context.Context, data []byte) ([]byte, error) {
+	return p.Sign(data)
+}
+
+func (p *HasherProvider) Anchor() Anchor {
+	return &HasherAnchor{
+		PublicKeyAnchor: &PublicKeyAnchor{did: p.did, pubk: p.privk.GetPublic()},
+		hasher:          p.hasher,
+	}
+}
+
+func (a *HasherAnchor) Verify(data []byte, sig []byte) error {
+	return verifyDigest(a.pubk, a.hasher(data), sig)
+}
+
+func checkDigestKeyType(t pb.KeyType) error {
+	switch t {
+	case crypto.Ed25519, crypto.Secp256k1, libp2p_crypto.ECDSA:
+		return nil
+	default:
+		return fmt.Errorf("digest signing not supported for key type %d: %w", t, ErrInvalidKeyType)
+	}
+}
+
+// signDigest signs a precomputed digest; Ed25519 signs it as the message.
+func signDigest(privk crypto.PrivKey, digest []byte) ([]byte, error) {
+	switch privk.Type() {
+	case crypto.Ed25519:
+		return privk.Sign(digest)
+
+	case crypto.Secp256k1:
+		raw, err := privk.Raw()
+		if err != nil {
+			return nil, fmt.Errorf("raw private key: %w", err)
+		}
+		return secpECDSA.Sign(secp256k1.PrivKeyFromBytes(raw), digest).Serialize(), nil
+
+	case libp2p_crypto.ECDSA:
+		std, err := libp2p_crypto.PrivKeyToStdKey(privk)
+		if err != nil {
+			return nil, fmt.Errorf("ecdsa private key: %w", err)
+		}
+		ecpriv, ok := std.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, ErrInvalidKeyType
+		}
+		return ecdsa.SignASN1(rand.Reader, ecpriv, digest)
+
+	default:
+		return nil, fmt.Errorf("digest signing not supported for key type %d: %w", privk.Type(), ErrInvalidKeyType)
+	}
+}
+
+// verifyDigest verifies a signDigest signature over digest.
+func verifyDigest(pubk crypto.PubKey, digest []byte, sig []byte) error {
+	switch pubk.Type() {
+	case crypto.Ed25519:
+		ok, err := pubk.Verify(digest, sig)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidSignature
+		}
+		return nil
+
+	case crypto.Secp256k1, crypto.Eth:
+		if err := checkLowS(sig); err != nil {
+			return err
+		}
+		raw, err := pubk.Raw()
+		if err != nil {
+			return fmt.Errorf("raw public key: %w", err)
+		}
+		key, err := secp256k1.ParsePubKey(raw)
+		if err != nil {
+			return fmt.Errorf("parse public key: %w", err)
+		}
+		parsed, err := secpECDSA.ParseDERSignature(sig)
+		if err != nil {
+			return fmt.Errorf("parse signature: %w: %w", ErrMalformedSignature, err)
+		}
+		if !parsed.Verify(digest, key) {
+			return ErrInvalidSignature
+		}
+		return nil
+
+	case libp2p_crypto.ECDSA:
+		std, err := libp2p_crypto.PubKeyToStdKey(pubk)
+		if err != nil {
+			return fmt.Errorf("ecdsa public key: %w", err)
+		}
+		ecpub, ok := std.(*ecdsa.PublicKey)
+		if !ok {
+			return ErrInvalidKeyType
+		}
+		if !ecdsa.VerifyASN1(ecpub, digest, sig) {
+			return ErrInvalidSignature
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("digest verification not supported for key type %d: %w", pubk.Type(), ErrInvalidKeyType)
+	}
+}
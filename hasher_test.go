@@ -0,0 +1,71 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpECDSA "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+func keccakHasher(data []byte) []byte {
+	return keccak256(data)
+}
+
+func TestProviderWithHasher(t *testing.T) {
+	privk, pubk, err := crypto.GenerateKeyPair(crypto.Secp256k1)
+	require.NoError(t, err)
+	did := FromPublicKey(pubk)
+
+	provider, err := NewProviderWithHasher(did, privk, keccakHasher)
+	require.NoError(t, err)
+
+	data := []byte("ethereum domain")
+	sig, err := provider.Sign(data)
+	require.NoError(t, err)
+	require.NoError(t, provider.Anchor().Verify(data, sig))
+
+	// the signature is over the bare Keccak-256 digest
+	raw, err := pubk.Raw()
+	require.NoError(t, err)
+	key, err := secp256k1.ParsePubKey(raw)
+	require.NoError(t, err)
+	parsed, err := secpECDSA.ParseDERSignature(sig)
+	require.NoError(t, err)
+	require.True(t, parsed.Verify(keccak256(data), key))
+
+	// and is not valid in the libp2p (SHA-256) domain
+	require.Error(t, NewAnchor(did, pubk).Verify(data, sig))
+
+	anchor, err := NewAnchorWithHasher(did, pubk, keccakHasher)
+	require.NoError(t, err)
+	require.NoError(t, anchor.Verify(data, sig))
+	require.ErrorIs(t, anchor.Verify([]byte("other"), sig), ErrInvalidSignature)
+}
+
+func TestProviderWithHasherEd25519(t *testing.T) {
+	privk, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+
+	provider, err := NewProviderWithHasher(FromPublicKey(pubk), privk, keccakHasher)
+	require.NoError(t, err)
+
+	data := []byte("hello")
+	sig, err := provider.Sign(data)
+	require.NoError(t, err)
+	require.NoError(t, provider.Anchor().Verify(data, sig))
+	require.ErrorIs(t, provider.Anchor().Verify([]byte("other"), sig), ErrInvalidSignature)
+
+	_, err = NewProviderWithHasher(FromPublicKey(pubk), privk, nil)
+	require.Error(t, err)
+}
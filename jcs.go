@@ -0,0 +1,257 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// CanonicalSign canonicalizes v with CanonicalJSON and signs the result
+// with the provider, returning the signed bytes and the signature envelope.
+func CanonicalSign(prov Provider, v any) ([]byte, SignatureEnvelope, error) {
+	data, err := CanonicalJSON(v)
+	if err != nil {
+		return nil, SignatureEnvelope{}, err
+	}
+
+	env, err := SignEnvelope(prov, data)
+	if err != nil {
+		return nil, SignatureEnvelope{}, err
+	}
+
+	return data, env, nil
+}
+
+// CanonicalVerify canonicalizes v with CanonicalJSON and verifies env over
+// the result; see VerifyEnvelope.
+func CanonicalVerify(ctx TrustContext, v any, env SignatureEnvelope) error {
+	data, err := CanonicalJSON(v)
+	if err != nil {
+		return err
+	}
+
+	return VerifyEnvelope(ctx, data, env)
+}
+
+// CanonicalJSON returns the RFC 8785 (JCS) canonical JSON encoding of v:
+// v is marshaled with encoding/json, then object members are sorted by
+// their UTF-16 code units, insignificant whitespace is dropped and numbers
+// and strings are written in their ECMAScript forms. A json.RawMessage is
+// canonicalized as is. Objects with duplicate member names and numbers
+// outside the IEEE 754 double range are rejected.
+func CanonicalJSON(v any) ([]byte, error) {
+	raw, ok := v.(json.RawMessage)
+	if !ok {
+		var err error
+		raw, err = json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("marshal: %w", err)
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var buf bytes.Buffer
+	if err := jcsValue(dec, &buf); err != nil {
+		return nil, fmt.Errorf("canonicalize json: %w", err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("canonicalize json: trailing data")
+	}
+
+	return buf.Bytes(), nil
+}
+
+func jcsValue(dec *json.Decoder, buf *bytes.Buffer) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			return jcsObject(dec, buf)
+		case '[':
+			return jcsArray(dec, buf)
+		default:
+			return fmt.Errorf("unexpected %s", t)
+		}
+
+	case string:
+		jcsString(buf, t)
+
+	case json.Number:
+		f, err := strconv.ParseFloat(t.String(), 64)
+		if err != nil {
+			return fmt.Errorf("number %s: %w", t, err)
+		}
+		buf.WriteString(jcsNumber(f))
+
+	case bool:
+		buf.WriteString(strconv.FormatBool(t))
+
+	case nil:
+		buf.WriteString("null")
+	}
+
+	return nil
+}
+
+func jcsObject(dec *json.Decoder, buf *bytes.Buffer) error {
+	type member struct {
+		key   string
+		utf16 []uint16
+		value []byte
+	}
+
+	var members []member
+	seen := make(map[string]struct{})
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("unexpected object key %v", tok)
+		}
+		if _, dup := seen[key]; dup {
+			return fmt.Errorf("duplicate object member %q", key)
+		}
+		seen[key] = struct{}{}
+
+		var value bytes.Buffer
+		if err := jcsValue(dec, &value); err != nil {
+			return err
+		}
+		members = append(members, member{key: key, utf16: utf16.Encode([]rune(key)), value: value.Bytes()})
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+
+	slices.SortFunc(members, func(a, b member) int { return slices.Compare(a.utf16, b.utf16) })
+
+	buf.WriteByte('{')
+	for i, m := range members {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		jcsString(buf, m.key)
+		buf.WriteByte(':')
+		buf.Write(m.value)
+	}
+	buf.WriteByte('}')
+
+	return nil
+}
+
+func jcsArray(dec *json.Decoder, buf *bytes.Buffer) error {
+	buf.WriteByte('[')
+	for i := 0; dec.More(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := jcsValue(dec, buf); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	buf.WriteByte(']')
+
+	return nil
+}
+
+// jcsString writes s as a JSON string, escaping only what JSON requires and
+// using the short escapes where they exist.
+func jcsString(buf *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[r>>4])
+				buf.WriteByte(hex[r&0xf])
+				continue
+			}
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// jcsNumber formats f as ECMAScript Number.prototype.toString does: the
+// shortest round-tripping digits, in plain notation for magnitudes in
+// [1e-6, 1e21) and in exponent notation otherwise.
+func jcsNumber(f float64) string {
+	if f == 0 {
+		return "0" // including -0
+	}
+
+	var sign string
+	if f < 0 {
+		sign, f = "-", -f
+	}
+
+	// shortest digits d1.d2...dk and exponent, e.g. "1.2345e+06"
+	exp := strconv.FormatFloat(f, 'e', -1, 64)
+	mantissa, exponent, _ := strings.Cut(exp, "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	e, _ := strconv.Atoi(exponent)
+
+	k, n := len(digits), e+1
+	switch {
+	case k <= n && n <= 21:
+		return sign + digits + strings.Repeat("0", n-k)
+	case 0 < n && n <= 21:
+		return sign + digits[:n] + "." + digits[n:]
+	case -6 < n && n <= 0:
+		return sign + "0." + strings.Repeat("0", -n) + digits
+	}
+
+	expSign := "+"
+	if e < 0 {
+		expSign, e = "-", -e
+	}
+	if k == 1 {
+		return sign + digits + "e" + expSign + strconv.Itoa(e)
+	}
+
+	return sign + digits[:1] + "." + digits[1:] + "e" + expSign + strconv.Itoa(e)
+}
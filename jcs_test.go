@@ -0,0 +1,100 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	// RFC 8785 section 3.2.4
+	in := json.RawMessage(`{
+		"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+		"string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+		"literals": [null, true, false]
+	}`)
+	out, err := CanonicalJSON(in)
+	require.NoError(t, err)
+	require.Equal(t, `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`, string(out))
+
+	// RFC 8785 section 3.2.3: members sorted by UTF-16 code units
+	in = json.RawMessage(`{"\u20ac":"Euro Sign","\r":"Carriage Return","\ufb33":"Hebrew Letter Dalet With Dagesh","1":"One","\ud83d\ude00":"Emoji: Grinning Face","\u0080":"Control","\u00f6":"Latin Small Letter O With Diaeresis"}`)
+	out, err = CanonicalJSON(in)
+	require.NoError(t, err)
+	require.Equal(t, "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\",\"\u20ac\":\"Euro Sign\",\"\U0001f600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}", string(out))
+
+	// Go values go through encoding/json first
+	out, err = CanonicalJSON(map[string]any{"b": []int{1, 2}, "a": struct {
+		X float64 `json:"x"`
+		Y string  `json:"y,omitempty"`
+	}{X: 1e21}})
+	require.NoError(t, err)
+	require.Equal(t, `{"a":{"x":1e+21},"b":[1,2]}`, string(out))
+
+	for _, bad := range []string{`{"a":1,"a":2}`, `[1e400]`, `{"a":1} {}`, `{"a"`} {
+		_, err := CanonicalJSON(json.RawMessage(bad))
+		require.Error(t, err, bad)
+	}
+}
+
+func TestJCSNumber(t *testing.T) {
+	// RFC 8785 appendix B
+	for bits, want := range map[uint64]string{
+		0x0000000000000000: "0",
+		0x8000000000000000: "0",
+		0x0000000000000001: "5e-324",
+		0x8000000000000001: "-5e-324",
+		0x7fefffffffffffff: "1.7976931348623157e+308",
+		0xffefffffffffffff: "-1.7976931348623157e+308",
+		0x4340000000000000: "9007199254740992",
+		0xc340000000000000: "-9007199254740992",
+		0x4430000000000000: "295147905179352830000",
+		0x44b52d02c7e14af5: "9.999999999999997e+22",
+		0x44b52d02c7e14af6: "1e+23",
+		0x44b52d02c7e14af7: "1.0000000000000001e+23",
+		0x444b1ae4d6e2ef4e: "999999999999999700000",
+		0x444b1ae4d6e2ef4f: "999999999999999900000",
+		0x444b1ae4d6e2ef50: "1e+21",
+		0x3eb0c6f7a0b5ed8c: "9.999999999999997e-7",
+		0x3eb0c6f7a0b5ed8d: "0.000001",
+		0x41b3de4355555553: "333333333.3333332",
+		0x41b3de4355555554: "333333333.33333325",
+		0x41b3de4355555555: "333333333.3333333",
+		0x41b3de4355555556: "333333333.3333334",
+		0x41b3de4355555557: "333333333.33333343",
+		0xbecbf647612f3696: "-0.0000033333333333333333",
+		0x43143ff3c1cb0959: "1424953923781206.2",
+	} {
+		require.Equal(t, want, jcsNumber(math.Float64frombits(bits)), "%#x", bits)
+	}
+}
+
+func TestCanonicalSignVerify(t *testing.T) {
+	privk, _, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	provider, err := ProviderFromPrivateKey(privk)
+	require.NoError(t, err)
+
+	data, env, err := CanonicalSign(provider, map[string]any{"to": "bob", "amount": 10})
+	require.NoError(t, err)
+	require.Equal(t, `{"amount":10,"to":"bob"}`, string(data))
+	require.Equal(t, provider.DID(), env.DID)
+
+	// the verifier's encoding of the same object differs in member order,
+	// whitespace and number form
+	ctx := NewTrustContext()
+	require.NoError(t, CanonicalVerify(ctx, json.RawMessage(`{ "to": "bob", "amount": 1.0e1 }`), env))
+	require.ErrorIs(t, CanonicalVerify(ctx, json.RawMessage(`{"to":"bob","amount":11}`), env), ErrInvalidSignature)
+}
@@ -0,0 +1,173 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+
+	"github.com/depinkit/crypto"
+)
+
+const (
+	jwkKtyOKP = "OKP"
+	jwkKtyEC  = "EC"
+
+	jwkCrvEd25519   = "Ed25519"
+	jwkCrvSecp256k1 = "secp256k1"
+	jwkCrvP256      = "P-256"
+)
+
+// JWK is an RFC 7517 JSON Web Key for a public key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// JWK returns the anchor's public key as a JWK whose kid is the DID URL of
+// the anchor's verification method.
+func (a *PublicKeyAnchor) JWK() (json.RawMessage, error) {
+	jwk, err := publicKeyJWK(a.pubk)
+	if err != nil {
+		return nil, err
+	}
+
+	multibaseKey, err := keyMultibase(a.pubk)
+	if err != nil {
+		return nil, err
+	}
+	jwk.Kid = verificationMethodID(a.did, multibaseKey, 1)
+
+	return json.Marshal(jwk)
+}
+
+// FromJWK reconstructs a public key from a JWK and returns it with its
+// did:key DID.
+func FromJWK(data []byte) (DID, crypto.PubKey, error) {
+	var jwk JWK
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return DID{}, nil, fmt.Errorf("parse jwk: %w", err)
+	}
+
+	pubk, err := jwk.PublicKey()
+	if err != nil {
+		return DID{}, nil, err
+	}
+
+	did := FromPublicKey(pubk)
+	if did.Empty() {
+		return DID{}, nil, ErrInvalidKeyType
+	}
+
+	return did, pubk, nil
+}
+
+// PublicKey decodes the public key described by the JWK.
+func (jwk *JWK) PublicKey() (crypto.PubKey, error) {
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("decode jwk x: %w", err)
+	}
+
+	switch {
+	case jwk.Kty == jwkKtyOKP && jwk.Crv == jwkCrvEd25519:
+		return libp2p_crypto.UnmarshalEd25519PublicKey(x)
+
+	case jwk.Kty == jwkKtyEC && (jwk.Crv == jwkCrvSecp256k1 || jwk.Crv == jwkCrvP256):
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, fmt.Errorf("decode jwk y: %w", err)
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("invalid jwk coordinate length: %w", ErrInvalidKeyType)
+		}
+
+		if jwk.Crv == jwkCrvSecp256k1 {
+			uncompressed := make([]byte, 0, 65)
+			uncompressed = append(uncompressed, 0x04)
+			uncompressed = append(uncompressed, x...)
+			uncompressed = append(uncompressed, y...)
+			return libp2p_crypto.UnmarshalSecp256k1PublicKey(uncompressed)
+		}
+
+		curve := elliptic.P256()
+		px, py := new(big.Int).SetBytes(x), new(big.Int).SetBytes(y)
+		if !curve.IsOnCurve(px, py) {
+			return nil, fmt.Errorf("jwk point is not on %s: %w", jwkCrvP256, ErrInvalidKeyType)
+		}
+		return libp2p_crypto.ECDSAPublicKeyFromPubKey(ecdsa.PublicKey{Curve: curve, X: px, Y: py})
+
+	default:
+		return nil, fmt.Errorf("unsupported jwk kty %q crv %q: %w", jwk.Kty, jwk.Crv, ErrInvalidKeyType)
+	}
+}
+
+func publicKeyJWK(pubk crypto.PubKey) (*JWK, error) {
+	switch pubk.Type() {
+	case crypto.Ed25519:
+		raw, err := pubk.Raw()
+		if err != nil {
+			return nil, err
+		}
+		return &JWK{
+			Kty: jwkKtyOKP,
+			Crv: jwkCrvEd25519,
+			X:   base64.RawURLEncoding.EncodeToString(raw),
+		}, nil
+
+	case crypto.Eth:
+		// a secp256k1 JWK would come back from FromJWK as a Secp256k1 key,
+		// which verifies plain signatures rather than Ethereum ones
+		return nil, fmt.Errorf("eth keys have no jwk form: %w", ErrInvalidKeyType)
+
+	case crypto.Secp256k1:
+		raw, err := pubk.Raw()
+		if err != nil {
+			return nil, err
+		}
+		pk, err := secp256k1.ParsePubKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parse secp256k1 key: %w", err)
+		}
+		return ecJWK(jwkCrvSecp256k1, pk.X(), pk.Y()), nil
+
+	case libp2p_crypto.ECDSA:
+		std, err := libp2p_crypto.PubKeyToStdKey(pubk)
+		if err != nil {
+			return nil, err
+		}
+		ecpub, ok := std.(*ecdsa.PublicKey)
+		if !ok || ecpub.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("unsupported ecdsa curve: %w", ErrInvalidKeyType)
+		}
+		return ecJWK(jwkCrvP256, ecpub.X, ecpub.Y), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %d: %w", pubk.Type(), ErrInvalidKeyType)
+	}
+}
+
+func ecJWK(crv string, x, y *big.Int) *JWK {
+	return &JWK{
+		Kty: jwkKtyEC,
+		Crv: crv,
+		X:   base64.RawURLEncoding.EncodeToString(x.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(y.FillBytes(make([]byte, 32))),
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+func TestJWKRoundTrip(t *testing.T) {
+	cases := []struct {
+		name    string
+		kty     string
+		crv     string
+		genFunc func() (crypto.PrivKey, crypto.PubKey, error)
+	}{
+		{"Ed25519", "OKP", "Ed25519", func() (crypto.PrivKey, crypto.PubKey, error) {
+			return crypto.GenerateKeyPair(crypto.Ed25519)
+		}},
+		{"Secp256k1", "EC", "secp256k1", func() (crypto.PrivKey, crypto.PubKey, error) {
+			return crypto.GenerateKeyPair(crypto.Secp256k1)
+		}},
+		{"P256", "EC", "P-256", func() (crypto.PrivKey, crypto.PubKey, error) {
+			return libp2p_crypto.GenerateECDSAKeyPair(rand.Reader)
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, pubk, err := tc.genFunc()
+			require.NoError(t, err)
+
+			anchor, err := AnchorFromPublicKey(pubk)
+			require.NoError(t, err)
+
+			data, err := anchor.(*PublicKeyAnchor).JWK()
+			require.NoError(t, err)
+
+			var jwk JWK
+			require.NoError(t, json.Unmarshal(data, &jwk))
+			require.Equal(t, tc.kty, jwk.Kty)
+			require.Equal(t, tc.crv, jwk.Crv)
+			require.Equal(t, anchor.DID().URI+"#"+anchor.DID().Identifier(), jwk.Kid)
+
+			did, recovered, err := FromJWK(data)
+			require.NoError(t, err)
+			require.Equal(t, anchor.DID(), did)
+			require.True(t, pubk.Equals(recovered))
+		})
+	}
+}
+
+func TestJWKEthKey(t *testing.T) {
+	sk, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	pubk, err := crypto.UnmarshalEthPublicKey(sk.PubKey().SerializeCompressed())
+	require.NoError(t, err)
+
+	// exporting an Eth key as secp256k1 would change how it verifies
+	anchor := NewAnchor(DID{URI: "did:web:example.com"}, pubk).(*PublicKeyAnchor)
+	_, err = anchor.JWK()
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+}
+
+func TestFromJWKInvalid(t *testing.T) {
+	cases := []string{
+		`not-json`,
+		`{"kty":"RSA","n":"AQAB","e":"AQAB"}`,
+		`{"kty":"EC","crv":"P-256","x":"AAAA","y":"AAAA"}`,
+		`{"kty":"EC","crv":"P-256","x":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA","y":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"}`,
+	}
+
+	for _, jwk := range cases {
+		_, _, err := FromJWK([]byte(jwk))
+		require.Error(t, err, jwk)
+	}
+}
+
+func TestKeyDIDP256RoundTrip(t *testing.T) {
+	privk, pubk, err := libp2p_crypto.GenerateECDSAKeyPair(rand.Reader)
+	require.NoError(t, err)
+
+	did := FromPublicKey(pubk)
+	require.Equal(t, "key", did.Method())
+	require.Equal(t, "zDn", did.Identifier()[:3], "P-256 did:key prefix")
+
+	recovered, err := PublicKeyFromDID(did)
+	require.NoError(t, err)
+	require.True(t, pubk.Equals(recovered))
+
+	msg := []byte("p256")
+	sig, err := privk.Sign(msg)
+	require.NoError(t, err)
+	require.NoError(t, NewAnchor(did, recovered).Verify(msg, sig))
+}
@@ -0,0 +1,186 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpECDSA "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// es256kSignatureSize is the size of a JWS ES256K signature: R || S, each
+// a 32-byte big-endian scalar (RFC 7515 appendix A.3).
+const es256kSignatureSize = 64
+
+// jwsEncoding is unpadded base64url, rejecting non-canonical encodings
+// (non-zero trailing bits) so a JWS has a single valid serialization.
+var jwsEncoding = base64.RawURLEncoding.Strict()
+
+// jwsHeader is the protected header of a JWS. Marshaling the struct yields
+// a fixed member order, so signing the same payload with the same provider
+// always produces the same header.
+type jwsHeader struct {
+	Alg  string   `json:"alg"`
+	Kid  string   `json:"kid"`
+	Crit []string `json:"crit,omitempty"`
+}
+
+// SignJWS signs payload with the provider and returns it as a compact JWS
+// (RFC 7515). The alg header is derived from the provider's key type and
+// kid is the DID URL of the provider's verification method. Only EdDSA and
+// ES256K are supported.
+func SignJWS(prov Provider, payload []byte) (string, error) {
+	anchor := prov.Anchor()
+
+	alg := anchor.Algorithm()
+	if alg != AlgEdDSA && alg != AlgES256K {
+		return "", fmt.Errorf("JWS algorithm %q for %s: %w", alg, prov.DID(), ErrInvalidKeyType)
+	}
+
+	pubk := anchor.PublicKey()
+	if pubk == nil {
+		return "", fmt.Errorf("no public key for %s: %w", prov.DID(), ErrInvalidKeyType)
+	}
+
+	multibaseKey, err := keyMultibase(pubk)
+	if err != nil {
+		return "", err
+	}
+
+	header, err := json.Marshal(jwsHeader{
+		Alg: alg,
+		Kid: verificationMethodID(prov.DID(), multibaseKey, 1),
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshal JWS header: %w", err)
+	}
+
+	signingInput := jwsEncoding.EncodeToString(header) + "." + jwsEncoding.EncodeToString(payload)
+
+	sig, err := prov.Sign([]byte(signingInput))
+	if err != nil {
+		return "", fmt.Errorf("sign: %w", err)
+	}
+
+	if alg == AlgES256K {
+		sig, err = es256kFromDER(sig)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return signingInput + "." + jwsEncoding.EncodeToString(sig), nil
+}
+
+// VerifyJWS verifies a compact JWS: it resolves the anchor for the DID in
+// the kid header in the trust context, checks that alg matches the
+// anchor's algorithm and verifies the signature over the protected header
+// and payload as received. It returns the payload and the signer's DID.
+// JWS with critical header parameters are rejected, as none are supported.
+func VerifyJWS(ctx TrustContext, jws string) (payload []byte, signer DID, err error) {
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 {
+		return nil, DID{}, fmt.Errorf("malformed JWS: %d segments: %w", len(parts), ErrInvalidSignature)
+	}
+
+	rawHeader, err := jwsEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, DID{}, fmt.Errorf("malformed JWS header: %w: %w", ErrInvalidSignature, err)
+	}
+
+	var header jwsHeader
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, DID{}, fmt.Errorf("malformed JWS header: %w: %w", ErrInvalidSignature, err)
+	}
+
+	if len(header.Crit) > 0 {
+		return nil, DID{}, fmt.Errorf("unsupported critical JWS headers %q: %w", header.Crit, ErrInvalidSignature)
+	}
+
+	if header.Alg != AlgEdDSA && header.Alg != AlgES256K {
+		return nil, DID{}, fmt.Errorf("unsupported JWS algorithm %q: %w", header.Alg, ErrAlgorithmMismatch)
+	}
+
+	payload, err = jwsEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, DID{}, fmt.Errorf("malformed JWS payload: %w: %w", ErrInvalidSignature, err)
+	}
+
+	sig, err := jwsEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, DID{}, fmt.Errorf("malformed JWS signature: %w: %w", ErrInvalidSignature, err)
+	}
+
+	kid, err := FromString(header.Kid)
+	if err != nil || kid.Empty() {
+		return nil, DID{}, fmt.Errorf("JWS kid %q: %w", header.Kid, ErrInvalidDID)
+	}
+
+	signer = kid
+	if end := strings.IndexAny(kid.URI, "/?#"); end >= 0 {
+		signer = DID{URI: kid.URI[:end]}
+	}
+
+	anchor, err := ctx.GetAnchor(signer)
+	if err != nil {
+		return nil, DID{}, fmt.Errorf("get anchor: %w", err)
+	}
+
+	if alg := anchor.Algorithm(); alg != header.Alg {
+		return nil, DID{}, fmt.Errorf("JWS algorithm %q does not match anchor algorithm %q: %w", header.Alg, alg, ErrAlgorithmMismatch)
+	}
+
+	if header.Alg == AlgES256K {
+		sig, err = es256kToDER(sig)
+		if err != nil {
+			return nil, DID{}, err
+		}
+	}
+
+	if err := anchor.Verify([]byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, DID{}, err
+	}
+
+	return payload, signer, nil
+}
+
+// es256kFromDER converts a DER secp256k1 signature to the fixed-size
+// R || S form used by JWS.
+func es256kFromDER(der []byte) ([]byte, error) {
+	sig, err := secpECDSA.ParseDERSignature(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse signature: %w", err)
+	}
+
+	r, s := sig.R(), sig.S()
+	out := make([]byte, es256kSignatureSize)
+	r.PutBytesUnchecked(out[:32])
+	s.PutBytesUnchecked(out[32:])
+
+	return out, nil
+}
+
+// es256kToDER converts a JWS R || S secp256k1 signature to DER, rejecting
+// scalars that are zero or not reduced modulo the group order.
+func es256kToDER(raw []byte) ([]byte, error) {
+	if len(raw) != es256kSignatureSize {
+		return nil, fmt.Errorf("signature length %d, expected %d: %w", len(raw), es256kSignatureSize, ErrInvalidSignature)
+	}
+
+	var r, s secp256k1.ModNScalar
+	if r.SetByteSlice(raw[:32]) || s.SetByteSlice(raw[32:]) || r.IsZero() || s.IsZero() {
+		return nil, fmt.Errorf("signature scalar out of range: %w", ErrInvalidSignature)
+	}
+
+	return secpECDSA.NewSignature(&r, &s).Serialize(), nil
+}
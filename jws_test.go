@@ -0,0 +1,96 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+func TestJWSRoundTrip(t *testing.T) {
+	payload := []byte(`{"hello":"world"}`)
+
+	for kt, alg := range map[int]string{crypto.Ed25519: AlgEdDSA, crypto.Secp256k1: AlgES256K} {
+		privk, _, err := crypto.GenerateKeyPair(kt)
+		require.NoError(t, err)
+		provider, err := ProviderFromPrivateKey(privk)
+		require.NoError(t, err)
+
+		jws, err := SignJWS(provider, payload)
+		require.NoError(t, err)
+
+		parts := strings.Split(jws, ".")
+		require.Len(t, parts, 3)
+		rawHeader, err := jwsEncoding.DecodeString(parts[0])
+		require.NoError(t, err)
+		var header map[string]string
+		require.NoError(t, json.Unmarshal(rawHeader, &header))
+		require.Equal(t, alg, header["alg"])
+		require.True(t, strings.HasPrefix(header["kid"], provider.DID().URI+"#"))
+
+		sig, err := jwsEncoding.DecodeString(parts[2])
+		require.NoError(t, err)
+		require.Len(t, sig, 64)
+
+		got, signer, err := VerifyJWS(NewTrustContext(), jws)
+		require.NoError(t, err)
+		require.Equal(t, payload, got)
+		require.Equal(t, provider.DID(), signer)
+
+		// the signature covers the header and payload as received
+		tampered := parts[0] + "." + jwsEncoding.EncodeToString([]byte(`{"hello":"there"}`)) + "." + parts[2]
+		_, _, err = VerifyJWS(NewTrustContext(), tampered)
+		require.ErrorIs(t, err, ErrInvalidSignature)
+	}
+}
+
+func TestVerifyJWSInvalid(t *testing.T) {
+	privk, _, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	provider, err := ProviderFromPrivateKey(privk)
+	require.NoError(t, err)
+
+	jws, err := SignJWS(provider, []byte("payload"))
+	require.NoError(t, err)
+	parts := strings.Split(jws, ".")
+
+	header := func(v string) string { return jwsEncoding.EncodeToString([]byte(v)) }
+	kid := provider.DID().URI
+
+	for name, tc := range map[string]struct {
+		jws string
+		err error
+	}{
+		"segments":  {parts[0] + "." + parts[1], ErrInvalidSignature},
+		"padding":   {parts[0] + "=." + parts[1] + "." + parts[2], ErrInvalidSignature},
+		"crit":      {header(`{"alg":"EdDSA","kid":"`+kid+`","crit":["b64"]}`) + "." + parts[1] + "." + parts[2], ErrInvalidSignature},
+		"alg none":  {header(`{"alg":"none","kid":"`+kid+`"}`) + "." + parts[1] + ".", ErrAlgorithmMismatch},
+		"alg wrong": {header(`{"alg":"ES256K","kid":"`+kid+`"}`) + "." + parts[1] + "." + parts[2], ErrAlgorithmMismatch},
+		"no kid":    {header(`{"alg":"EdDSA"}`) + "." + parts[1] + "." + parts[2], ErrInvalidDID},
+		"signature": {parts[0] + "." + parts[1] + "." + header("short"), ErrMalformedSignature},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := VerifyJWS(NewTrustContext(), tc.jws)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestES256KSignatureConversion(t *testing.T) {
+	_, err := es256kToDER(make([]byte, 64))
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = es256kToDER(make([]byte, 63))
+	require.ErrorIs(t, err, ErrInvalidSignature)
+}
@@ -15,11 +15,21 @@
 package did
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha512"
 	"fmt"
+	"hash"
 	"strings"
 
+	secpECDSA "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
 	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/crypto/pb"
+	"github.com/libp2p/go-libp2p/core/peer"
 	mb "github.com/multiformats/go-multibase"
+	mh "github.com/multiformats/go-multihash"
 	varint "github.com/multiformats/go-varint"
 
 	"github.com/depinkit/crypto"
@@ -37,7 +47,10 @@ type PrivateKeyProvider struct {
 	privk crypto.PrivKey
 }
 
-var _ Provider = (*PrivateKeyProvider)(nil)
+var (
+	_ Provider      = (*PrivateKeyProvider)(nil)
+	_ ContextSigner = (*PrivateKeyProvider)(nil)
+)
 
 func NewAnchor(did DID, pubk crypto.PubKey) Anchor {
 	return &PublicKeyAnchor{
@@ -58,9 +71,29 @@ func (a *PublicKeyAnchor) DID() DID {
 }
 
 func (a *PublicKeyAnchor) Verify(data []byte, sig []byte) error {
+	if err := checkSignatureSize(a.pubk, sig); err != nil {
+		return err
+	}
+
+	switch a.pubk.Type() {
+	case crypto.Secp256k1, crypto.Eth:
+		if err := checkLowS(sig); err != nil {
+			return err
+		}
+	}
+
+	if digest, ok := ecdsaCurveDigest(a.pubk, data); ok {
+		return verifyDigest(a.pubk, digest, sig)
+	}
+
 	ok, err := a.pubk.Verify(data, sig)
 	if err != nil {
-		return err
+		// RSA reports a mismatch as an error; the other key types only
+		// fail to verify when the signature cannot be decoded
+		if a.pubk.Type() == libp2p_crypto.RSA {
+			return fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+		}
+		return fmt.Errorf("%w: %w", ErrMalformedSignature, err)
 	}
 
 	if !ok {
@@ -74,12 +107,158 @@ func (a *PublicKeyAnchor) PublicKey() crypto.PubKey {
 	return a.pubk
 }
 
+func (a *PublicKeyAnchor) KeyType() pb.KeyType {
+	return a.pubk.Type()
+}
+
+func (a *PublicKeyAnchor) Algorithm() string {
+	return keyAlgorithm(a.pubk)
+}
+
+const (
+	ed25519SignatureSize = 64
+	// minDERSignatureSize is the smallest DER ECDSA signature: a sequence
+	// of two one-byte integers.
+	minDERSignatureSize = 8
+)
+
+// checkSignatureSize rejects signatures whose length cannot be valid for
+// the key, before any parsing or curve arithmetic.
+func checkSignatureSize(pubk crypto.PubKey, sig []byte) error {
+	lo, hi := 0, 0
+	switch pubk.Type() {
+	case crypto.Ed25519:
+		lo, hi = ed25519SignatureSize, ed25519SignatureSize
+
+	case crypto.Secp256k1, crypto.Eth:
+		lo, hi = minDERSignatureSize, maxDERSignatureSize(32)
+
+	case libp2p_crypto.ECDSA:
+		std, err := libp2p_crypto.PubKeyToStdKey(pubk)
+		if err != nil {
+			return fmt.Errorf("ecdsa public key: %w", err)
+		}
+		ecpub, ok := std.(*ecdsa.PublicKey)
+		if !ok {
+			return ErrInvalidKeyType
+		}
+		lo, hi = minDERSignatureSize, maxDERSignatureSize((ecpub.Curve.Params().BitSize+7)/8)
+
+	case libp2p_crypto.RSA:
+		key, err := rsaPublicKey(pubk)
+		if err != nil {
+			return err
+		}
+		lo, hi = key.Size(), key.Size()
+
+	default:
+		return nil
+	}
+
+	if len(sig) < lo || len(sig) > hi {
+		if lo == hi {
+			return fmt.Errorf("signature length %d, expected %d: %w", len(sig), lo, ErrMalformedSignature)
+		}
+		return fmt.Errorf("signature length %d, expected %d to %d: %w", len(sig), lo, hi, ErrMalformedSignature)
+	}
+
+	return nil
+}
+
+// maxDERSignatureSize is the largest DER ECDSA signature for a scalar of
+// size bytes: each integer may need a leading zero byte, and the sequence
+// length takes two bytes once the content exceeds 127 bytes.
+func maxDERSignatureSize(size int) int {
+	content := 2 * (2 + size + 1)
+	if content > 127 {
+		return content + 3
+	}
+	return content + 2
+}
+
+// checkLowS rejects DER secp256k1 signatures that are not in canonical
+// low-S form (S <= n/2), so that a signature has a single valid encoding.
+func checkLowS(sig []byte) error {
+	parsed, err := secpECDSA.ParseDERSignature(sig)
+	if err != nil {
+		return fmt.Errorf("parse signature: %w: %v", ErrMalformedSignature, err)
+	}
+
+	s := parsed.S()
+	if s.IsOverHalfOrder() {
+		return fmt.Errorf("non-canonical high-S signature: %w", ErrInvalidSignature)
+	}
+
+	return nil
+}
+
+// Equal reports whether other anchors the same DID with the same public
+// key, comparing raw key bytes rather than struct identity.
+func (a *PublicKeyAnchor) Equal(other Anchor) bool {
+	if other == nil || !a.did.Equal(other.DID()) {
+		return false
+	}
+
+	return publicKeysEqual(a.pubk, other.PublicKey())
+}
+
+// Fingerprint returns a stable identifier of the anchor's public key: the
+// base58 sha2-256 multihash of its raw bytes. It is independent of the DID,
+// so anchors for the same key under different methods share a fingerprint.
+func (a *PublicKeyAnchor) Fingerprint() string {
+	raw, err := a.pubk.Raw()
+	if err != nil {
+		return ""
+	}
+
+	digest, err := mh.Sum(raw, mh.SHA2_256, -1)
+	if err != nil {
+		return ""
+	}
+
+	return digest.B58String()
+}
+
+func publicKeysEqual(a, b crypto.PubKey) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	rawA, err := a.Raw()
+	if err != nil {
+		return false
+	}
+	rawB, err := b.Raw()
+	if err != nil {
+		return false
+	}
+
+	return a.Type() == b.Type() && bytes.Equal(rawA, rawB)
+}
+
 func (p *PrivateKeyProvider) DID() DID {
 	return p.did
 }
 
 func (p *PrivateKeyProvider) Sign(data []byte) ([]byte, error) {
-	return p.privk.Sign(data)
+	return signData(p.privk, data)
+}
+
+// signData signs data as PublicKeyAnchor.Verify expects; P-384 and P-521
+// keys sign the SHA-384 and SHA-512 digest rather than the SHA-256 digest
+// libp2p uses.
+func signData(privk crypto.PrivKey, data []byte) ([]byte, error) {
+	if digest, ok := ecdsaCurveDigest(privk.GetPublic(), data); ok {
+		return signDigest(privk, digest)
+	}
+
+	return privk.Sign(data)
+}
+
+// SignContext signs data with the in-memory key; signing never blocks so the
+// context is ignored.
+func (p *PrivateKeyProvider) SignContext(_ context.Context, data []byte) ([]byte, error) {
+	return p.Sign(data)
 }
 
 func (p *PrivateKeyProvider) PrivateKey() (crypto.PrivKey, error) {
@@ -99,22 +278,70 @@ func FromID(id crypto.ID) (DID, error) {
 	return FromPublicKey(pubk), nil
 }
 
+// FromPeerID returns the did:key DID for a libp2p peer ID. Only peer IDs
+// that inline their public key (identity multihash, as used for Ed25519
+// and secp256k1 keys) can be mapped; hashed peer IDs, e.g. for RSA keys,
+// fail with peer.ErrNoPublicKey.
+func FromPeerID(p peer.ID) (DID, error) {
+	pubk, err := p.ExtractPublicKey()
+	if err != nil {
+		return DID{}, fmt.Errorf("public key from peer id %s: %w", p, err)
+	}
+
+	return FromPublicKey(pubk), nil
+}
+
+// ToPeerID returns the libp2p peer ID for the key of a did:key DID.
+func ToPeerID(did DID) (peer.ID, error) {
+	pubk, err := PublicKeyFromDID(did)
+	if err != nil {
+		return "", err
+	}
+
+	p, err := peer.IDFromPublicKey(pubk)
+	if err != nil {
+		return "", fmt.Errorf("peer id from public key: %w", err)
+	}
+
+	return p, nil
+}
+
 func FromPublicKey(pubk crypto.PubKey) DID {
 	uri := FormatKeyURI(pubk)
 	return DID{URI: uri}
 }
 
+// PublicKeyFromDID returns the key of a did:key DID. If the DID carries a
+// fragment naming a verification method, the key of that method is returned:
+// the signing key for the self-referencing fragment, or the derived X25519
+// key agreement key for an Ed25519 did:key.
 func PublicKeyFromDID(did DID) (crypto.PubKey, error) {
-	if did.Method() != "key" {
+	base, fragment, hasFragment := strings.Cut(did.URI, "#")
+	baseDID := DID{URI: base}
+	if baseDID.Method() != "key" {
 		return nil, ErrInvalidDID
 	}
 
-	pubk, err := ParseKeyURI(did.URI)
+	pubk, err := ParseKeyURI(base)
 	if err != nil {
 		return nil, fmt.Errorf("parsing did key identifier: %w", err)
 	}
 
-	return pubk, nil
+	if !hasFragment || fragment == baseDID.Identifier() {
+		return pubk, nil
+	}
+
+	if pubk.Type() == crypto.Ed25519 {
+		xkey, vmID, err := keyAgreementKey(baseDID, pubk)
+		if err != nil {
+			return nil, err
+		}
+		if vmID == did.URI {
+			return xkey, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: unknown verification method %q", ErrInvalidDID, fragment)
 }
 
 func AnchorFromPublicKey(pubk crypto.PubKey) (Anchor, error) {
@@ -127,6 +354,83 @@ func ProviderFromPrivateKey(privk crypto.PrivKey) (Provider, error) {
 	return NewProvider(did, privk), nil
 }
 
+// AnchorWithDID creates an anchor for pubk under an arbitrary DID, e.g. a
+// did:web DID backed by a standard key. The caller asserts that the DID and
+// the key correspond; only the DID syntax and the key type are checked.
+func AnchorWithDID(did DID, pubk crypto.PubKey) (Anchor, error) {
+	if err := validateKeyWithDID(did, pubk); err != nil {
+		return nil, err
+	}
+
+	return NewAnchor(did, pubk), nil
+}
+
+// ProviderWithDID creates a provider for privk under an arbitrary DID; see
+// AnchorWithDID.
+func ProviderWithDID(did DID, privk crypto.PrivKey) (Provider, error) {
+	if privk == nil {
+		return nil, fmt.Errorf("nil private key: %w", ErrInvalidKeyType)
+	}
+
+	if err := validateKeyWithDID(did, privk.GetPublic()); err != nil {
+		return nil, err
+	}
+
+	return NewProvider(did, privk), nil
+}
+
+// NewProviderChecked is NewProvider for callers that cannot vouch for the
+// DID: for DIDs derived from a key (did:key, did:peer) it checks that the
+// DID encodes privk's public key. Other DIDs get the checks of
+// ProviderWithDID.
+func NewProviderChecked(did DID, privk crypto.PrivKey) (Provider, error) {
+	if privk == nil {
+		return nil, fmt.Errorf("nil private key: %w", ErrInvalidKeyType)
+	}
+
+	pubk := privk.GetPublic()
+	switch did.Method() {
+	case "key":
+		if !FromPublicKey(pubk).Equal(did) {
+			return nil, &DIDError{Op: "check provider", DID: did.URI, Err: fmt.Errorf("%w: did does not match private key", ErrInvalidDID)}
+		}
+
+	case peerMethod:
+		peerDID, err := FromPeerKey(pubk)
+		if err != nil {
+			return nil, err
+		}
+		if !peerDID.Equal(did) {
+			return nil, &DIDError{Op: "check provider", DID: did.URI, Err: fmt.Errorf("%w: did does not match private key", ErrInvalidDID)}
+		}
+
+	default:
+		return ProviderWithDID(did, privk)
+	}
+
+	return NewProvider(did, privk), nil
+}
+
+func validateKeyWithDID(did DID, pubk crypto.PubKey) error {
+	if did.Empty() {
+		return fmt.Errorf("empty did: %w", ErrInvalidDID)
+	}
+
+	if _, err := FromString(did.URI); err != nil {
+		return err
+	}
+
+	if pubk == nil {
+		return fmt.Errorf("nil public key: %w", ErrInvalidKeyType)
+	}
+
+	if FormatKeyURI(pubk) == "" {
+		return fmt.Errorf("key type %d cannot be used for verification: %w", pubk.Type(), ErrInvalidKeyType)
+	}
+
+	return nil
+}
+
 // Note: this code originated in https://github.com/ucan-wg/go-ucan/blob/main/didkey/key.go
 // Copyright applies; some superficial modifications by vyzo.
 
@@ -134,10 +438,36 @@ const (
 	multicodecKindEd25519PubKey   uint64 = 0xed
 	multicodecKindSecp256k1PubKey uint64 = 0xe7
 	multicodecKindEthPubKey       uint64 = 0xef01
+	multicodecKindP256PubKey      uint64 = 0x1200
+	multicodecKindP384PubKey      uint64 = 0x1201
+	multicodecKindP521PubKey      uint64 = 0x1202
+	// bls12_381-g2-pub; varint-encoded as 0xeb 0x01
+	multicodecKindBLS12381G2PubKey uint64 = 0xeb
+	// rsa-pub; the key is a DER-encoded PKCS#1 RSAPublicKey
+	multicodecKindRSAPubKey uint64 = 0x1205
 
 	keyPrefix = "did:key"
 )
 
+// keyLengths is the raw key length for each multicodec; secp256k1 and NIST
+// curve keys are compressed points. RSA keys are variable length and are
+// validated when the DER is parsed.
+var keyLengths = map[uint64]int{
+	multicodecKindEd25519PubKey:    32,
+	multicodecKindSecp256k1PubKey:  33,
+	multicodecKindEthPubKey:        33,
+	multicodecKindP256PubKey:       33,
+	multicodecKindP384PubKey:       49,
+	multicodecKindP521PubKey:       67,
+	multicodecKindBLS12381G2PubKey: 96,
+	multicodecKindX25519PubKey:     32,
+}
+
+// KeyTypeBLS12381G2 is the key type of BLS12-381 G2 public keys; libp2p has
+// no BLS key type, so the multicodec is used. BLS support requires the bls
+// build tag.
+const KeyTypeBLS12381G2 pb.KeyType = 0xeb
+
 func FormatKeyURI(pubk crypto.PubKey) string {
 	raw, err := pubk.Raw()
 	if err != nil {
@@ -152,9 +482,25 @@ func FormatKeyURI(pubk crypto.PubKey) string {
 		t = multicodecKindSecp256k1PubKey
 	case crypto.Eth:
 		t = multicodecKindEthPubKey
+	case libp2p_crypto.ECDSA:
+		t, raw, err = ecdsaCompressedKey(pubk)
+		if err != nil {
+			log.Errorf("unsupported ecdsa key: %s", err)
+			return ""
+		}
+	case KeyTypeBLS12381G2:
+		t = multicodecKindBLS12381G2PubKey
+	case KeyTypeX25519:
+		t = multicodecKindX25519PubKey
+	case libp2p_crypto.RSA:
+		t, raw, err = rsaPKCS1Key(pubk)
+		if err != nil {
+			log.Errorf("unsupported rsa key: %s", err)
+			return ""
+		}
 	default:
 		// we don't support those yet
-		log.Errorf("unsupported key type: %d", t)
+		log.Errorf("unsupported key type: %d", pubk.Type())
 		return ""
 	}
 
@@ -172,37 +518,242 @@ func FormatKeyURI(pubk crypto.PubKey) string {
 }
 
 func ParseKeyURI(uri string) (crypto.PubKey, error) {
+	pubk, err := parseKeyURI(uri)
+	if err != nil {
+		return nil, &DIDError{Op: "parse key", DID: uri, Err: err}
+	}
+
+	return pubk, nil
+}
+
+// DecodeKeyDID decodes a did:key DID into its multicodec and raw key bytes
+// without unmarshaling the key; the key length is checked for codecs with a
+// fixed size.
+func DecodeKeyDID(did DID) (codec uint64, raw []byte, err error) {
+	codec, raw, err = decodeKeyURI(did.URI)
+	if err != nil {
+		return 0, nil, &DIDError{Op: "decode key", DID: did.URI, Err: err}
+	}
+
+	return codec, raw, nil
+}
+
+// KeyTypeOf returns the key type of a did:key DID from its multicodec,
+// without unmarshaling the key; a fragment is ignored. It fails with
+// ErrInvalidDID for other methods and ErrInvalidKeyType for unknown codecs.
+func KeyTypeOf(did DID) (pb.KeyType, error) {
+	base, _, _ := strings.Cut(did.URI, "#")
+	if (DID{URI: base}).Method() != "key" {
+		return 0, &DIDError{Op: "key type", DID: did.URI, Err: ErrInvalidDID}
+	}
+
+	codec, _, err := DecodeKeyDID(DID{URI: base})
+	if err != nil {
+		return 0, err
+	}
+
+	switch codec {
+	case multicodecKindEd25519PubKey:
+		return crypto.Ed25519, nil
+	case multicodecKindSecp256k1PubKey:
+		return crypto.Secp256k1, nil
+	case multicodecKindEthPubKey:
+		return crypto.Eth, nil
+	case multicodecKindP256PubKey, multicodecKindP384PubKey, multicodecKindP521PubKey:
+		return libp2p_crypto.ECDSA, nil
+	case multicodecKindBLS12381G2PubKey:
+		return KeyTypeBLS12381G2, nil
+	case multicodecKindRSAPubKey:
+		return libp2p_crypto.RSA, nil
+	case multicodecKindX25519PubKey:
+		return KeyTypeX25519, nil
+	default:
+		return 0, &DIDError{Op: "key type", DID: did.URI, Err: fmt.Errorf("unknown codec %#x: %w", codec, ErrInvalidKeyType)}
+	}
+}
+
+func decodeKeyURI(uri string) (uint64, []byte, error) {
 	if !strings.HasPrefix(uri, keyPrefix) {
-		return nil, fmt.Errorf("decentralized identifier is not a 'key' type")
+		return 0, nil, fmt.Errorf("decentralized identifier is not a 'key' type")
 	}
 
 	uri = strings.TrimPrefix(uri, keyPrefix+":")
 
 	enc, data, err := mb.Decode(uri)
 	if err != nil {
-		return nil, fmt.Errorf("decoding multibase: %w", err)
+		return 0, nil, fmt.Errorf("decoding multibase: %w", err)
 	}
 
 	if enc != mb.Base58BTC {
-		return nil, fmt.Errorf("unexpected multibase encoding: %s", mb.EncodingToStr[enc])
+		return 0, nil, fmt.Errorf("unexpected multibase encoding: %s", mb.EncodingToStr[enc])
 	}
 
 	keyType, n, err := varint.FromUvarint(data)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if want, ok := keyLengths[keyType]; ok && len(data[n:]) != want {
+		return 0, nil, fmt.Errorf("invalid key length for codec %#x: got %d want %d: %w", keyType, len(data[n:]), want, ErrInvalidKeyType)
+	}
+
+	return keyType, data[n:], nil
+}
+
+func parseKeyURI(uri string) (crypto.PubKey, error) {
+	keyType, raw, err := decodeKeyURI(uri)
 	if err != nil {
 		return nil, err
 	}
 
 	switch keyType {
 	case multicodecKindEd25519PubKey:
-		return libp2p_crypto.UnmarshalEd25519PublicKey(data[n:])
+		return libp2p_crypto.UnmarshalEd25519PublicKey(raw)
 
 	case multicodecKindSecp256k1PubKey:
-		return libp2p_crypto.UnmarshalSecp256k1PublicKey(data[n:])
+		return libp2p_crypto.UnmarshalSecp256k1PublicKey(raw)
 
 	case multicodecKindEthPubKey:
-		return crypto.UnmarshalEthPublicKey(data[n:])
+		return crypto.UnmarshalEthPublicKey(raw)
+
+	case multicodecKindP256PubKey:
+		return unmarshalECDSACompressedKey(elliptic.P256(), raw)
+
+	case multicodecKindP384PubKey:
+		return unmarshalECDSACompressedKey(elliptic.P384(), raw)
+
+	case multicodecKindP521PubKey:
+		return unmarshalECDSACompressedKey(elliptic.P521(), raw)
+
+	case multicodecKindBLS12381G2PubKey:
+		return unmarshalBLSPublicKey(raw)
+
+	case multicodecKindRSAPubKey:
+		return unmarshalRSAPKCS1Key(raw)
+
+	case multicodecKindX25519PubKey:
+		return &X25519PublicKey{raw: append([]byte(nil), raw...)}, nil
 
 	default:
 		return nil, ErrInvalidKeyType
 	}
 }
+
+// JOSE signature algorithms, per RFC 7518 and RFC 8037.
+const (
+	AlgEdDSA  = "EdDSA"
+	AlgES256K = "ES256K"
+	AlgES256  = "ES256"
+	AlgES384  = "ES384"
+	AlgES512  = "ES512"
+	AlgRS256  = "RS256"
+	AlgPS256  = "PS256"
+)
+
+// keyAlgorithm returns the JOSE algorithm for the key, or "" if unknown.
+func keyAlgorithm(pubk crypto.PubKey) string {
+	switch pubk.Type() {
+	case crypto.Ed25519:
+		return AlgEdDSA
+	case crypto.Secp256k1, crypto.Eth:
+		return AlgES256K
+	case libp2p_crypto.RSA:
+		return AlgRS256
+	case libp2p_crypto.ECDSA:
+		std, err := libp2p_crypto.PubKeyToStdKey(pubk)
+		if err != nil {
+			return ""
+		}
+		ecpub, ok := std.(*ecdsa.PublicKey)
+		if !ok {
+			return ""
+		}
+		switch ecpub.Curve {
+		case elliptic.P256():
+			return AlgES256
+		case elliptic.P384():
+			return AlgES384
+		case elliptic.P521():
+			return AlgES512
+		}
+	}
+
+	return ""
+}
+
+// ecdsaCompressedKey returns the multicodec and the compressed point
+// encoding of an ECDSA public key.
+func ecdsaCompressedKey(pubk crypto.PubKey) (uint64, []byte, error) {
+	std, err := libp2p_crypto.PubKeyToStdKey(pubk)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	ecpub, ok := std.(*ecdsa.PublicKey)
+	if !ok {
+		return 0, nil, ErrInvalidKeyType
+	}
+
+	var codec uint64
+	switch ecpub.Curve {
+	case elliptic.P256():
+		codec = multicodecKindP256PubKey
+	case elliptic.P384():
+		codec = multicodecKindP384PubKey
+	case elliptic.P521():
+		codec = multicodecKindP521PubKey
+	default:
+		return 0, nil, fmt.Errorf("unsupported curve %s: %w", ecpub.Curve.Params().Name, ErrInvalidKeyType)
+	}
+
+	return codec, elliptic.MarshalCompressed(ecpub.Curve, ecpub.X, ecpub.Y), nil
+}
+
+// ecdsaCurveHash returns the hash that signatures with an ECDSA key use
+// when it differs from libp2p's SHA-256: SHA-384 for P-384 and SHA-512 for
+// P-521 keys, as for ES384 and ES512.
+func ecdsaCurveHash(pubk crypto.PubKey) (func() hash.Hash, bool) {
+	if pubk.Type() != libp2p_crypto.ECDSA {
+		return nil, false
+	}
+
+	std, err := libp2p_crypto.PubKeyToStdKey(pubk)
+	if err != nil {
+		return nil, false
+	}
+	ecpub, ok := std.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, false
+	}
+
+	switch ecpub.Curve {
+	case elliptic.P384():
+		return sha512.New384, true
+	case elliptic.P521():
+		return sha512.New, true
+	default:
+		return nil, false
+	}
+}
+
+// ecdsaCurveDigest hashes data with the curve hash of the key's curve; ok is
+// false for keys that sign SHA-256 as libp2p does.
+func ecdsaCurveDigest(pubk crypto.PubKey, data []byte) (digest []byte, ok bool) {
+	newHash, ok := ecdsaCurveHash(pubk)
+	if !ok {
+		return nil, false
+	}
+
+	h := newHash()
+	h.Write(data)
+	return h.Sum(nil), true
+}
+
+func unmarshalECDSACompressedKey(curve elliptic.Curve, data []byte) (crypto.PubKey, error) {
+	x, y := elliptic.UnmarshalCompressed(curve, data)
+	if x == nil {
+		return nil, fmt.Errorf("invalid compressed %s key: %w", curve.Params().Name, ErrInvalidKeyType)
+	}
+
+	return libp2p_crypto.ECDSAPublicKeyFromPubKey(ecdsa.PublicKey{Curve: curve, X: x, Y: y})
+}
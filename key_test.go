@@ -9,13 +9,19 @@
 package did
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha512"
 	"encoding/hex"
 	"fmt"
 	"testing"
 
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	secpECDSA "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/crypto/pb"
+	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/multiformats/go-multibase"
 	varint "github.com/multiformats/go-varint"
 	"github.com/stretchr/testify/require"
@@ -175,6 +181,22 @@ func TestParseKeyURITruncatedPayload(t *testing.T) {
 	_, err := ParseKeyURI("did:key:" + enc)
 
 	require.Error(t, err, "expected failure for truncated payload")
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+	require.Contains(t, err.Error(), "invalid key length for codec 0xed: got 0 want 32")
+}
+
+func TestParseKeyURIKeyLengths(t *testing.T) {
+	for codec, want := range keyLengths {
+		for _, size := range []int{want - 1, want + 1} {
+			data := append(varint.ToUvarint(codec), make([]byte, size)...)
+			enc, err := multibase.Encode(multibase.Base58BTC, data)
+			require.NoError(t, err)
+
+			_, err = ParseKeyURI("did:key:" + enc)
+			require.ErrorIs(t, err, ErrInvalidKeyType)
+			require.Contains(t, err.Error(), fmt.Sprintf("got %d want %d", size, want))
+		}
+	}
 }
 
 // unsupported key type in FormatKeyURI → should return ""
@@ -209,3 +231,433 @@ func TestFormatKeyURIErrorOnRaw(t *testing.T) {
 	uri := FormatKeyURI(badRawKey{})
 	require.Equal(t, "", uri, "FormatKeyURI should return empty string on Raw() error")
 }
+
+// AnchorWithDID/ProviderWithDID bind a standard key to a caller-chosen DID.
+func TestAnchorAndProviderWithDID(t *testing.T) {
+	privk, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+
+	did, err := FromString("did:web:example.com")
+	require.NoError(t, err)
+
+	anchor, err := AnchorWithDID(did, pubk)
+	require.NoError(t, err)
+	require.Equal(t, did, anchor.DID())
+
+	prov, err := ProviderWithDID(did, privk)
+	require.NoError(t, err)
+	require.Equal(t, did, prov.DID())
+	require.Equal(t, did, prov.Anchor().DID())
+
+	msg := []byte("custom-method")
+	sig, err := prov.Sign(msg)
+	require.NoError(t, err)
+	require.NoError(t, anchor.Verify(msg, sig))
+
+	_, err = AnchorWithDID(DID{}, pubk)
+	require.ErrorIs(t, err, ErrInvalidDID)
+
+	_, err = AnchorWithDID(DID{URI: "not-a-did"}, pubk)
+	require.ErrorIs(t, err, ErrInvalidDID)
+
+	_, err = AnchorWithDID(did, nil)
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+
+	_, err = AnchorWithDID(did, bogusKey{})
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+
+	_, err = ProviderWithDID(did, nil)
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+}
+
+func TestAnchorKeyTypeAndAlgorithm(t *testing.T) {
+	_, edPub, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	_, secpPub, err := crypto.GenerateKeyPair(crypto.Secp256k1)
+	require.NoError(t, err)
+	_, p256Pub, err := libp2p_crypto.GenerateECDSAKeyPair(rand.Reader)
+	require.NoError(t, err)
+	sk, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	ethPub, err := crypto.UnmarshalEthPublicKey(sk.PubKey().SerializeCompressed())
+	require.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		pubk    crypto.PubKey
+		keyType pb.KeyType
+		alg     string
+	}{
+		{"Ed25519", edPub, crypto.Ed25519, AlgEdDSA},
+		{"Secp256k1", secpPub, crypto.Secp256k1, AlgES256K},
+		{"Eth", ethPub, crypto.Eth, AlgES256K},
+		{"P256", p256Pub, libp2p_crypto.ECDSA, AlgES256},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			anchor, err := AnchorFromPublicKey(tc.pubk)
+			require.NoError(t, err)
+			require.Equal(t, tc.keyType, anchor.KeyType())
+			require.Equal(t, tc.alg, anchor.Algorithm())
+		})
+	}
+
+	require.Equal(t, "", NewAnchor(DID{}, bogusKey{}).Algorithm())
+}
+
+// highSDER re-encodes sig in DER with S replaced by n - S.
+func highSDER(sig *secpECDSA.Signature) []byte {
+	r, s := sig.R(), sig.S()
+	s.Negate()
+
+	rb, sb := r.Bytes(), s.Bytes()
+	encInt := func(b []byte) []byte {
+		for len(b) > 1 && b[0] == 0 {
+			b = b[1:]
+		}
+		if b[0]&0x80 != 0 {
+			b = append([]byte{0}, b...)
+		}
+		return append([]byte{0x02, byte(len(b))}, b...)
+	}
+
+	body := append(encInt(rb[:]), encInt(sb[:])...)
+	return append([]byte{0x30, byte(len(body))}, body...)
+}
+
+func TestVerifyRejectsHighS(t *testing.T) {
+	sk, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	msg := []byte("malleable")
+
+	// secp256k1 anchor: libp2p hashes with SHA-256
+	privk, pubk, err := crypto.GenerateKeyPair(crypto.Secp256k1)
+	require.NoError(t, err)
+	anchor, err := AnchorFromPublicKey(pubk)
+	require.NoError(t, err)
+
+	raw, err := privk.Sign(msg)
+	require.NoError(t, err)
+	require.NoError(t, anchor.Verify(msg, raw))
+
+	sig, err := secpECDSA.ParseDERSignature(raw)
+	require.NoError(t, err)
+	high := highSDER(sig)
+	ok, err := pubk.Verify(msg, high)
+	require.NoError(t, err)
+	require.True(t, ok, "underlying key accepts the high-S form")
+	require.ErrorIs(t, anchor.Verify(msg, high), ErrInvalidSignature)
+	require.ErrorIs(t, anchor.Verify(msg, []byte{0x30, 0x01, 0x00}), ErrMalformedSignature)
+
+	// eth anchor
+	ethPubk, err := crypto.UnmarshalEthPublicKey(sk.PubKey().SerializeCompressed())
+	require.NoError(t, err)
+	ethAnchor, err := AnchorFromPublicKey(ethPubk)
+	require.NoError(t, err)
+
+	hasher := sha3.NewLegacyKeccak256()
+	fmt.Fprintf(hasher, "\x19Ethereum Signed Message:\n%d", len(msg))
+	hasher.Write(msg)
+	ethSig := secpECDSA.Sign(sk, hasher.Sum(nil))
+	require.NoError(t, ethAnchor.Verify(msg, ethSig.Serialize()))
+	require.ErrorIs(t, ethAnchor.Verify(msg, highSDER(ethSig)), ErrInvalidSignature)
+}
+
+func TestAnchorEqualAndFingerprint(t *testing.T) {
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	_, otherPubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+
+	did := FromPublicKey(pubk)
+	anchor := NewAnchor(did, pubk).(*PublicKeyAnchor)
+
+	raw, err := pubk.Raw()
+	require.NoError(t, err)
+	copied, err := libp2p_crypto.UnmarshalEd25519PublicKey(raw)
+	require.NoError(t, err)
+
+	require.True(t, anchor.Equal(NewAnchor(did, copied)))
+	require.False(t, anchor.Equal(NewAnchor(did, otherPubk)))
+	require.False(t, anchor.Equal(NewAnchor(DID{URI: "did:web:example.com"}, pubk)))
+	require.False(t, anchor.Equal(nil))
+
+	fp := anchor.Fingerprint()
+	require.NotEmpty(t, fp)
+	require.Equal(t, fp, NewAnchor(DID{URI: "did:web:example.com"}, copied).(*PublicKeyAnchor).Fingerprint())
+	require.NotEqual(t, fp, NewAnchor(did, otherPubk).(*PublicKeyAnchor).Fingerprint())
+	require.Empty(t, NewAnchor(did, badRawKey{}).(*PublicKeyAnchor).Fingerprint())
+}
+
+func TestNewProviderChecked(t *testing.T) {
+	privk := mustPrivKey(t)
+	other := mustPrivKey(t)
+
+	provider, err := NewProviderChecked(FromPublicKey(privk.GetPublic()), privk)
+	require.NoError(t, err)
+	require.Equal(t, FromPublicKey(privk.GetPublic()), provider.Anchor().DID())
+
+	_, err = NewProviderChecked(FromPublicKey(other.GetPublic()), privk)
+	require.ErrorIs(t, err, ErrInvalidDID)
+
+	peerDID, err := FromPeerKey(privk.GetPublic())
+	require.NoError(t, err)
+	_, err = NewProviderChecked(peerDID, privk)
+	require.NoError(t, err)
+	_, err = NewProviderChecked(peerDID, other)
+	require.ErrorIs(t, err, ErrInvalidDID)
+
+	_, err = NewProviderChecked(DID{URI: "did:web:example.com"}, privk)
+	require.NoError(t, err)
+	_, err = NewProviderChecked(DID{URI: "did:web:example.com"}, nil)
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+}
+
+func TestDecodeKeyDID(t *testing.T) {
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	raw, err := pubk.Raw()
+	require.NoError(t, err)
+
+	codec, decoded, err := DecodeKeyDID(FromPublicKey(pubk))
+	require.NoError(t, err)
+	require.Equal(t, multicodecKindEd25519PubKey, codec)
+	require.Equal(t, raw, decoded)
+
+	_, _, err = DecodeKeyDID(DID{URI: "did:web:example.com"})
+	require.Error(t, err)
+
+	// codecs we cannot unmarshal are still decoded
+	unknown, err := multibase.Encode(multibase.Base58BTC, append(varint.ToUvarint(0x1234), 1, 2, 3))
+	require.NoError(t, err)
+	codec, decoded, err = DecodeKeyDID(DID{URI: keyPrefix + ":" + unknown})
+	require.NoError(t, err)
+	require.Equal(t, uint64(0x1234), codec)
+	require.Equal(t, []byte{1, 2, 3}, decoded)
+}
+
+func TestKeyTypeOf(t *testing.T) {
+	sk, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	ethPub, err := crypto.UnmarshalEthPublicKey(sk.PubKey().SerializeCompressed())
+	require.NoError(t, err)
+
+	keys := []crypto.PubKey{ethPub}
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		priv, _, err := libp2p_crypto.GenerateECDSAKeyPairWithCurve(curve, rand.Reader)
+		require.NoError(t, err)
+		keys = append(keys, priv.GetPublic())
+	}
+	for _, keyType := range []int{crypto.Ed25519, crypto.Secp256k1} {
+		_, pubk, err := crypto.GenerateKeyPair(keyType)
+		require.NoError(t, err)
+		keys = append(keys, pubk)
+	}
+
+	for _, pubk := range keys {
+		did := FromPublicKey(pubk)
+		keyType, err := KeyTypeOf(did)
+		require.NoError(t, err)
+		require.Equal(t, pubk.Type(), keyType)
+
+		keyType, err = KeyTypeOf(DID{URI: did.URI + "#" + did.Identifier()})
+		require.NoError(t, err)
+		require.Equal(t, pubk.Type(), keyType)
+	}
+
+	_, err = KeyTypeOf(DID{URI: "did:web:example.com"})
+	require.ErrorIs(t, err, ErrInvalidDID)
+
+	unknown, err := multibase.Encode(multibase.Base58BTC, append(varint.ToUvarint(0x1234), 1, 2, 3))
+	require.NoError(t, err)
+	_, err = KeyTypeOf(DID{URI: keyPrefix + ":" + unknown})
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+}
+
+func TestPeerIDRoundTrip(t *testing.T) {
+	for _, keyType := range []int{crypto.Ed25519, crypto.Secp256k1} {
+		_, pubk, err := crypto.GenerateKeyPair(keyType)
+		require.NoError(t, err)
+
+		did := FromPublicKey(pubk)
+		p, err := ToPeerID(did)
+		require.NoError(t, err)
+
+		want, err := peer.IDFromPublicKey(pubk)
+		require.NoError(t, err)
+		require.Equal(t, want, p)
+
+		back, err := FromPeerID(p)
+		require.NoError(t, err)
+		require.Equal(t, did, back)
+	}
+
+	// RSA peer IDs are hashed and cannot be reversed
+	_, rsaPub, err := libp2p_crypto.GenerateRSAKeyPair(2048, rand.Reader)
+	require.NoError(t, err)
+	hashed, err := ToPeerID(FromPublicKey(rsaPub))
+	require.NoError(t, err)
+	_, err = FromPeerID(hashed)
+	require.ErrorIs(t, err, peer.ErrNoPublicKey)
+
+	_, err = ToPeerID(DID{URI: "did:web:example.com"})
+	require.ErrorIs(t, err, ErrInvalidDID)
+}
+
+func TestFormatKeyURILogsKeyType(t *testing.T) {
+	l := setTestLogger(t)
+
+	require.Empty(t, FormatKeyURI(bogusKey{}))
+	require.Equal(t, []string{"unsupported key type: 170"}, l.errors)
+}
+
+func TestVerifySignatureSizeBounds(t *testing.T) {
+	edPriv, _, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	secpPriv, _, err := crypto.GenerateKeyPair(crypto.Secp256k1)
+	require.NoError(t, err)
+	p256Priv, _, err := libp2p_crypto.GenerateECDSAKeyPairWithCurve(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p521Priv, _, err := libp2p_crypto.GenerateECDSAKeyPairWithCurve(elliptic.P521(), rand.Reader)
+	require.NoError(t, err)
+	rsaPriv, _, err := libp2p_crypto.GenerateRSAKeyPair(2048, rand.Reader)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		privk   crypto.PrivKey
+		tooLong int
+	}{
+		{"ed25519", edPriv, 65},
+		{"secp256k1", secpPriv, 73},
+		{"p256", p256Priv, 73},
+		{"p521", p521Priv, 142},
+		{"rsa", rsaPriv, 257},
+	}
+
+	data := []byte("payload")
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			anchor := NewAnchor(FromPublicKey(tc.privk.GetPublic()), tc.privk.GetPublic())
+
+			sig, err := NewProvider(anchor.DID(), tc.privk).Sign(data)
+			require.NoError(t, err)
+			require.NoError(t, anchor.Verify(data, sig))
+
+			require.ErrorIs(t, anchor.Verify(data, nil), ErrMalformedSignature)
+			require.ErrorIs(t, anchor.Verify(data, make([]byte, tc.tooLong)), ErrMalformedSignature)
+			require.ErrorIs(t, anchor.Verify(data, make([]byte, 1<<20)), ErrMalformedSignature)
+		})
+	}
+}
+
+func TestVerifyMalformedSignature(t *testing.T) {
+	data := []byte("payload")
+	for _, typ := range []int{crypto.Ed25519, crypto.Secp256k1} {
+		privk, pubk, err := crypto.GenerateKeyPair(typ)
+		require.NoError(t, err)
+		anchor := NewAnchor(FromPublicKey(pubk), pubk)
+
+		sig, err := privk.Sign(data)
+		require.NoError(t, err)
+
+		err = anchor.Verify([]byte("other payload"), sig)
+		require.ErrorIs(t, err, ErrInvalidSignature)
+		require.NotErrorIs(t, err, ErrMalformedSignature)
+
+		garbage := make([]byte, len(sig))
+		err = anchor.Verify(data, garbage)
+		if typ == crypto.Ed25519 {
+			// every 64-byte string decodes as an Ed25519 signature
+			require.ErrorIs(t, err, ErrInvalidSignature)
+			continue
+		}
+		require.ErrorIs(t, err, ErrMalformedSignature)
+		require.NotErrorIs(t, err, ErrInvalidSignature)
+	}
+
+	p256Priv, _, err := libp2p_crypto.GenerateECDSAKeyPairWithCurve(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	anchor := NewAnchor(FromPublicKey(p256Priv.GetPublic()), p256Priv.GetPublic())
+	require.ErrorIs(t, anchor.Verify(data, make([]byte, 16)), ErrMalformedSignature)
+
+	rsaPriv, _, err := libp2p_crypto.GenerateRSAKeyPair(2048, rand.Reader)
+	require.NoError(t, err)
+	anchor = NewAnchor(FromPublicKey(rsaPriv.GetPublic()), rsaPriv.GetPublic())
+	sig, err := rsaPriv.Sign(data)
+	require.NoError(t, err)
+	err = anchor.Verify([]byte("other payload"), sig)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+	require.NotErrorIs(t, err, ErrMalformedSignature)
+}
+
+func TestNISTCurveKeys(t *testing.T) {
+	cases := []struct {
+		name   string
+		curve  elliptic.Curve
+		vector string
+		alg    string
+		digest func([]byte) []byte
+	}{
+		{"P384", elliptic.P384(), "did:key:z82Lm1MpAkeJcix9K8TMiLd5NMAhnwkjjCBeWHXyu3U4oT2MVJJKXkcVBgjGhnLBn2Kaau9", AlgES384,
+			func(data []byte) []byte { h := sha512.Sum384(data); return h[:] }},
+		{"P521", elliptic.P521(), "did:key:z2J9gaYxrKVpdoG9A4gRnmpnRCcxU6agDtFVVBVdn1JedouoZN7SzcyREXXzWgt3gGiwpoHq7K68X4m32D8HgzG8wv3sY5j7", AlgES512,
+			func(data []byte) []byte { h := sha512.Sum512(data); return h[:] }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// did:key spec vector
+			pubk, err := ParseKeyURI(tc.vector)
+			require.NoError(t, err)
+			require.Equal(t, tc.vector, FormatKeyURI(pubk))
+			anchor := NewAnchor(DID{URI: tc.vector}, pubk)
+			require.Equal(t, tc.alg, anchor.Algorithm())
+
+			privk, pubk, err := libp2p_crypto.GenerateECDSAKeyPairWithCurve(tc.curve, rand.Reader)
+			require.NoError(t, err)
+			did := FromPublicKey(pubk)
+			require.NotEmpty(t, did.URI)
+
+			parsed, err := PublicKeyFromDID(did)
+			require.NoError(t, err)
+			require.True(t, pubk.Equals(parsed))
+
+			// signatures are over the curve's hash, not SHA-256
+			provider := NewProvider(did, privk)
+			data := []byte("enterprise payload")
+			sig, err := provider.Sign(data)
+			require.NoError(t, err)
+			require.NoError(t, provider.Anchor().Verify(data, sig))
+			require.ErrorIs(t, provider.Anchor().Verify([]byte("other"), sig), ErrInvalidSignature)
+
+			std, err := libp2p_crypto.PubKeyToStdKey(pubk)
+			require.NoError(t, err)
+			require.True(t, ecdsa.VerifyASN1(std.(*ecdsa.PublicKey), tc.digest(data), sig))
+			require.NoError(t, provider.Anchor().(DigestVerifier).VerifyDigest(tc.digest(data), sig))
+
+			libp2pSig, err := privk.Sign(data)
+			require.NoError(t, err)
+			require.ErrorIs(t, provider.Anchor().Verify(data, libp2pSig), ErrInvalidSignature)
+
+			// the raw key length is checked against the curve
+			_, raw, err := DecodeKeyDID(did)
+			require.NoError(t, err)
+			codec, _, err := DecodeKeyDID(DID{URI: tc.vector})
+			require.NoError(t, err)
+			short, err := multibase.Encode(multibase.Base58BTC, append(varint.ToUvarint(codec), raw[:len(raw)-1]...))
+			require.NoError(t, err)
+			_, err = ParseKeyURI(keyPrefix + ":" + short)
+			require.ErrorIs(t, err, ErrInvalidKeyType)
+		})
+	}
+
+	// a P-384 key does not parse under the P-521 codec
+	_, raw, err := DecodeKeyDID(DID{URI: cases[0].vector})
+	require.NoError(t, err)
+	mismatched, err := multibase.Encode(multibase.Base58BTC, append(varint.ToUvarint(multicodecKindP521PubKey), raw...))
+	require.NoError(t, err)
+	_, err = ParseKeyURI(keyPrefix + ":" + mismatched)
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+}
@@ -9,11 +9,19 @@
 package did
 
 import (
+	"bytes"
+	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
@@ -26,10 +34,64 @@ const ledgerCLI = "ledger-cli"
 type LedgerWalletProvider struct {
 	did  DID
 	pubk crypto.PubKey
-	acct int
+	cfg  LedgerConfig
 }
 
-var _ Provider = (*LedgerWalletProvider)(nil)
+// LedgerConfig configures how the ledger-cli binary is invoked.
+type LedgerConfig struct {
+	// BinaryPath is the ledger CLI to execute; if empty, ledger-cli is
+	// looked up in PATH.
+	BinaryPath string `json:"binaryPath,omitempty"`
+	// Timeout bounds each CLI invocation; zero means no timeout.
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// Account is the wallet account index; it is ignored if Path is set.
+	Account int `json:"account"`
+	// Path is an explicit BIP-32 derivation path, e.g. m/44'/60'/0'/0/5,
+	// for wallets that do not follow the account-index convention.
+	Path string `json:"path,omitempty"`
+	// OutputFile makes the CLI write its JSON output to a temporary file
+	// (passed with -o) instead of stdout, for CLIs that only write to files.
+	OutputFile bool `json:"outputFile,omitempty"`
+	// SkipAddressCheck disables checking the address the CLI reports
+	// against the Ethereum address of the returned key, for non-Ethereum
+	// ledger apps.
+	SkipAddressCheck bool `json:"skipAddressCheck,omitempty"`
+	// Retries is how many times a transient CLI failure is retried, with
+	// exponential backoff; zero disables retries.
+	Retries int `json:"retries,omitempty"`
+	// RetryBackoff is the delay before the first retry, doubling after
+	// each one; defaults to 500ms.
+	RetryBackoff time.Duration `json:"retryBackoff,omitempty"`
+	// RetryExitCodes are the exit codes treated as transient (e.g. the
+	// device is locked or awaiting confirmation); if empty, any nonzero
+	// exit is. Failures to start or to exit normally are never retried.
+	RetryExitCodes []int `json:"retryExitCodes,omitempty"`
+}
+
+const defaultLedgerRetryBackoff = 500 * time.Millisecond
+
+var (
+	_ Provider      = (*LedgerWalletProvider)(nil)
+	_ ContextSigner = (*LedgerWalletProvider)(nil)
+)
+
+// LedgerCommandError is returned when ledger-cli fails; it matches
+// ErrLedgerCommand with errors.Is. ExitCode is -1 if the command did not
+// exit normally (e.g. it was killed on timeout).
+type LedgerCommandError struct {
+	Command  string
+	ExitCode int
+	Stderr   string
+	Err      error
+}
+
+func (e *LedgerCommandError) Error() string {
+	return fmt.Sprintf("%s: %s (exit status %d): %s", ErrLedgerCommand, e.Command, e.ExitCode, e.Stderr)
+}
+
+func (e *LedgerCommandError) Unwrap() []error {
+	return []error{ErrLedgerCommand, e.Err}
+}
 
 type LedgerKeyOutput struct {
 	Key     string `json:"key"`
@@ -47,19 +109,34 @@ type LedgerSignECDSAOutput struct {
 }
 
 func NewLedgerWalletProvider(acct int) (Provider, error) {
-	tmp, err := getLedgerTmpFile()
-	if err != nil {
-		return nil, err
+	return NewLedgerWalletProviderWithConfig(LedgerConfig{Account: acct})
+}
+
+// NewLedgerWalletProviderWithPath creates a provider for the key at the
+// given BIP-32 derivation path.
+func NewLedgerWalletProviderWithPath(path string) (Provider, error) {
+	// an empty config path selects the account key instead
+	if path == "" {
+		return nil, fmt.Errorf("invalid derivation path %q: must start with m/", path)
+	}
+
+	return NewLedgerWalletProviderWithConfig(LedgerConfig{Path: path})
+}
+
+func NewLedgerWalletProviderWithConfig(cfg LedgerConfig) (Provider, error) {
+	if cfg.Path != "" {
+		if err := validateDerivationPath(cfg.Path); err != nil {
+			return nil, err
+		}
 	}
-	defer os.Remove(tmp)
 
 	var output LedgerKeyOutput
 	if err := ledgerExec(
-		tmp,
+		context.Background(),
+		cfg,
 		&output,
 		"key",
-		"-o", tmp,
-		"-a", fmt.Sprintf("%d", acct),
+		cfg.keyArgs()...,
 	); err != nil {
 		return nil, fmt.Errorf("error executing ledger cli: %w", err)
 	}
@@ -75,37 +152,248 @@ func NewLedgerWalletProvider(acct int) (Provider, error) {
 		return nil, fmt.Errorf("unmarshal ledger raw key: %w", err)
 	}
 
+	if !cfg.SkipAddressCheck {
+		if err := checkLedgerAddress(pubk, output.Address); err != nil {
+			return nil, err
+		}
+	}
+
 	did := FromPublicKey(pubk)
 
 	return &LedgerWalletProvider{
 		did:  did,
 		pubk: pubk,
-		acct: acct,
+		cfg:  cfg,
 	}, nil
 }
 
-func ledgerExec(tmp string, output interface{}, args ...string) error {
-	ledger, err := exec.LookPath(ledgerCLI)
+// LedgerWallet hands out providers for several accounts of one ledger
+// device, deriving each account's key only once.
+type LedgerWallet struct {
+	mx        sync.Mutex
+	cfg       LedgerConfig
+	providers map[int]*LedgerWalletProvider
+}
+
+// NewLedgerWallet creates a wallet using ledger-cli from PATH.
+func NewLedgerWallet() (*LedgerWallet, error) {
+	return NewLedgerWalletWithConfig(LedgerConfig{})
+}
+
+// NewLedgerWalletWithConfig creates a wallet invoking the CLI as configured
+// by cfg; cfg.Account and cfg.Path are ignored. The CLI is located once, up front.
+func NewLedgerWalletWithConfig(cfg LedgerConfig) (*LedgerWallet, error) {
+	if cfg.BinaryPath == "" {
+		ledger, err := exec.LookPath(ledgerCLI)
+		if err != nil {
+			return nil, fmt.Errorf("can't find %s in PATH: %w", ledgerCLI, err)
+		}
+		cfg.BinaryPath = ledger
+	}
+
+	return &LedgerWallet{
+		cfg:       cfg,
+		providers: make(map[int]*LedgerWalletProvider),
+	}, nil
+}
+
+// Provider returns the provider for acct, querying the device for its key
+// on first use.
+func (w *LedgerWallet) Provider(acct int) (Provider, error) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if p, ok := w.providers[acct]; ok {
+		return p, nil
+	}
+
+	cfg := w.cfg
+	cfg.Account = acct
+	cfg.Path = ""
+	p, err := NewLedgerWalletProviderWithConfig(cfg)
 	if err != nil {
-		return fmt.Errorf("can't find %s in PATH: %w", ledgerCLI, err)
+		return nil, err
+	}
+
+	w.providers[acct] = p.(*LedgerWalletProvider)
+	return p, nil
+}
+
+// DIDs returns the DIDs of the accounts derived so far, ordered by account.
+func (w *LedgerWallet) DIDs() []DID {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	accts := make([]int, 0, len(w.providers))
+	for acct := range w.providers {
+		accts = append(accts, acct)
 	}
+	slices.Sort(accts)
 
-	cmd := exec.Command(ledger, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	result := make([]DID, 0, len(accts))
+	for _, acct := range accts {
+		result = append(result, w.providers[acct].did)
+	}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("get ledger key: %w", err)
+	return result
+}
+
+// checkLedgerAddress checks that the address reported by the CLI is the
+// Ethereum address of the key it returned.
+func checkLedgerAddress(pubk crypto.PubKey, address string) error {
+	want, err := pubKeyEthAddress(pubk)
+	if err != nil {
+		return fmt.Errorf("ledger key address: %w", err)
 	}
 
-	f, err := os.Open(tmp)
+	got, err := parseEthAddress(address)
 	if err != nil {
-		return fmt.Errorf("open ledger output: %w", err)
+		return fmt.Errorf("ledger address: %w", err)
+	}
+
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("ledger address %s does not match key address %s", address, ethChecksumAddress(want))
 	}
-	defer f.Close()
 
-	decoder := json.NewDecoder(f)
-	if err := decoder.Decode(&output); err != nil {
+	return nil
+}
+
+// keyArgs returns the CLI arguments selecting the key: -p <path> if a
+// derivation path is configured, -a <account> otherwise.
+func (cfg LedgerConfig) keyArgs() []string {
+	if cfg.Path != "" {
+		return []string{"-p", cfg.Path}
+	}
+
+	return []string{"-a", strconv.Itoa(cfg.Account)}
+}
+
+// validateDerivationPath checks a BIP-32 path: "m" followed by one or more
+// /index components, each below 2^31 and optionally hardened with ' or h.
+func validateDerivationPath(path string) error {
+	components := strings.Split(path, "/")
+	if components[0] != "m" || len(components) < 2 {
+		return fmt.Errorf("invalid derivation path %q: must start with m/", path)
+	}
+
+	for _, c := range components[1:] {
+		index := strings.TrimRight(c, "'h")
+		if len(c)-len(index) > 1 {
+			return fmt.Errorf("invalid derivation path %q: bad component %q", path, c)
+		}
+
+		if index == "" || strings.TrimLeft(index, "0123456789") != "" {
+			return fmt.Errorf("invalid derivation path %q: bad component %q", path, c)
+		}
+
+		if n, err := strconv.ParseUint(index, 10, 32); err != nil || n >= 1<<31 {
+			return fmt.Errorf("invalid derivation path %q: index %s out of range", path, index)
+		}
+	}
+
+	return nil
+}
+
+// ledgerExec runs a ledger-cli subcommand and decodes its JSON output into
+// output. The output is read from the captured stdout, or from a temporary
+// file passed with -o when cfg.OutputFile is set. Transient failures are
+// retried as configured by cfg until ctx is done.
+func ledgerExec(ctx context.Context, cfg LedgerConfig, output interface{}, cmdName string, args ...string) error {
+	ledger := cfg.BinaryPath
+	if ledger == "" {
+		var err error
+		ledger, err = exec.LookPath(ledgerCLI)
+		if err != nil {
+			return fmt.Errorf("can't find %s in PATH: %w", ledgerCLI, err)
+		}
+	}
+
+	backoff := cfg.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultLedgerRetryBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := ledgerExecOnce(ctx, cfg, ledger, output, cmdName, args...)
+		if err == nil || attempt >= cfg.Retries || !cfg.transient(err) {
+			return err
+		}
+
+		log.Debugf("ledger %s failed, retrying in %s: %s", cmdName, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return fmt.Errorf("%w (retry aborted: %w)", err, ctx.Err())
+		}
+		backoff *= 2
+	}
+}
+
+// transient reports whether a ledgerExec failure is worth retrying.
+func (cfg LedgerConfig) transient(err error) bool {
+	var cmdErr *LedgerCommandError
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode <= 0 {
+		return false
+	}
+
+	if len(cfg.RetryExitCodes) == 0 {
+		return true
+	}
+
+	return slices.Contains(cfg.RetryExitCodes, cmdErr.ExitCode)
+}
+
+func ledgerExecOnce(ctx context.Context, cfg LedgerConfig, ledger string, output interface{}, cmdName string, args ...string) error {
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+
+	var tmp string
+	if cfg.OutputFile {
+		var err error
+		tmp, err = getLedgerTmpFile()
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp)
+
+		args = append([]string{"-o", tmp}, args...)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, ledger, append([]string{cmdName}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// a failed command may leave partial output behind; never parse it
+	if err := cmd.Run(); err != nil {
+		cmdErr := &LedgerCommandError{
+			Command:  cmdName,
+			ExitCode: -1,
+			Stderr:   string(bytes.TrimSpace(stderr.Bytes())),
+			Err:      err,
+		}
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			cmdErr.ExitCode = exitErr.ExitCode()
+		}
+
+		return cmdErr
+	}
+
+	data := stdout.Bytes()
+	if cfg.OutputFile {
+		var err error
+		data, err = os.ReadFile(tmp)
+		if err != nil {
+			return fmt.Errorf("open ledger output: %w", err)
+		}
+	}
+
+	if err := json.Unmarshal(data, output); err != nil {
 		return fmt.Errorf("parse ledger output: %w", err)
 	}
 
@@ -128,22 +416,21 @@ func (p *LedgerWalletProvider) DID() DID {
 }
 
 func (p *LedgerWalletProvider) Sign(data []byte) ([]byte, error) {
-	tmp, err := getLedgerTmpFile()
-	if err != nil {
-		return nil, err
-	}
-	defer os.Remove(tmp)
+	return p.SignContext(context.Background(), data)
+}
 
+// SignContext signs data on the ledger device; cancelling ctx kills the
+// ledger-cli subprocess.
+func (p *LedgerWalletProvider) SignContext(ctx context.Context, data []byte) ([]byte, error) {
 	dataHex := hex.EncodeToString(data)
 
 	var output LedgerSignOutput
 	if err := ledgerExec(
-		tmp,
+		ctx,
+		p.cfg,
 		&output,
 		"sign",
-		"-o", tmp,
-		"-a", fmt.Sprintf("%d", p.acct),
-		dataHex,
+		append(p.cfg.keyArgs(), dataHex)...,
 	); err != nil {
 		return nil, fmt.Errorf("error executing ledger cli: %w", err)
 	}
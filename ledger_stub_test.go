@@ -1,11 +1,20 @@
 package did
 
 import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpECDSA "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
 )
 
 // create fake ledger-cli in a temp dir and prepend to PATH
@@ -23,16 +32,19 @@ func fakeLedgerCLI(t *testing.T, script string) func() {
 // valid compressed secp256k1 generator point (33 bytes → 66 hex chars)
 const generatorHex = "0279BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798"
 
+// Ethereum address of the generator point (private key 1)
+const generatorAddress = "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf"
+
 // happy-path up to Sign() returning bytes (we don’t verify the signature)
 func TestLedgerStubHappyPath(t *testing.T) {
 	restore := fakeLedgerCLI(t, `#!/bin/sh
 case "$1" in
   key)
-    echo '{"key":"`+generatorHex+`","address":"0x00"}' > "$3"
+    echo '{"key":"`+generatorHex+`","address":"`+generatorAddress+`"}'
     ;;
   sign)
     # minimal parseable signature: r=1, s=1
-    echo '{"ecdsa":{"v":27,"r":"01","s":"01"}}' > "$3"
+    echo '{"ecdsa":{"v":27,"r":"01","s":"01"}}'
     ;;
 esac
 `)
@@ -59,7 +71,7 @@ func TestLedgerCLIMissing(t *testing.T) {
 // Invalid hex in key JSON → decode ledger key error
 func TestLedgerInvalidHexKey(t *testing.T) {
 	restore := fakeLedgerCLI(t, `#!/bin/sh
-echo '{"key":"ZZZ","address":"x"}' > "$3"
+echo '{"key":"ZZZ","address":"x"}'
 `)
 	defer restore()
 
@@ -74,10 +86,10 @@ func TestLedgerMalformedSignJSON(t *testing.T) {
 	restore := fakeLedgerCLI(t, `#!/bin/sh
 case "$1" in
   key)
-    echo '{"key":"`+generatorHex+`","address":"0x00"}' > "$3"
+    echo '{"key":"`+generatorHex+`","address":"`+generatorAddress+`"}'
     ;;
   sign)
-    echo 'not-json' > "$3"
+    echo 'not-json'
     ;;
 esac
 `)
@@ -90,3 +102,363 @@ esac
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "parse ledger output")
 }
+
+// cancelled context → the hanging ledger-cli is killed and Sign returns
+func TestLedgerSignContextCancel(t *testing.T) {
+	restore := fakeLedgerCLI(t, `#!/bin/sh
+case "$1" in
+  key)
+    echo '{"key":"`+generatorHex+`","address":"`+generatorAddress+`"}'
+    ;;
+  sign)
+    exec sleep 10
+    ;;
+esac
+`)
+	defer restore()
+
+	prov, err := NewLedgerWalletProvider(0)
+	require.NoError(t, err)
+
+	signer, ok := prov.(ContextSigner)
+	require.True(t, ok, "ledger provider must implement ContextSigner")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = signer.SignContext(ctx, []byte("data"))
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 5*time.Second, "sign must not wait for the stuck CLI")
+}
+
+// explicit binary path bypasses PATH lookup and the timeout bounds the CLI
+func TestLedgerConfigBinaryPathAndTimeout(t *testing.T) {
+	bin := filepath.Join(t.TempDir(), "my-ledger")
+	require.NoError(t, os.WriteFile(bin, []byte(`#!/bin/sh
+case "$1" in
+  key)
+    echo '{"key":"`+generatorHex+`","address":"`+generatorAddress+`"}'
+    ;;
+  sign)
+    exec sleep 10
+    ;;
+esac
+`), 0o755))
+
+	orig := os.Getenv("PATH")
+	t.Setenv("PATH", "")
+	defer t.Setenv("PATH", orig)
+
+	prov, err := NewLedgerWalletProviderWithConfig(LedgerConfig{
+		BinaryPath: bin,
+		Timeout:    50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = prov.Sign([]byte("data"))
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 5*time.Second, "timeout must kill the stuck CLI")
+}
+
+// file output mode: the CLI writes JSON to the -o file instead of stdout
+func TestLedgerOutputFileMode(t *testing.T) {
+	restore := fakeLedgerCLI(t, `#!/bin/sh
+[ "$2" = "-o" ] || exit 1
+case "$1" in
+  key)
+    echo '{"key":"`+generatorHex+`","address":"`+generatorAddress+`"}' > "$3"
+    ;;
+  sign)
+    echo '{"ecdsa":{"v":27,"r":"01","s":"01"}}' > "$3"
+    ;;
+esac
+echo 'noise on stdout'
+`)
+	defer restore()
+
+	prov, err := NewLedgerWalletProviderWithConfig(LedgerConfig{OutputFile: true})
+	require.NoError(t, err)
+
+	_, err = prov.Sign([]byte("payload"))
+	require.NoError(t, err)
+}
+
+// CLI failure → stderr is surfaced in the error
+func TestLedgerStderrInError(t *testing.T) {
+	restore := fakeLedgerCLI(t, `#!/bin/sh
+echo 'device locked' >&2
+exit 2
+`)
+	defer restore()
+
+	_, err := NewLedgerWalletProvider(0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "device locked")
+}
+
+// fakeLedgerSignerCLI installs a ledger-cli that re-executes the test binary
+// as TestLedgerHelperProcess, which signs with an in-memory secp256k1 key
+// the way the device does (EIP-191 personal message, r/s/v output).
+func fakeLedgerSignerCLI(t *testing.T, sk *secp256k1.PrivateKey) func() {
+	t.Setenv("DID_LEDGER_HELPER", "1")
+	t.Setenv("DID_LEDGER_KEY", hex.EncodeToString(sk.Serialize()))
+
+	return fakeLedgerCLI(t, fmt.Sprintf(`#!/bin/sh
+exec %q -test.run='^TestLedgerHelperProcess$' -- "$@"
+`, os.Args[0]))
+}
+
+//nolint:revive // not a real test: the body runs only as a fake ledger-cli
+func TestLedgerHelperProcess(t *testing.T) {
+	if os.Getenv("DID_LEDGER_HELPER") != "1" {
+		return
+	}
+
+	args := os.Args
+	for i, arg := range args {
+		if arg == "--" {
+			args = args[i+1:]
+			break
+		}
+	}
+
+	keyBytes, err := hex.DecodeString(os.Getenv("DID_LEDGER_KEY"))
+	if err != nil || len(args) == 0 {
+		os.Exit(2)
+	}
+	sk := secp256k1.PrivKeyFromBytes(keyBytes)
+
+	var output interface{}
+	switch args[0] {
+	case "key":
+		output = LedgerKeyOutput{
+			Key:     hex.EncodeToString(sk.PubKey().SerializeCompressed()),
+			Address: ethChecksumAddress(ethAddress(sk.PubKey())),
+		}
+	case "sign":
+		data, err := hex.DecodeString(args[len(args)-1])
+		if err != nil {
+			os.Exit(2)
+		}
+
+		hasher := sha3.NewLegacyKeccak256()
+		hasher.Write([]byte("\x19Ethereum Signed Message:\n"))
+		fmt.Fprintf(hasher, "%d", len(data))
+		hasher.Write(data)
+
+		// compact signature: [v || r || s]
+		compact := secpECDSA.SignCompact(sk, hasher.Sum(nil), false)
+		output = LedgerSignOutput{
+			ECDSA: LedgerSignECDSAOutput{
+				V: uint(compact[0]),
+				R: hex.EncodeToString(compact[1:33]),
+				S: hex.EncodeToString(compact[33:65]),
+			},
+		}
+	default:
+		os.Exit(2)
+	}
+
+	if err := json.NewEncoder(os.Stdout).Encode(output); err != nil {
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
+
+// the fake CLI produces real signatures, so the ledger path verifies in CI
+func TestLedgerStubVerify(t *testing.T) {
+	sk, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	restore := fakeLedgerSignerCLI(t, sk)
+	defer restore()
+
+	prov, err := NewLedgerWalletProvider(0)
+	require.NoError(t, err)
+
+	data := []byte("i am a walrus")
+	sig, err := prov.Sign(data)
+	require.NoError(t, err)
+
+	anchor := prov.Anchor()
+	require.NoError(t, anchor.Verify(data, sig))
+	require.ErrorIs(t, anchor.Verify([]byte("tampered"), sig), ErrInvalidSignature)
+
+	// the DID alone is enough to verify
+	resolved, err := GetAnchorForDID(prov.DID())
+	require.NoError(t, err)
+	require.NoError(t, resolved.Verify(data, sig))
+}
+
+// CLI exits non-zero after writing garbage → typed error, output not parsed
+func TestLedgerNonZeroExitPartialOutput(t *testing.T) {
+	restore := fakeLedgerCLI(t, `#!/bin/sh
+echo '{"key":"02' > "$3"
+echo 'app not open' >&2
+exit 1
+`)
+	defer restore()
+
+	_, err := NewLedgerWalletProviderWithConfig(LedgerConfig{OutputFile: true})
+	require.ErrorIs(t, err, ErrLedgerCommand)
+	require.NotContains(t, err.Error(), "parse ledger output")
+
+	var cmdErr *LedgerCommandError
+	require.ErrorAs(t, err, &cmdErr)
+	require.Equal(t, "key", cmdErr.Command)
+	require.Equal(t, 1, cmdErr.ExitCode)
+	require.Equal(t, "app not open", cmdErr.Stderr)
+}
+
+// fakeFlakyLedgerCLI installs a ledger-cli that exits with code until it has
+// failed failures times, then returns the key.
+func fakeFlakyLedgerCLI(t *testing.T, failures, code int) string {
+	counter := filepath.Join(t.TempDir(), "count")
+	fakeLedgerCLI(t, fmt.Sprintf(`#!/bin/sh
+n=$(cat %[1]s 2>/dev/null || echo 0)
+echo $((n + 1)) > %[1]s
+if [ "$n" -lt %[2]d ]; then
+  echo 'confirm on device' >&2
+  exit %[3]d
+fi
+echo '{"key":"%[4]s","address":"%[5]s"}'
+`, counter, failures, code, generatorHex, generatorAddress))
+	return counter
+}
+
+func TestLedgerRetryTransient(t *testing.T) {
+	counter := fakeFlakyLedgerCLI(t, 2, 3)
+
+	_, err := NewLedgerWalletProviderWithConfig(LedgerConfig{
+		Retries:        2,
+		RetryBackoff:   time.Millisecond,
+		RetryExitCodes: []int{3},
+	})
+	require.NoError(t, err)
+
+	count, err := os.ReadFile(counter)
+	require.NoError(t, err)
+	require.Equal(t, "3\n", string(count))
+}
+
+func TestLedgerRetryExhausted(t *testing.T) {
+	fakeFlakyLedgerCLI(t, 5, 3)
+
+	_, err := NewLedgerWalletProviderWithConfig(LedgerConfig{
+		Retries:      1,
+		RetryBackoff: time.Millisecond,
+	})
+	var cmdErr *LedgerCommandError
+	require.ErrorAs(t, err, &cmdErr)
+	require.Equal(t, 3, cmdErr.ExitCode)
+}
+
+func TestLedgerRetryPermanent(t *testing.T) {
+	counter := fakeFlakyLedgerCLI(t, 1, 4)
+
+	_, err := NewLedgerWalletProviderWithConfig(LedgerConfig{
+		Retries:        3,
+		RetryBackoff:   time.Millisecond,
+		RetryExitCodes: []int{3},
+	})
+	require.ErrorIs(t, err, ErrLedgerCommand)
+
+	count, err := os.ReadFile(counter)
+	require.NoError(t, err)
+	require.Equal(t, "1\n", string(count), "non-transient exit must not be retried")
+}
+
+func TestLedgerRetryRespectsContext(t *testing.T) {
+	fakeFlakyLedgerCLI(t, 5, 3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := ledgerExec(ctx, LedgerConfig{Retries: 100, RetryBackoff: time.Hour}, &LedgerKeyOutput{}, "key")
+	require.ErrorIs(t, err, ErrLedgerCommand)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestLedgerWalletAccounts(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+	fakeLedgerCLI(t, fmt.Sprintf(`#!/bin/sh
+echo "$1 $3" >> %s
+case "$3" in
+  1) echo '{"key":"%s","address":"%s"}' ;;
+  3) echo '{"key":"02C6047F9441ED7D6D3045406E95C07CD85C778E4B8CEF3CA7ABAC09B95C709EE5","address":"0x2B5AD5c4795c026514f8317c7a215E218DcCD6cF"}' ;;
+  *) exit 1 ;;
+esac
+`, calls, generatorHex, generatorAddress))
+
+	wallet, err := NewLedgerWallet()
+	require.NoError(t, err)
+	require.Empty(t, wallet.DIDs())
+
+	p3, err := wallet.Provider(3)
+	require.NoError(t, err)
+	p1, err := wallet.Provider(1)
+	require.NoError(t, err)
+	require.NotEqual(t, p1.DID(), p3.DID())
+
+	again, err := wallet.Provider(3)
+	require.NoError(t, err)
+	require.Same(t, p3, again)
+
+	_, err = wallet.Provider(7)
+	require.ErrorIs(t, err, ErrLedgerCommand)
+
+	require.Equal(t, []DID{p1.DID(), p3.DID()}, wallet.DIDs())
+
+	invocations, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	require.Equal(t, "key 3\nkey 1\nkey 7\n", string(invocations), "each account is derived once")
+}
+
+func TestLedgerDerivationPath(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+	fakeLedgerCLI(t, fmt.Sprintf(`#!/bin/sh
+echo "$@" >> %s
+case "$1" in
+  key) echo '{"key":"%s","address":"%s"}' ;;
+  sign) echo '{"ecdsa":{"v":27,"r":"01","s":"01"}}' ;;
+esac
+`, calls, generatorHex, generatorAddress))
+
+	prov, err := NewLedgerWalletProviderWithPath("m/44'/60'/0'/0/5")
+	require.NoError(t, err)
+	_, err = prov.Sign([]byte{0xab})
+	require.NoError(t, err)
+
+	invocations, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	require.Equal(t, "key -p m/44'/60'/0'/0/5\nsign -p m/44'/60'/0'/0/5 ab\n", string(invocations))
+
+	for _, path := range []string{"", "m", "44'/60'", "m/", "m/44''", "m/x", "m/2147483648", "m//0", "m/-1"} {
+		_, err := NewLedgerWalletProviderWithPath(path)
+		require.Error(t, err, path)
+	}
+	for _, path := range []string{"m/0", "m/44h/60h/0h/0/0", "m/2147483647'"} {
+		require.NoError(t, validateDerivationPath(path), path)
+	}
+
+	invocations, err = os.ReadFile(calls)
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(invocations), "\n"), "invalid paths must not reach the CLI")
+}
+
+func TestLedgerAddressMismatch(t *testing.T) {
+	fakeLedgerCLI(t, `#!/bin/sh
+echo '{"key":"`+generatorHex+`","address":"0x2B5AD5c4795c026514f8317c7a215E218DcCD6cF"}'
+`)
+
+	_, err := NewLedgerWalletProvider(0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not match key address "+generatorAddress)
+
+	_, err = NewLedgerWalletProviderWithConfig(LedgerConfig{SkipAddressCheck: true})
+	require.NoError(t, err)
+}
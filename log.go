@@ -9,7 +9,55 @@
 package did
 
 import (
-	logging "github.com/ipfs/go-log/v2"
+	"sync/atomic"
 )
 
-var log = logging.Logger("did")
+// Logger receives the package's diagnostic messages.
+type Logger interface {
+	Errorf(format string, args ...any)
+	Debugf(format string, args ...any)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Errorf(string, ...any) {}
+func (nopLogger) Debugf(string, ...any) {}
+
+// loggerBox wraps the logger so atomic.Value always stores one concrete
+// type.
+type loggerBox struct {
+	Logger
+}
+
+// logger forwards to the logger installed with SetLogger.
+type logger struct {
+	current atomic.Value
+}
+
+var log = &logger{}
+
+// SetLogger routes the package's log messages to l; a nil l discards them,
+// which is the default. It is safe to call concurrently with logging.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = nopLogger{}
+	}
+
+	log.current.Store(loggerBox{l})
+}
+
+func (l *logger) get() Logger {
+	if box, ok := l.current.Load().(loggerBox); ok {
+		return box.Logger
+	}
+
+	return nopLogger{}
+}
+
+func (l *logger) Errorf(format string, args ...any) {
+	l.get().Errorf(format, args...)
+}
+
+func (l *logger) Debugf(format string, args ...any) {
+	l.get().Debugf(format, args...)
+}
@@ -0,0 +1,57 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingLogger struct {
+	mx     sync.Mutex
+	errors []string
+	debugs []string
+}
+
+func (l *recordingLogger) Errorf(format string, args ...any) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	l.errors = append(l.errors, fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Debugf(format string, args ...any) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	l.debugs = append(l.debugs, fmt.Sprintf(format, args...))
+}
+
+// setTestLogger installs a recording logger for the duration of the test.
+func setTestLogger(t *testing.T) *recordingLogger {
+	t.Helper()
+	l := &recordingLogger{}
+	SetLogger(l)
+	t.Cleanup(func() { SetLogger(nil) })
+	return l
+}
+
+func TestSetLogger(t *testing.T) {
+	// the default logger discards messages
+	require.NotPanics(t, func() { FormatKeyURI(bogusKey{}) })
+
+	l := setTestLogger(t)
+	require.Empty(t, FormatKeyURI(bogusKey{}))
+	require.Len(t, l.errors, 1)
+
+	SetLogger(nil)
+	FormatKeyURI(bogusKey{})
+	require.Len(t, l.errors, 1, "nil logger discards messages")
+}
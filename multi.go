@@ -0,0 +1,371 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/crypto/pb"
+	mb "github.com/multiformats/go-multibase"
+	varint "github.com/multiformats/go-varint"
+
+	"github.com/depinkit/crypto"
+)
+
+const multiPrefix = "did:multi"
+
+// MultiProvider is a threshold provider backed by a committee of
+// sub-providers. Its DID is a did:multi DID encoding the threshold and the
+// member DIDs, in sorted order.
+type MultiProvider struct {
+	did       DID
+	threshold int
+	providers []Provider
+}
+
+var _ Provider = (*MultiProvider)(nil)
+
+// MultiAnchor verifies threshold signatures produced by a MultiProvider.
+type MultiAnchor struct {
+	did       DID
+	threshold int
+	anchors   []Anchor
+}
+
+var _ Anchor = (*MultiAnchor)(nil)
+
+// NewMultiProvider creates a t-of-n provider from the given sub-providers.
+// Duplicate signers, including distinct DIDs for the same key, are rejected.
+func NewMultiProvider(threshold int, providers ...Provider) (*MultiProvider, error) {
+	sorted := make([]Provider, len(providers))
+	copy(sorted, providers)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].DID().URI < sorted[j].DID().URI
+	})
+
+	members := make([]DID, len(sorted))
+	anchors := make([]Anchor, len(sorted))
+	for i, p := range sorted {
+		members[i] = p.DID()
+		anchors[i] = p.Anchor()
+	}
+
+	did, err := formatMultiDID(threshold, members)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDistinctKeys(anchors); err != nil {
+		return nil, err
+	}
+
+	return &MultiProvider{
+		did:       did,
+		threshold: threshold,
+		providers: sorted,
+	}, nil
+}
+
+// NewMultiAnchor creates a t-of-n anchor from the given sub-anchors.
+// Duplicate signers, including distinct DIDs for the same key, are rejected.
+func NewMultiAnchor(threshold int, anchors ...Anchor) (*MultiAnchor, error) {
+	sorted := make([]Anchor, len(anchors))
+	copy(sorted, anchors)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].DID().URI < sorted[j].DID().URI
+	})
+
+	members := make([]DID, len(sorted))
+	for i, a := range sorted {
+		members[i] = a.DID()
+	}
+
+	did, err := formatMultiDID(threshold, members)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDistinctKeys(sorted); err != nil {
+		return nil, err
+	}
+
+	return &MultiAnchor{
+		did:       did,
+		threshold: threshold,
+		anchors:   sorted,
+	}, nil
+}
+
+func (p *MultiProvider) DID() DID {
+	return p.did
+}
+
+// Sign collects signatures from every sub-provider that can sign and returns
+// them in an envelope; it fails if fewer than the threshold succeed.
+func (p *MultiProvider) Sign(data []byte) ([]byte, error) {
+	var (
+		sigs []multiSig
+		errs []error
+	)
+	for i, sub := range p.providers {
+		sig, err := sub.Sign(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sub.DID(), err))
+			continue
+		}
+		sigs = append(sigs, multiSig{index: i, sig: sig})
+	}
+
+	if len(sigs) < p.threshold {
+		return nil, fmt.Errorf("got %d of %d required signatures: %w", len(sigs), p.threshold, errors.Join(errs...))
+	}
+
+	return encodeMultiSig(sigs), nil
+}
+
+func (p *MultiProvider) Anchor() Anchor {
+	anchors := make([]Anchor, len(p.providers))
+	for i, sub := range p.providers {
+		anchors[i] = sub.Anchor()
+	}
+
+	return &MultiAnchor{
+		did:       p.did,
+		threshold: p.threshold,
+		anchors:   anchors,
+	}
+}
+
+func (p *MultiProvider) PrivateKey() (crypto.PrivKey, error) {
+	return nil, fmt.Errorf("multi provider has no single private key: %w", ErrMultiKey)
+}
+
+func (a *MultiAnchor) DID() DID {
+	return a.did
+}
+
+// Verify accepts the envelope if at least threshold sub-signatures verify
+// against their corresponding sub-anchors. Sub-signatures must be in
+// strictly increasing signer order, which also rejects duplicate signers.
+func (a *MultiAnchor) Verify(data []byte, sig []byte) error {
+	sigs, err := decodeMultiSig(sig)
+	if err != nil {
+		return err
+	}
+
+	valid := 0
+	last := -1
+	for _, s := range sigs {
+		if s.index <= last || s.index >= len(a.anchors) {
+			return fmt.Errorf("invalid signer index %d: %w", s.index, ErrInvalidSignature)
+		}
+		last = s.index
+
+		if a.anchors[s.index].Verify(data, s.sig) == nil {
+			valid++
+		}
+	}
+
+	if valid < a.threshold {
+		return fmt.Errorf("%d of %d required signatures verified: %w", valid, a.threshold, ErrInvalidSignature)
+	}
+
+	return nil
+}
+
+// PublicKey returns nil; a multi anchor has no single public key.
+func (a *MultiAnchor) PublicKey() crypto.PubKey {
+	return nil
+}
+
+// KeyType returns KeyTypeNone; a multi anchor has no single key type.
+func (a *MultiAnchor) KeyType() pb.KeyType {
+	return KeyTypeNone
+}
+
+// Algorithm returns ""; sub-signatures use their members' algorithms.
+func (a *MultiAnchor) Algorithm() string {
+	return ""
+}
+
+// Threshold returns the number of sub-signatures required.
+func (a *MultiAnchor) Threshold() int {
+	return a.threshold
+}
+
+// Anchors returns the sub-anchors in signer order.
+func (a *MultiAnchor) Anchors() []Anchor {
+	result := make([]Anchor, len(a.anchors))
+	copy(result, a.anchors)
+	return result
+}
+
+// makeMultiAnchor resolves a did:multi DID by resolving each member DID.
+func makeMultiAnchor(did DID) (Anchor, error) {
+	threshold, members, err := parseMultiDID(did)
+	if err != nil {
+		return nil, err
+	}
+
+	anchors := make([]Anchor, len(members))
+	for i, member := range members {
+		anchors[i], err = GetAnchorForDID(member)
+		if err != nil {
+			return nil, fmt.Errorf("resolve multi member %s: %w", member, err)
+		}
+	}
+	if err := checkDistinctKeys(anchors); err != nil {
+		return nil, err
+	}
+
+	return &MultiAnchor{
+		did:       did,
+		threshold: threshold,
+		anchors:   anchors,
+	}, nil
+}
+
+// formatMultiDID encodes the threshold and the sorted member DIDs as
+// uvarint(threshold) || (uvarint(len) || uri)* in base58btc.
+func formatMultiDID(threshold int, members []DID) (DID, error) {
+	if len(members) == 0 || threshold < 1 || threshold > len(members) {
+		return DID{}, fmt.Errorf("invalid threshold %d of %d: %w", threshold, len(members), ErrInvalidDID)
+	}
+
+	data := varint.ToUvarint(uint64(threshold))
+	seen := make(map[DID]bool, len(members))
+	for _, member := range members {
+		if member.Empty() {
+			return DID{}, fmt.Errorf("empty multi member: %w", ErrInvalidDID)
+		}
+		normalized, err := member.Normalize()
+		if err != nil {
+			normalized = member.canonical()
+		}
+		if seen[normalized] {
+			return DID{}, fmt.Errorf("duplicate multi member %s: %w", member, ErrInvalidDID)
+		}
+		seen[normalized] = true
+
+		data = append(data, varint.ToUvarint(uint64(len(member.URI)))...)
+		data = append(data, member.URI...)
+	}
+
+	enc, err := mb.Encode(mb.Base58BTC, data)
+	if err != nil {
+		return DID{}, fmt.Errorf("encoding multi did: %w", err)
+	}
+
+	return DID{URI: multiPrefix + ":" + enc}, nil
+}
+
+func parseMultiDID(did DID) (int, []DID, error) {
+	if !strings.HasPrefix(did.URI, multiPrefix+":") {
+		return 0, nil, ErrInvalidDID
+	}
+
+	enc, data, err := mb.Decode(strings.TrimPrefix(did.URI, multiPrefix+":"))
+	if err != nil || enc != mb.Base58BTC {
+		return 0, nil, fmt.Errorf("decoding multi did: %w", ErrInvalidDID)
+	}
+
+	threshold, n, err := varint.FromUvarint(data)
+	if err != nil {
+		return 0, nil, fmt.Errorf("decoding multi threshold: %w", ErrInvalidDID)
+	}
+	data = data[n:]
+
+	var members []DID
+	for len(data) > 0 {
+		size, n, err := varint.FromUvarint(data)
+		if err != nil || uint64(len(data)-n) < size {
+			return 0, nil, fmt.Errorf("decoding multi member: %w", ErrInvalidDID)
+		}
+		members = append(members, DID{URI: string(data[n : n+int(size)])})
+		data = data[n+int(size):]
+	}
+
+	// re-encode to validate threshold, ordering and duplicates
+	canonical, err := formatMultiDID(int(threshold), members)
+	if err != nil {
+		return 0, nil, err
+	}
+	if !canonical.Equal(did) {
+		return 0, nil, fmt.Errorf("non-canonical multi did: %w", ErrInvalidDID)
+	}
+
+	return int(threshold), members, nil
+}
+
+// checkDistinctKeys rejects members whose anchors share a public key, so
+// that one key cannot count more than once towards the threshold.
+func checkDistinctKeys(anchors []Anchor) error {
+	for i, a := range anchors {
+		pubk := a.PublicKey()
+		if pubk == nil {
+			continue
+		}
+		for _, b := range anchors[:i] {
+			if publicKeysEqual(pubk, b.PublicKey()) {
+				return fmt.Errorf("multi members %s and %s share a key: %w", b.DID(), a.DID(), ErrInvalidDID)
+			}
+		}
+	}
+
+	return nil
+}
+
+type multiSig struct {
+	index int
+	sig   []byte
+}
+
+// encodeMultiSig encodes sub-signatures as
+// uvarint(count) || (uvarint(index) || uvarint(len) || sig)*.
+func encodeMultiSig(sigs []multiSig) []byte {
+	data := varint.ToUvarint(uint64(len(sigs)))
+	for _, s := range sigs {
+		data = append(data, varint.ToUvarint(uint64(s.index))...)
+		data = append(data, varint.ToUvarint(uint64(len(s.sig)))...)
+		data = append(data, s.sig...)
+	}
+
+	return data
+}
+
+func decodeMultiSig(data []byte) ([]multiSig, error) {
+	count, n, err := varint.FromUvarint(data)
+	if err != nil || count > uint64(len(data)) {
+		return nil, fmt.Errorf("decoding multi signature: %w", ErrInvalidSignature)
+	}
+	data = data[n:]
+
+	sigs := make([]multiSig, 0, count)
+	for i := uint64(0); i < count; i++ {
+		index, n, err := varint.FromUvarint(data)
+		if err != nil {
+			return nil, fmt.Errorf("decoding multi signature index: %w", ErrInvalidSignature)
+		}
+		data = data[n:]
+
+		size, n, err := varint.FromUvarint(data)
+		if err != nil || uint64(len(data)-n) < size {
+			return nil, fmt.Errorf("decoding multi signature: %w", ErrInvalidSignature)
+		}
+		sigs = append(sigs, multiSig{index: int(index), sig: data[n : n+int(size)]})
+		data = data[n+int(size):]
+	}
+
+	if len(data) > 0 {
+		return nil, fmt.Errorf("trailing multi signature bytes: %w", ErrInvalidSignature)
+	}
+
+	return sigs, nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+type failingProvider struct {
+	Provider
+}
+
+func (failingProvider) Sign([]byte) ([]byte, error) {
+	return nil, errors.New("offline")
+}
+
+func makeCommittee(t *testing.T, n int) []Provider {
+	t.Helper()
+
+	providers := make([]Provider, n)
+	for i := range providers {
+		privk, _, err := crypto.GenerateKeyPair(crypto.Ed25519)
+		require.NoError(t, err)
+		providers[i], err = ProviderFromPrivateKey(privk)
+		require.NoError(t, err)
+	}
+	return providers
+}
+
+func TestMultiProviderThreshold(t *testing.T) {
+	committee := makeCommittee(t, 3)
+
+	prov, err := NewMultiProvider(2, committee...)
+	require.NoError(t, err)
+	require.Equal(t, "multi", prov.DID().Method())
+
+	// member order does not affect the DID
+	reordered, err := NewMultiProvider(2, committee[2], committee[0], committee[1])
+	require.NoError(t, err)
+	require.Equal(t, prov.DID(), reordered.DID())
+
+	msg := []byte("committee decision")
+	sig, err := prov.Sign(msg)
+	require.NoError(t, err)
+
+	anchor := prov.Anchor()
+	require.NoError(t, anchor.Verify(msg, sig))
+	require.ErrorIs(t, anchor.Verify([]byte("other"), sig), ErrInvalidSignature)
+
+	// the DID alone resolves the committee
+	resolved, err := GetAnchorForDID(prov.DID())
+	require.NoError(t, err)
+	require.NoError(t, resolved.Verify(msg, sig))
+
+	// one member offline: still 2 of 3
+	degraded, err := NewMultiProvider(2, committee[0], committee[1], failingProvider{committee[2]})
+	require.NoError(t, err)
+	sig, err = degraded.Sign(msg)
+	require.NoError(t, err)
+	require.NoError(t, anchor.Verify(msg, sig))
+
+	// two members offline: below threshold
+	broken, err := NewMultiProvider(2, committee[0], failingProvider{committee[1]}, failingProvider{committee[2]})
+	require.NoError(t, err)
+	_, err = broken.Sign(msg)
+	require.Error(t, err)
+
+	_, err = prov.PrivateKey()
+	require.ErrorIs(t, err, ErrMultiKey)
+}
+
+func TestMultiAnchorRejectsDuplicateSigners(t *testing.T) {
+	committee := makeCommittee(t, 3)
+
+	_, err := NewMultiProvider(2, committee[0], committee[0], committee[1])
+	require.ErrorIs(t, err, ErrInvalidDID)
+
+	_, err = NewMultiProvider(4, committee...)
+	require.ErrorIs(t, err, ErrInvalidDID)
+
+	// equivalent spellings of a DID are the same member
+	k1, k2 := committee[0].Anchor().PublicKey(), committee[1].Anchor().PublicKey()
+	_, err = NewMultiAnchor(1, NewAnchor(DID{URI: "did:web:Example.com"}, k1), NewAnchor(DID{URI: "did:web:example.com"}, k2))
+	require.ErrorIs(t, err, ErrInvalidDID)
+
+	// distinct DIDs for the same key are the same signer
+	peerDID, err := FromPeerKey(k1)
+	require.NoError(t, err)
+	_, err = NewMultiAnchor(1, committee[0].Anchor(), NewAnchor(peerDID, k1))
+	require.ErrorIs(t, err, ErrInvalidDID)
+	privk, err := committee[0].PrivateKey()
+	require.NoError(t, err)
+	_, err = NewMultiProvider(1, committee[0], NewProvider(peerDID, privk))
+	require.ErrorIs(t, err, ErrInvalidDID)
+
+	prov, err := NewMultiProvider(2, committee...)
+	require.NoError(t, err)
+	anchor := prov.Anchor()
+
+	// the same sub-signature counted twice must not reach the threshold
+	msg := []byte("dup")
+	sub, err := prov.providers[0].Sign(msg)
+	require.NoError(t, err)
+	forged := encodeMultiSig([]multiSig{{index: 0, sig: sub}, {index: 0, sig: sub}})
+	require.ErrorIs(t, anchor.Verify(msg, forged), ErrInvalidSignature)
+
+	// out-of-order sub-signatures are rejected
+	sub1, err := prov.providers[1].Sign(msg)
+	require.NoError(t, err)
+	unordered := encodeMultiSig([]multiSig{{index: 1, sig: sub1}, {index: 0, sig: sub}})
+	require.ErrorIs(t, anchor.Verify(msg, unordered), ErrInvalidSignature)
+
+	require.ErrorIs(t, anchor.Verify(msg, []byte{0xff}), ErrInvalidSignature)
+}
+
+func TestParseMultiDIDInvalid(t *testing.T) {
+	for _, uri := range []string{"did:multi:notbase58!", "did:multi:z1", "did:key:z6Mk"} {
+		_, _, err := parseMultiDID(DID{URI: uri})
+		require.ErrorIs(t, err, ErrInvalidDID, uri)
+	}
+}
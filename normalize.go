@@ -0,0 +1,119 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"fmt"
+	"strings"
+
+	mb "github.com/multiformats/go-multibase"
+)
+
+// Normalize returns the normal form of the DID, so that semantically equal
+// DIDs compare equal:
+//   - the method name is lowercased;
+//   - percent-encoded octets use uppercase hex digits;
+//   - did:web domains are lowercased;
+//   - did:key identifiers are re-encoded as base58btc from the decoded key;
+//   - eip155 did:pkh addresses are lowercased.
+//
+// A DID URL path, query or fragment is preserved as is.
+func (did DID) Normalize() (DID, error) {
+	base, suffix := did.URI, ""
+	if end := strings.IndexAny(base, "/?#"); end >= 0 {
+		base, suffix = base[:end], base[end:]
+	}
+
+	rest, ok := strings.CutPrefix(base, "did:")
+	if !ok {
+		return DID{}, &DIDError{Op: "normalize", DID: did.URI, Err: fmt.Errorf("%w: missing did scheme", ErrInvalidDID)}
+	}
+
+	method, id, ok := strings.Cut(rest, ":")
+	if !ok {
+		return DID{}, &DIDError{Op: "normalize", DID: did.URI, Err: fmt.Errorf("%w: missing method-specific-id", ErrInvalidDID)}
+	}
+	method = strings.ToLower(method)
+
+	switch method {
+	case "web":
+		domain, path, _ := strings.Cut(id, ":")
+		id = strings.ToLower(domain)
+		if path != "" {
+			id += ":" + path
+		}
+
+	case "key":
+		// did:key identifiers may use any multibase; the normal form is
+		// base58btc
+		_, data, err := mb.Decode(id)
+		if err != nil {
+			return DID{}, &DIDError{Op: "normalize", DID: did.URI, Err: fmt.Errorf("%w: %w", ErrInvalidDID, err)}
+		}
+		base58, err := mb.Encode(mb.Base58BTC, data)
+		if err != nil {
+			return DID{}, &DIDError{Op: "normalize", DID: did.URI, Err: fmt.Errorf("%w: %w", ErrInvalidDID, err)}
+		}
+
+		pubk, err := ParseKeyURI(keyPrefix + ":" + base58)
+		if err != nil {
+			return DID{}, &DIDError{Op: "normalize", DID: did.URI, Err: fmt.Errorf("%w: %w", ErrInvalidDID, err)}
+		}
+		id = strings.TrimPrefix(FormatKeyURI(pubk), keyPrefix+":")
+	}
+
+	normalized := DID{URI: "did:" + method + ":" + normalizePctEncoding(id)}.canonical()
+	if err := validateDID(normalized.URI); err != nil {
+		return DID{}, &DIDError{Op: "normalize", DID: did.URI, Err: err}
+	}
+
+	normalized.URI += suffix
+	return normalized, nil
+}
+
+// EqualNormalized compares DIDs after normalization; DIDs that cannot be
+// normalized are compared as strings.
+func (did DID) EqualNormalized(other DID) bool {
+	a, err := did.Normalize()
+	if err != nil {
+		return did.Equal(other)
+	}
+
+	b, err := other.Normalize()
+	if err != nil {
+		return false
+	}
+
+	return a.Equal(b)
+}
+
+// normalizePctEncoding uppercases the hex digits of percent-encoded octets.
+func normalizePctEncoding(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	b := []byte(s)
+	for i := 0; i+2 < len(b); i++ {
+		if b[i] == '%' && isHexDigit(b[i+1]) && isHexDigit(b[i+2]) {
+			b[i+1] = upperHex(b[i+1])
+			b[i+2] = upperHex(b[i+2])
+			i += 2
+		}
+	}
+
+	return string(b)
+}
+
+func upperHex(c byte) byte {
+	if c >= 'a' && c <= 'f' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
@@ -0,0 +1,60 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"strings"
+	"testing"
+
+	mb "github.com/multiformats/go-multibase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+func TestDIDNormalize(t *testing.T) {
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	keyDID := FromPublicKey(pubk)
+
+	// the same did:key identifier in base32
+	_, data, err := mb.Decode(keyDID.Identifier())
+	require.NoError(t, err)
+	base32, err := mb.Encode(mb.Base32, data)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(base32, "b"))
+
+	cases := map[string]string{
+		"did:WEB:Example.COM":                 "did:web:example.com",
+		"did:web:Example.com%3a3000:Users:Al": "did:web:example.com%3A3000:Users:Al",
+		"did:example:a%2fb":                   "did:example:a%2Fb",
+		"did:web:Example.com#key-1":           "did:web:example.com#key-1",
+		"DID:key:x":                           "",
+		"did:pkh:eip155:1:" + "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed": "did:pkh:eip155:1:0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+		"did:KEY:" + keyDID.Identifier():                                   keyDID.URI,
+		"did:key:" + base32:                                                keyDID.URI,
+		"did:key:zgarbage":                                                 "",
+		"did:web":                                                          "",
+		"did:we b:x":                                                       "",
+	}
+
+	for in, want := range cases {
+		got, err := DID{URI: in}.Normalize()
+		if want == "" {
+			require.ErrorIs(t, err, ErrInvalidDID, in)
+			continue
+		}
+		require.NoError(t, err, in)
+		require.Equal(t, want, got.URI, in)
+	}
+
+	require.True(t, DID{URI: "did:web:EXAMPLE.com"}.EqualNormalized(DID{URI: "did:web:example.com"}))
+	require.False(t, DID{URI: "did:web:example.com"}.EqualNormalized(DID{URI: "did:web:example.org"}))
+	require.False(t, DID{URI: "did:web:Example.com:Path"}.EqualNormalized(DID{URI: "did:web:example.com:path"}))
+}
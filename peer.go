@@ -0,0 +1,63 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/depinkit/crypto"
+)
+
+const (
+	peerMethod = "peer"
+
+	// numalgo 0: the identifier is the inception key, encoded as in did:key
+	peerNumalgo0 = "0"
+
+	peerPrefix = "did:peer:"
+)
+
+// FromPeerKey returns the numalgo 0 peer DID (did:peer:0z...) for pubk.
+func FromPeerKey(pubk crypto.PubKey) (DID, error) {
+	uri := FormatKeyURI(pubk)
+	if uri == "" {
+		return DID{}, fmt.Errorf("key type %d cannot be used for a peer did: %w", pubk.Type(), ErrInvalidKeyType)
+	}
+
+	return DID{URI: peerPrefix + peerNumalgo0 + strings.TrimPrefix(uri, keyPrefix+":")}, nil
+}
+
+// PublicKeyFromPeerDID returns the inception key of a numalgo 0 peer DID.
+func PublicKeyFromPeerDID(did DID) (crypto.PubKey, error) {
+	if did.Method() != peerMethod {
+		return nil, ErrInvalidDID
+	}
+
+	id := did.Identifier()
+	if !strings.HasPrefix(id, peerNumalgo0) {
+		return nil, fmt.Errorf("unsupported peer did numalgo in %s: %w", did, ErrInvalidDID)
+	}
+
+	pubk, err := ParseKeyURI(keyPrefix + ":" + strings.TrimPrefix(id, peerNumalgo0))
+	if err != nil {
+		return nil, fmt.Errorf("parsing peer did inception key: %w", err)
+	}
+
+	return pubk, nil
+}
+
+func makePeerAnchor(did DID) (Anchor, error) {
+	pubk, err := PublicKeyFromPeerDID(did)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAnchor(did, pubk), nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+func TestPeerDID(t *testing.T) {
+	privk, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+
+	did, err := FromPeerKey(pubk)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(did.URI, "did:peer:0z6Mk"), did.URI)
+	require.Equal(t, "peer", did.Method())
+	require.Equal(t, strings.TrimPrefix(FromPublicKey(pubk).URI, "did:key:"), strings.TrimPrefix(did.URI, "did:peer:0"))
+
+	parsed, err := FromString(did.URI)
+	require.NoError(t, err)
+	require.Equal(t, did, parsed)
+
+	anchor, err := GetAnchorForDID(did)
+	require.NoError(t, err)
+	require.Equal(t, did, anchor.DID())
+	require.True(t, pubk.Equals(anchor.PublicKey()))
+
+	provider := NewProvider(did, privk)
+	sig, err := provider.Sign([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, anchor.Verify([]byte("hello"), sig))
+}
+
+func TestPeerDIDInvalid(t *testing.T) {
+	for _, uri := range []string{
+		"did:peer:2.Ez6LSbysY2xFMRpGMhb7tFTLMpeuPRaqaWM1yECx2AtzE3KCc",
+		"did:peer:0zgarbage",
+		"did:peer:0",
+	} {
+		_, err := GetAnchorForDID(DID{URI: uri})
+		require.Error(t, err, uri)
+	}
+
+	_, err := PublicKeyFromPeerDID(DID{URI: "did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK"})
+	require.ErrorIs(t, err, ErrInvalidDID)
+
+	_, err = FromPeerKey(bogusKey{})
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+}
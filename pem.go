@@ -0,0 +1,256 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+
+	"github.com/depinkit/crypto"
+)
+
+const (
+	pemTypePKCS8          = "PRIVATE KEY"
+	pemTypeSEC1           = "EC PRIVATE KEY"
+	pemTypeEncryptedPKCS8 = "ENCRYPTED PRIVATE KEY"
+)
+
+var (
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidCurveSecp256k1 = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+)
+
+// pkcs8 is a PKCS#8 PrivateKeyInfo (RFC 5208).
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+	Attributes asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+// ecPrivateKey is a SEC1 ECPrivateKey (RFC 5915).
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+// ProviderFromPEM creates a did:key provider from a PEM-encoded private
+// key: a PKCS#8 "PRIVATE KEY" holding an Ed25519, secp256k1 or P-256 key,
+// or a SEC1 "EC PRIVATE KEY" holding a secp256k1 or P-256 key. Encrypted
+// keys are rejected with ErrEncryptedKey and must be decrypted first.
+func ProviderFromPEM(pemBytes []byte) (Provider, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found: %w", ErrInvalidKeyType)
+	}
+	defer zero(block.Bytes)
+
+	// PKCS#8 encryption, or legacy OpenSSL "Proc-Type: 4,ENCRYPTED" headers
+	if block.Type == pemTypeEncryptedPKCS8 || strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED") {
+		return nil, fmt.Errorf("%w: decrypt the PEM file first (e.g. openssl pkcs8 -in key.pem -out plain.pem)", ErrEncryptedKey)
+	}
+
+	var (
+		privk crypto.PrivKey
+		err   error
+	)
+	switch block.Type {
+	case pemTypePKCS8:
+		privk, err = parsePKCS8PrivateKey(block.Bytes)
+	case pemTypeSEC1:
+		privk, err = parseSEC1PrivateKey(block.Bytes, nil)
+	default:
+		return nil, fmt.Errorf("unsupported PEM type %q: %w", block.Type, ErrInvalidKeyType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ProviderFromPrivateKey(privk)
+}
+
+// parsePKCS8PrivateKey parses a PKCS#8 key. secp256k1 keys are decoded
+// here, as crypto/x509 only supports the NIST curves.
+func parsePKCS8PrivateKey(der []byte) (crypto.PrivKey, error) {
+	var info pkcs8
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("parse pkcs8 key: %w: %w", ErrInvalidKeyType, err)
+	}
+	defer zero(info.PrivateKey)
+
+	if info.Algo.Algorithm.Equal(oidPublicKeyECDSA) {
+		var curve asn1.ObjectIdentifier
+		if _, err := asn1.Unmarshal(info.Algo.Parameters.FullBytes, &curve); err != nil {
+			return nil, fmt.Errorf("parse pkcs8 curve: %w: %w", ErrInvalidKeyType, err)
+		}
+		if curve.Equal(oidCurveSecp256k1) {
+			return parseSEC1PrivateKey(info.PrivateKey, curve)
+		}
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse pkcs8 key: %w: %w", ErrInvalidKeyType, err)
+	}
+
+	return stdPrivateKey(key)
+}
+
+// parseSEC1PrivateKey parses a SEC1 key; curve is the curve named by an
+// enclosing PKCS#8 structure, if any.
+func parseSEC1PrivateKey(der []byte, curve asn1.ObjectIdentifier) (crypto.PrivKey, error) {
+	var key ecPrivateKey
+	if _, err := asn1.Unmarshal(der, &key); err != nil {
+		return nil, fmt.Errorf("parse ec key: %w: %w", ErrInvalidKeyType, err)
+	}
+	defer zero(key.PrivateKey)
+
+	if len(key.NamedCurveOID) > 0 {
+		curve = key.NamedCurveOID
+	}
+
+	if !curve.Equal(oidCurveSecp256k1) {
+		std, err := x509.ParseECPrivateKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("parse ec key: %w: %w", ErrInvalidKeyType, err)
+		}
+		return stdPrivateKey(std)
+	}
+
+	if key.Version != 1 || len(key.PrivateKey) > 32 {
+		return nil, fmt.Errorf("invalid secp256k1 private key: %w", ErrInvalidKeyType)
+	}
+
+	raw := make([]byte, 32)
+	defer zero(raw)
+	copy(raw[32-len(key.PrivateKey):], key.PrivateKey)
+
+	return libp2p_crypto.UnmarshalSecp256k1PrivateKey(raw)
+}
+
+// stdPrivateKey converts a crypto/x509 private key.
+func stdPrivateKey(key any) (crypto.PrivKey, error) {
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		return libp2p_crypto.UnmarshalEd25519PrivateKey(k)
+
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("unsupported curve %s: %w", k.Curve.Params().Name, ErrInvalidKeyType)
+		}
+		privk, _, err := libp2p_crypto.ECDSAKeyPairFromKey(k)
+		return privk, err
+
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T: %w", key, ErrInvalidKeyType)
+	}
+}
+
+// ExportPEM encodes the provider's private key as an unencrypted PKCS#8
+// "PRIVATE KEY" PEM block, which ProviderFromPEM and common tooling such as
+// openssl read back. The returned PEM holds the raw key material and must
+// be protected accordingly; intermediate buffers are zeroed.
+func (p *PrivateKeyProvider) ExportPEM() ([]byte, error) {
+	der, err := marshalPKCS8PrivateKey(p.privk)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(der)
+
+	return pem.EncodeToMemory(&pem.Block{Type: pemTypePKCS8, Bytes: der}), nil
+}
+
+// ExportPEM fails with ErrHardwareKey: ledger keys never leave the device.
+func (p *LedgerWalletProvider) ExportPEM() ([]byte, error) {
+	return nil, fmt.Errorf("ledger private key cannot be exported: %w", ErrHardwareKey)
+}
+
+// ExportPEM fails with ErrHardwareKey: the key is held by the remote
+// signer.
+func (p *RemoteSignerProvider) ExportPEM() ([]byte, error) {
+	return nil, fmt.Errorf("remote private key cannot be exported: %w", ErrHardwareKey)
+}
+
+// marshalPKCS8PrivateKey encodes privk as PKCS#8. secp256k1 keys are
+// encoded here, as crypto/x509 only supports the NIST curves.
+func marshalPKCS8PrivateKey(privk crypto.PrivKey) ([]byte, error) {
+	if privk.Type() == crypto.Secp256k1 {
+		return marshalSecp256k1PKCS8(privk)
+	}
+
+	std, err := libp2p_crypto.PrivKeyToStdKey(privk)
+	if err != nil {
+		return nil, fmt.Errorf("private key: %w: %w", ErrInvalidKeyType, err)
+	}
+
+	// libp2p returns a pointer to its Ed25519 key; x509 wants the value
+	if k, ok := std.(*ed25519.PrivateKey); ok {
+		std = *k
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(std)
+	if err != nil {
+		return nil, fmt.Errorf("marshal pkcs8 key: %w: %w", ErrInvalidKeyType, err)
+	}
+
+	return der, nil
+}
+
+// marshalSecp256k1PKCS8 encodes a secp256k1 key as PKCS#8 wrapping a SEC1
+// key with its public point, the layout openssl produces.
+func marshalSecp256k1PKCS8(privk crypto.PrivKey) ([]byte, error) {
+	raw, err := privk.Raw()
+	if err != nil {
+		return nil, fmt.Errorf("raw private key: %w", err)
+	}
+	defer zero(raw)
+
+	pub := secp256k1.PrivKeyFromBytes(raw).PubKey().SerializeUncompressed()
+	inner, err := asn1.Marshal(ecPrivateKey{
+		Version:    1,
+		PrivateKey: raw,
+		PublicKey:  asn1.BitString{Bytes: pub, BitLength: 8 * len(pub)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal ec key: %w", err)
+	}
+	defer zero(inner)
+
+	curve, err := asn1.Marshal(oidCurveSecp256k1)
+	if err != nil {
+		return nil, fmt.Errorf("marshal curve: %w", err)
+	}
+
+	return asn1.Marshal(pkcs8{
+		Algo: pkix.AlgorithmIdentifier{
+			Algorithm:  oidPublicKeyECDSA,
+			Parameters: asn1.RawValue{FullBytes: curve},
+		},
+		PrivateKey: inner,
+	})
+}
+
+// zero overwrites key material that is no longer needed.
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
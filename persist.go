@@ -0,0 +1,245 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/depinkit/crypto"
+)
+
+type trustContextSnapshot struct {
+	Providers []providerSnapshot `json:"providers"`
+	Anchors   []anchorSnapshot   `json:"anchors"`
+}
+
+// Snapshot type tags; the empty tag is a plain key anchor or provider.
+const (
+	snapshotPKH          = "pkh"
+	snapshotPersonalSign = "personal_sign"
+	snapshotRevoked      = "revoked"
+)
+
+// providerSnapshot holds either the marshaled private key or, for ledger
+// providers, the public key and CLI configuration needed to re-attach.
+type providerSnapshot struct {
+	DID        string        `json:"did"`
+	Type       string        `json:"type,omitempty"`
+	PrivateKey []byte        `json:"privateKey,omitempty"`
+	PublicKey  string        `json:"publicKey,omitempty"`
+	Ledger     *LedgerConfig `json:"ledger,omitempty"`
+	Expire     time.Time     `json:"expire,omitempty"`
+}
+
+// anchorSnapshot holds the anchor's public key as a did:key URI, or the
+// reason for revoked anchors.
+type anchorSnapshot struct {
+	DID       string `json:"did"`
+	Type      string `json:"type,omitempty"`
+	PublicKey string `json:"publicKey,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Export serializes the providers and anchors of the trust context.
+// Only types that can be rebuilt exactly are persisted: key, did:pkh and
+// ledger providers, and key, personal_sign and revoked anchors. Other
+// providers are skipped and must be added again after ImportTrustContext;
+// other anchors are cache only and resolved again from their DID.
+func (ctx *BasicTrustContext) Export() ([]byte, error) {
+	ctx.mx.Lock()
+	defer ctx.mx.Unlock()
+
+	var snapshot trustContextSnapshot
+	now, wallNow := monotonicNow(), time.Now()
+	for _, entry := range ctx.providers {
+		if entry.expired(now) {
+			continue
+		}
+
+		ps, ok, err := snapshotProvider(entry.provider)
+		if err != nil {
+			return nil, fmt.Errorf("export provider %s: %w", entry.provider.DID(), err)
+		}
+		if !ok {
+			log.Debugf("skipping provider %s: %T is not exportable", entry.provider.DID(), entry.provider)
+			continue
+		}
+		// deadlines are process-local; persist the wall-clock time
+		if entry.expire != 0 {
+			ps.Expire = wallNow.Add(time.Duration(entry.expire - now))
+		}
+		snapshot.Providers = append(snapshot.Providers, ps)
+	}
+
+	for _, entry := range ctx.anchors {
+		as, ok := snapshotAnchor(entry.anchor)
+		if !ok {
+			continue
+		}
+		snapshot.Anchors = append(snapshot.Anchors, as)
+	}
+
+	return json.Marshal(snapshot)
+}
+
+// snapshotProvider reports false for provider types Export does not
+// persist.
+func snapshotProvider(p Provider) (providerSnapshot, bool, error) {
+	ps := providerSnapshot{DID: p.DID().URI}
+
+	var privk crypto.PrivKey
+	switch p := p.(type) {
+	case *PrivateKeyProvider:
+		privk = p.privk
+	case *PKHProvider:
+		ps.Type = snapshotPKH
+		privk = p.privk
+	case *LedgerWalletProvider:
+		ps.PublicKey = FormatKeyURI(p.pubk)
+		cfg := p.cfg
+		ps.Ledger = &cfg
+		return ps, true, nil
+	default:
+		return ps, false, nil
+	}
+
+	data, err := crypto.PrivateKeyToBytes(privk)
+	if err != nil {
+		return ps, false, fmt.Errorf("marshal private key: %w", err)
+	}
+	ps.PrivateKey = data
+	return ps, true, nil
+}
+
+// snapshotAnchor reports false for anchor types Export does not persist.
+func snapshotAnchor(a Anchor) (anchorSnapshot, bool) {
+	as := anchorSnapshot{DID: a.DID().URI}
+
+	switch a := a.(type) {
+	case *RevokedAnchor:
+		as.Type = snapshotRevoked
+		as.Reason = a.reason
+		return as, true
+	case *PublicKeyAnchor:
+	case *EthPersonalSignAnchor:
+		as.Type = snapshotPersonalSign
+	default:
+		return as, false
+	}
+
+	as.PublicKey = FormatKeyURI(a.PublicKey())
+	return as, as.PublicKey != ""
+}
+
+// ImportTrustContext restores a trust context serialized with Export.
+// Anchor TTLs are reset; ledger providers are re-attached without invoking
+// the CLI, which is only run again when signing.
+func ImportTrustContext(data []byte) (TrustContext, error) {
+	var snapshot trustContextSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("parse trust context: %w", err)
+	}
+
+	ctx := NewTrustContext().(*BasicTrustContext)
+	now, wallNow := monotonicNow(), time.Now()
+	for _, ps := range snapshot.Providers {
+		if !ps.Expire.IsZero() && ps.Expire.Before(wallNow) {
+			continue
+		}
+
+		p, err := restoreProvider(ps)
+		if err != nil {
+			return nil, fmt.Errorf("import provider %s: %w", ps.DID, err)
+		}
+		entry := &providerEntry{provider: p}
+		if !ps.Expire.IsZero() {
+			entry.expire = now.add(ps.Expire.Sub(wallNow))
+		}
+		ctx.providers[p.DID().canonical()] = entry
+	}
+
+	for _, as := range snapshot.Anchors {
+		did, err := FromString(as.DID)
+		if err != nil {
+			return nil, fmt.Errorf("import anchor: %w", err)
+		}
+
+		anchor, err := restoreAnchor(did, as)
+		if err != nil {
+			return nil, fmt.Errorf("import anchor %s: %w", as.DID, err)
+		}
+		ctx.AddAnchor(anchor)
+	}
+
+	return ctx, nil
+}
+
+func restoreAnchor(did DID, as anchorSnapshot) (Anchor, error) {
+	if as.Type == snapshotRevoked {
+		return NewRevokedAnchor(did, as.Reason), nil
+	}
+
+	pubk, err := ParseKeyURI(as.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	switch as.Type {
+	case "":
+		return NewAnchor(did, pubk), nil
+	case snapshotPersonalSign:
+		return NewEthPersonalSignAnchor(did, pubk)
+	default:
+		return nil, fmt.Errorf("unknown anchor type %q", as.Type)
+	}
+}
+
+func restoreProvider(ps providerSnapshot) (Provider, error) {
+	did, err := FromString(ps.DID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case ps.PrivateKey != nil:
+		privk, err := crypto.BytesToPrivateKey(ps.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal private key: %w", err)
+		}
+
+		switch ps.Type {
+		case "":
+			return NewProvider(did, privk), nil
+		case snapshotPKH:
+			address, err := didEthAddress(did)
+			if err != nil {
+				return nil, err
+			}
+			return &PKHProvider{did: did, address: address, privk: privk}, nil
+		default:
+			return nil, fmt.Errorf("unknown provider type %q", ps.Type)
+		}
+
+	case ps.Ledger != nil:
+		pubk, err := ParseKeyURI(ps.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("parse ledger key: %w", err)
+		}
+		return &LedgerWalletProvider{
+			did:  did,
+			pubk: pubk,
+			cfg:  *ps.Ledger,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("no key material: %w", ErrNoProvider)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+func TestTrustContextExportImport(t *testing.T) {
+	ctx := NewTrustContext().(*BasicTrustContext)
+
+	prov, err := ProviderFromPrivateKey(mustPrivKey(t))
+	require.NoError(t, err)
+	ctx.AddProvider(prov)
+
+	ephemeral, err := ProviderFromPrivateKey(mustPrivKey(t))
+	require.NoError(t, err)
+	ctx.AddProviderWithTTL(ephemeral, time.Hour)
+
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Secp256k1)
+	require.NoError(t, err)
+	anchor, err := AnchorWithDID(DID{URI: "did:web:example.com"}, pubk)
+	require.NoError(t, err)
+	ctx.AddAnchor(anchor)
+
+	data, err := ctx.Export()
+	require.NoError(t, err)
+
+	restored, err := ImportTrustContext(data)
+	require.NoError(t, err)
+	require.ElementsMatch(t, ctx.Providers(), restored.Providers())
+	require.ElementsMatch(t, ctx.Anchors(), restored.Anchors())
+
+	// restored providers still sign for their DID
+	rp, err := restored.GetProvider(prov.DID())
+	require.NoError(t, err)
+	msg := []byte("after restart")
+	sig, err := rp.Sign(msg)
+	require.NoError(t, err)
+	require.NoError(t, prov.Anchor().Verify(msg, sig))
+
+	ra, err := restored.GetAnchor(anchor.DID())
+	require.NoError(t, err)
+	require.True(t, pubk.Equals(ra.PublicKey()))
+
+	// provider TTLs survive the round trip
+	btc := restored.(*BasicTrustContext)
+	require.NotZero(t, btc.providers[ephemeral.DID()].expire)
+	require.Zero(t, btc.providers[prov.DID()].expire)
+}
+
+func TestTrustContextExportLedgerStub(t *testing.T) {
+	sk, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	restore := fakeLedgerSignerCLI(t, sk)
+	defer restore()
+
+	ledger, err := NewLedgerWalletProviderWithConfig(LedgerConfig{Account: 3})
+	require.NoError(t, err)
+
+	ctx := NewTrustContextWithProvider(ledger).(*BasicTrustContext)
+	data, err := ctx.Export()
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "privateKey")
+
+	restored, err := ImportTrustContext(data)
+	require.NoError(t, err)
+
+	rp, err := restored.GetProvider(ledger.DID())
+	require.NoError(t, err)
+	require.IsType(t, &LedgerWalletProvider{}, rp)
+	require.Equal(t, 3, rp.(*LedgerWalletProvider).cfg.Account)
+
+	msg := []byte("re-attached")
+	sig, err := rp.Sign(msg)
+	require.NoError(t, err)
+	require.NoError(t, ledger.Anchor().Verify(msg, sig))
+}
+
+func TestTrustContextExportTypes(t *testing.T) {
+	privk, _, err := crypto.GenerateKeyPair(crypto.Secp256k1)
+	require.NoError(t, err)
+
+	pkh, err := ProviderFromPrivateKeyWithMethod(privk, pkhMethod)
+	require.NoError(t, err)
+	ctx := NewTrustContextWithProvider(pkh).(*BasicTrustContext)
+
+	wallet, err := NewEthPersonalSignAnchor(DID{URI: "did:web:wallet.example.com"}, privk.GetPublic())
+	require.NoError(t, err)
+	ctx.AddAnchor(wallet)
+
+	// cache-only anchors are resolved again from their DID
+	pkhAnchor, err := NewPKHAnchor(pkh.DID())
+	require.NoError(t, err)
+	ctx.AddAnchor(pkhAnchor)
+
+	data, err := ctx.Export()
+	require.NoError(t, err)
+
+	restored, err := ImportTrustContext(data)
+	require.NoError(t, err)
+
+	rp, err := restored.GetProvider(pkh.DID())
+	require.NoError(t, err)
+	require.IsType(t, &PKHProvider{}, rp)
+
+	msg := []byte("wallet")
+	sig, err := rp.Sign(msg)
+	require.NoError(t, err)
+	require.NoError(t, pkhAnchor.Verify(msg, sig))
+
+	ra, err := restored.GetAnchor(wallet.DID())
+	require.NoError(t, err)
+	require.IsType(t, &EthPersonalSignAnchor{}, ra)
+	require.NoError(t, ra.Verify(msg, sig))
+
+	require.Len(t, restored.Anchors(), 1)
+}
+
+func TestTrustContextExportUnexportableProvider(t *testing.T) {
+	multi, err := NewMultiProvider(1, makeCommittee(t, 2)...)
+	require.NoError(t, err)
+
+	prov, err := ProviderFromPrivateKey(mustPrivKey(t))
+	require.NoError(t, err)
+
+	ctx := NewTrustContextWithProvider(multi).(*BasicTrustContext)
+	ctx.AddProvider(prov)
+	data, err := ctx.Export()
+	require.NoError(t, err)
+
+	restored, err := ImportTrustContext(data)
+	require.NoError(t, err)
+	_, err = restored.GetProvider(multi.DID())
+	require.ErrorIs(t, err, ErrNoProvider)
+	_, err = restored.GetProvider(prov.DID())
+	require.NoError(t, err)
+
+	_, err = ImportTrustContext([]byte("not-json"))
+	require.Error(t, err)
+}
+
+func TestTrustContextExportRevoked(t *testing.T) {
+	ctx := NewTrustContext().(*BasicTrustContext)
+
+	revoked := DID{URI: "did:web:revoked.example.com"}
+	ctx.AddAnchor(NewRevokedAnchor(revoked, "key compromised"))
+
+	data, err := ctx.Export()
+	require.NoError(t, err)
+
+	restored, err := ImportTrustContext(data)
+	require.NoError(t, err)
+
+	ra, err := restored.GetAnchor(revoked)
+	require.NoError(t, err)
+	require.IsType(t, &RevokedAnchor{}, ra)
+	require.Equal(t, "key compromised", ra.(*RevokedAnchor).Reason())
+	require.ErrorIs(t, ra.Verify([]byte("msg"), []byte("sig")), ErrRevoked)
+
+	// revoked entries stay permanent after import
+	require.True(t, restored.(*BasicTrustContext).anchors[revoked.canonical()].permanent)
+}
@@ -0,0 +1,231 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpECDSA "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+
+	"github.com/depinkit/crypto"
+)
+
+const (
+	pkhMethod          = "pkh"
+	pkhNamespaceEIP155 = "eip155"
+)
+
+// pkhParts splits a did:pkh URI into its CAIP-10 account components:
+// did:pkh:<namespace>:<reference>:<address>.
+func pkhParts(did DID) (namespace, reference, address string, ok bool) {
+	// canonical calls this on every trust context lookup, so scan without
+	// allocating
+	rest, ok := strings.CutPrefix(did.URI, "did:"+pkhMethod+":")
+	if !ok {
+		return "", "", "", false
+	}
+
+	namespace, rest, ok = strings.Cut(rest, ":")
+	if !ok {
+		return "", "", "", false
+	}
+	reference, address, ok = strings.Cut(rest, ":")
+	if !ok || strings.Contains(address, ":") {
+		return "", "", "", false
+	}
+
+	return namespace, reference, address, true
+}
+
+// canonical returns the canonical form of the DID used for comparison and
+// as a map key. Ethereum (eip155) did:pkh addresses are case-insensitive
+// (EIP-55 checksums are only mixed-case encoding) and are lowercased, as
+// are Cosmos bech32 addresses; all other DIDs are returned unchanged.
+func (did DID) canonical() DID {
+	namespace, reference, address, ok := pkhParts(did)
+	if !ok || (namespace != pkhNamespaceEIP155 && namespace != pkhNamespaceCosmos) {
+		return did
+	}
+
+	lower := strings.ToLower(address)
+	if lower == address {
+		return did
+	}
+
+	return DID{URI: strings.Join([]string{"did", pkhMethod, namespace, reference, lower}, ":")}
+}
+
+// CanonicalEqual compares DIDs after canonicalization, so that checksummed
+// and lowercased did:pkh Ethereum addresses compare equal.
+func (did DID) CanonicalEqual(other DID) bool {
+	return did.canonical().Equal(other.canonical())
+}
+
+// ProviderFromPrivateKeyWithMethod creates a provider for privk whose DID
+// uses the given method: "key", "peer" (numalgo 0) or "pkh" (an Ethereum
+// mainnet did:pkh account, for secp256k1 keys, signing with personal_sign;
+// see PKHProvider). Methods that cannot encode the key are rejected.
+func ProviderFromPrivateKeyWithMethod(privk crypto.PrivKey, method string) (Provider, error) {
+	pubk := privk.GetPublic()
+
+	var did DID
+	switch method {
+	case "key":
+		did = FromPublicKey(pubk)
+		if did.Empty() {
+			return nil, fmt.Errorf("key type %d cannot be used for did:key: %w", pubk.Type(), ErrInvalidKeyType)
+		}
+
+	case peerMethod:
+		var err error
+		did, err = FromPeerKey(pubk)
+		if err != nil {
+			return nil, err
+		}
+
+	case pkhMethod:
+		addr, err := pubKeyEthAddress(pubk)
+		if err != nil {
+			return nil, err
+		}
+		return &PKHProvider{did: ethAddressDID(addr, 1), address: addr, privk: privk}, nil
+
+	default:
+		return nil, fmt.Errorf("method %q: %w", method, ErrNoAnchorMethod)
+	}
+
+	return NewProvider(did, privk), nil
+}
+
+// PKHProvider signs for an Ethereum did:pkh account as a wallet does, with
+// personal_sign (EIP-191) R || S || V signatures, so that its signatures
+// verify with the PKHAnchor the DID resolves to.
+type PKHProvider struct {
+	did     DID
+	address []byte
+	privk   crypto.PrivKey
+}
+
+var (
+	_ Provider      = (*PKHProvider)(nil)
+	_ ContextSigner = (*PKHProvider)(nil)
+)
+
+func (p *PKHProvider) DID() DID {
+	return p.did
+}
+
+func (p *PKHProvider) Sign(data []byte) ([]byte, error) {
+	raw, err := p.privk.Raw()
+	if err != nil {
+		return nil, fmt.Errorf("raw private key: %w", err)
+	}
+
+	// SignCompact returns V || R || S with V = 27/28
+	compact := secpECDSA.SignCompact(secp256k1.PrivKeyFromBytes(raw), ethPersonalHash(data), false)
+	return append(compact[1:], compact[0]), nil
+}
+
+func (p *PKHProvider) SignContext(_ context.Context, data []byte) ([]byte, error) {
+	return p.Sign(data)
+}
+
+func (p *PKHProvider) PrivateKey() (crypto.PrivKey, error) {
+	return p.privk, nil
+}
+
+func (p *PKHProvider) Anchor() Anchor {
+	return &PKHAnchor{did: p.did, address: p.address, pubk: p.privk.GetPublic()}
+}
+
+// FromEthAddressString returns the Ethereum mainnet did:pkh DID for a
+// 0x-prefixed address; see FromEthAddressStringWithChainID.
+func FromEthAddressString(addr string) (DID, error) {
+	return FromEthAddressStringWithChainID(addr, 1)
+}
+
+// FromEthAddressStringWithChainID returns the did:pkh:eip155:<chainID> DID
+// for a 0x-prefixed address. Mixed-case addresses must carry a valid EIP-55
+// checksum; all-lowercase and all-uppercase addresses are accepted as
+// unchecksummed. The DID uses the checksummed form.
+func FromEthAddressStringWithChainID(addr string, chainID uint64) (DID, error) {
+	hexAddr, ok := strings.CutPrefix(addr, "0x")
+	if !ok {
+		return DID{}, fmt.Errorf("%w: address %q is not 0x-prefixed", ErrInvalidDID, addr)
+	}
+
+	raw, err := hex.DecodeString(hexAddr)
+	if err != nil || len(raw) != 20 {
+		return DID{}, fmt.Errorf("%w: invalid ethereum address %q", ErrInvalidDID, addr)
+	}
+
+	mixed := hexAddr != strings.ToLower(hexAddr) && hexAddr != strings.ToUpper(hexAddr)
+	if mixed && ethChecksumAddress(raw) != addr {
+		return DID{}, fmt.Errorf("%w: invalid EIP-55 checksum in %q", ErrInvalidDID, addr)
+	}
+
+	return ethAddressDID(raw, chainID), nil
+}
+
+// ToPKH returns the did:pkh:eip155:<chainID> account DID of the key of a
+// secp256k1 or Eth did:key DID. The conversion is one way: an address does
+// not determine its key, so did:pkh DIDs are rejected.
+func ToPKH(did DID, chainID int) (DID, error) {
+	if did.Method() == pkhMethod {
+		return DID{}, fmt.Errorf("%w: %s is already a did:pkh; an address cannot be converted to a key", ErrInvalidDID, did)
+	}
+	if did.Method() != "key" {
+		return DID{}, fmt.Errorf("%w: %s is not a did:key", ErrInvalidDID, did)
+	}
+	if chainID <= 0 {
+		return DID{}, fmt.Errorf("%w: invalid chain id %d", ErrInvalidDID, chainID)
+	}
+
+	pubk, err := PublicKeyFromDID(did)
+	if err != nil {
+		return DID{}, err
+	}
+
+	addr, err := pubKeyEthAddress(pubk)
+	if err != nil {
+		return DID{}, err
+	}
+
+	return ethAddressDID(addr, uint64(chainID)), nil
+}
+
+func ethAddressDID(addr []byte, chainID uint64) DID {
+	return DID{URI: strings.Join([]string{"did", pkhMethod, pkhNamespaceEIP155, strconv.FormatUint(chainID, 10), ethChecksumAddress(addr)}, ":")}
+}
+
+// ethChecksumAddress returns the EIP-55 mixed-case checksummed form of a
+// 20-byte address: a hex letter is uppercased when the corresponding nibble
+// of keccak256(lowercase hex address) is 8 or more.
+func ethChecksumAddress(addr []byte) string {
+	lower := hex.EncodeToString(addr)
+	hash := keccak256([]byte(lower))
+
+	out := []byte(lower)
+	for i, c := range out {
+		nibble := hash[i/2] >> 4
+		if i%2 == 1 {
+			nibble = hash[i/2] & 0x0f
+		}
+		if c >= 'a' && nibble >= 8 {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+
+	return "0x" + string(out)
+}
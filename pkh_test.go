@@ -0,0 +1,175 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+const (
+	pkhChecksummed = "did:pkh:eip155:1:0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	pkhLowercase   = "did:pkh:eip155:1:0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+)
+
+func TestPKHCanonicalEqual(t *testing.T) {
+	a := DID{URI: pkhChecksummed}
+	b := DID{URI: pkhLowercase}
+
+	require.False(t, a.Equal(b))
+	require.True(t, a.CanonicalEqual(b))
+	require.True(t, b.CanonicalEqual(a))
+	require.Equal(t, pkhLowercase, a.canonical().URI)
+
+	// different chain is a different identity
+	require.False(t, a.CanonicalEqual(DID{URI: "did:pkh:eip155:137:0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"}))
+
+	// non-eip155 namespaces and other methods are left untouched
+	other := DID{URI: "did:pkh:solana:4sGjMW1sUnHzSxGspuhpqLDx6wiyjNtZ:AbCd"}
+	require.Equal(t, other, other.canonical())
+	key := DID{URI: "did:key:zAbC"}
+	require.Equal(t, key, key.canonical())
+}
+
+func TestTrustContextPKHCanonicalKeys(t *testing.T) {
+	ctx := NewTrustContext()
+
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Secp256k1)
+	require.NoError(t, err)
+
+	ctx.AddAnchor(NewAnchor(DID{URI: pkhChecksummed}, pubk))
+	ctx.AddAnchor(NewAnchor(DID{URI: pkhLowercase}, pubk))
+	require.Len(t, ctx.Anchors(), 1, "same wallet must not produce duplicate entries")
+
+	anchor, err := ctx.GetAnchor(DID{URI: pkhLowercase})
+	require.NoError(t, err)
+	require.Equal(t, pubk, anchor.PublicKey())
+
+	privk, _, err := crypto.GenerateKeyPair(crypto.Secp256k1)
+	require.NoError(t, err)
+	ctx.AddProvider(NewProvider(DID{URI: pkhChecksummed}, privk))
+
+	_, err = ctx.GetProvider(DID{URI: pkhLowercase})
+	require.NoError(t, err)
+}
+
+func TestProviderFromPrivateKeyWithMethod(t *testing.T) {
+	privk, _, err := crypto.GenerateKeyPair(crypto.Secp256k1)
+	require.NoError(t, err)
+	addr, err := pubKeyEthAddress(privk.GetPublic())
+	require.NoError(t, err)
+
+	data := []byte("hello")
+	for method, check := range map[string]func(DID){
+		"key":  func(did DID) { require.Equal(t, FromPublicKey(privk.GetPublic()), did) },
+		"peer": func(did DID) { require.Equal(t, "peer", did.Method()) },
+		"pkh": func(did DID) {
+			require.Equal(t, "did:pkh:eip155:1:"+ethChecksumAddress(addr), did.URI)
+			require.True(t, did.CanonicalEqual(DID{URI: "did:pkh:eip155:1:0x" + hex.EncodeToString(addr)}))
+			require.True(t, did.CanonicalEqual(did))
+		},
+	} {
+		provider, err := ProviderFromPrivateKeyWithMethod(privk, method)
+		require.NoError(t, err, method)
+		check(provider.DID())
+
+		anchor := provider.Anchor()
+		require.Equal(t, provider.DID(), anchor.DID())
+		sig, err := provider.Sign(data)
+		require.NoError(t, err)
+		require.NoError(t, anchor.Verify(data, sig), method)
+
+		// the signature verifies with the anchor the DID resolves to
+		resolved, err := GetAnchorForDID(provider.DID())
+		require.NoError(t, err, method)
+		require.NoError(t, resolved.Verify(data, sig), method)
+
+		env, err := SignEnvelope(provider, data)
+		require.NoError(t, err)
+		require.NoError(t, VerifyEnvelope(NewTrustContext(), data, env), method)
+	}
+
+	edPrivk, _, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	_, err = ProviderFromPrivateKeyWithMethod(edPrivk, "pkh")
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+	_, err = ProviderFromPrivateKeyWithMethod(edPrivk, "web")
+	require.ErrorIs(t, err, ErrNoAnchorMethod)
+}
+
+func TestFromEthAddressString(t *testing.T) {
+	// EIP-55 test vectors
+	for _, addr := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	} {
+		did, err := FromEthAddressString(addr)
+		require.NoError(t, err, addr)
+		require.Equal(t, "did:pkh:eip155:1:"+addr, did.URI)
+
+		// unchecksummed input is rendered checksummed
+		did, err = FromEthAddressStringWithChainID(strings.ToLower(addr), 137)
+		require.NoError(t, err, addr)
+		require.Equal(t, "did:pkh:eip155:137:"+addr, did.URI)
+	}
+
+	_, err := FromEthAddressString(pkhChecksummed[len("did:pkh:eip155:1:"):])
+	require.NoError(t, err)
+
+	for _, bad := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", // bad checksum
+		"0x5aaeb6053f3e94c9b9a09f33669435e7ef1bea",   // too short
+		"5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",   // no prefix
+		"0xzzaeb6053f3e94c9b9a09f33669435e7ef1beaed", // not hex
+	} {
+		_, err := FromEthAddressString(bad)
+		require.ErrorIs(t, err, ErrInvalidDID, bad)
+	}
+}
+
+func TestToPKH(t *testing.T) {
+	// private key 1, whose Ethereum address is well known
+	var one [32]byte
+	one[31] = 1
+	compressed := secp256k1.PrivKeyFromBytes(one[:]).PubKey().SerializeCompressed()
+
+	secpPub, err := libp2p_crypto.UnmarshalSecp256k1PublicKey(compressed)
+	require.NoError(t, err)
+	ethPub, err := crypto.UnmarshalEthPublicKey(compressed)
+	require.NoError(t, err)
+
+	for _, pubk := range []crypto.PubKey{secpPub, ethPub} {
+		did, err := ToPKH(FromPublicKey(pubk), 1)
+		require.NoError(t, err)
+		require.Equal(t, "did:pkh:eip155:1:0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf", did.URI)
+
+		did, err = ToPKH(FromPublicKey(pubk), 137)
+		require.NoError(t, err)
+		require.Equal(t, "did:pkh:eip155:137:0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf", did.URI)
+	}
+
+	_, err = ToPKH(DID{URI: pkhChecksummed}, 1)
+	require.ErrorIs(t, err, ErrInvalidDID)
+	_, err = ToPKH(FromPublicKey(secpPub), 0)
+	require.ErrorIs(t, err, ErrInvalidDID)
+
+	_, edPub, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	_, err = ToPKH(FromPublicKey(edPub), 1)
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+}
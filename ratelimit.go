@@ -0,0 +1,114 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/depinkit/crypto"
+)
+
+// CountingProvider wraps a provider, counting its signing operations and
+// optionally capping them per interval; see RateLimitedProvider.
+type CountingProvider struct {
+	inner    Provider
+	max      int
+	interval time.Duration
+
+	mx          sync.Mutex
+	count       uint64
+	window      deadline
+	windowCount int
+}
+
+var (
+	_ Provider      = (*CountingProvider)(nil)
+	_ ContextSigner = (*CountingProvider)(nil)
+)
+
+// RateLimitedProvider wraps inner so that at most maxPerInterval signatures
+// are made in each interval; further Sign calls in the same interval fail
+// with ErrRateLimited without reaching inner. A non-positive maxPerInterval
+// or interval disables the cap, leaving only the count. The returned
+// provider is a *CountingProvider and is safe for concurrent use.
+func RateLimitedProvider(inner Provider, maxPerInterval int, interval time.Duration) Provider {
+	return &CountingProvider{
+		inner:    inner,
+		max:      maxPerInterval,
+		interval: interval,
+	}
+}
+
+func (p *CountingProvider) DID() DID {
+	return p.inner.DID()
+}
+
+func (p *CountingProvider) Sign(data []byte) ([]byte, error) {
+	if err := p.acquire(); err != nil {
+		return nil, err
+	}
+
+	return p.inner.Sign(data)
+}
+
+// SignContext signs with the inner provider's SignContext if it has one.
+func (p *CountingProvider) SignContext(ctx context.Context, data []byte) ([]byte, error) {
+	if err := p.acquire(); err != nil {
+		return nil, err
+	}
+
+	if signer, ok := p.inner.(ContextSigner); ok {
+		return signer.SignContext(ctx, data)
+	}
+
+	return p.inner.Sign(data)
+}
+
+func (p *CountingProvider) Anchor() Anchor {
+	return p.inner.Anchor()
+}
+
+func (p *CountingProvider) PrivateKey() (crypto.PrivKey, error) {
+	return p.inner.PrivateKey()
+}
+
+// SignCount returns the number of signing operations passed to the inner
+// provider; rejected calls are not counted.
+func (p *CountingProvider) SignCount() uint64 {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	return p.count
+}
+
+// acquire counts a signing operation, failing if the current interval's
+// cap has been reached.
+func (p *CountingProvider) acquire() error {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	if p.max > 0 && p.interval > 0 {
+		now := monotonicNow()
+		if !now.before(p.window) {
+			p.window = now.add(p.interval)
+			p.windowCount = 0
+		}
+
+		if p.windowCount >= p.max {
+			return fmt.Errorf("%d signatures per %s exceeded for %s: %w", p.max, p.interval, p.inner.DID(), ErrRateLimited)
+		}
+		p.windowCount++
+	}
+
+	p.count++
+	return nil
+}
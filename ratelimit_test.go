@@ -0,0 +1,89 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+func TestRateLimitedProvider(t *testing.T) {
+	mono := monotonicNow()
+	orig := monotonicNow
+	t.Cleanup(func() { monotonicNow = orig })
+	monotonicNow = func() deadline { return mono }
+
+	privk, _, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	inner, err := ProviderFromPrivateKey(privk)
+	require.NoError(t, err)
+
+	provider := RateLimitedProvider(inner, 2, time.Minute)
+	require.Equal(t, inner.DID(), provider.DID())
+	require.Equal(t, inner.Anchor(), provider.Anchor())
+	pk, err := provider.PrivateKey()
+	require.NoError(t, err)
+	require.True(t, pk.Equals(privk))
+
+	data := []byte("payload")
+	sig, err := provider.Sign(data)
+	require.NoError(t, err)
+	require.NoError(t, inner.Anchor().Verify(data, sig))
+	_, err = provider.(ContextSigner).SignContext(context.Background(), data)
+	require.NoError(t, err)
+
+	_, err = provider.Sign(data)
+	require.ErrorIs(t, err, ErrRateLimited)
+	require.Equal(t, uint64(2), provider.(*CountingProvider).SignCount())
+
+	mono = mono.add(time.Minute)
+	_, err = provider.Sign(data)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), provider.(*CountingProvider).SignCount())
+}
+
+func TestRateLimitedProviderConcurrent(t *testing.T) {
+	privk, _, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	inner, err := ProviderFromPrivateKey(privk)
+	require.NoError(t, err)
+
+	provider := RateLimitedProvider(inner, 10, time.Hour)
+	unlimited := RateLimitedProvider(inner, 0, 0)
+
+	var (
+		wg      sync.WaitGroup
+		mx      sync.Mutex
+		limited int
+	)
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := unlimited.Sign([]byte("payload"))
+			require.NoError(t, err)
+			if _, err := provider.Sign([]byte("payload")); err != nil {
+				mx.Lock()
+				limited++
+				mx.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 22, limited)
+	require.Equal(t, uint64(10), provider.(*CountingProvider).SignCount())
+	require.Equal(t, uint64(32), unlimited.(*CountingProvider).SignCount())
+}
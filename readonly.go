@@ -0,0 +1,91 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ReadOnlyTrustContext is a trust context that resolves anchors but never
+// holds state: every GetAnchor resolves afresh via GetAnchorForDID, nothing
+// is cached, there are no providers and mutations are ignored. It is meant
+// for untrusted input paths where cache poisoning must be impossible.
+type ReadOnlyTrustContext struct{}
+
+var _ TrustContext = ReadOnlyTrustContext{}
+
+func NewReadOnlyTrustContext() TrustContext {
+	return ReadOnlyTrustContext{}
+}
+
+func (ReadOnlyTrustContext) Anchors() []DID {
+	return nil
+}
+
+func (ReadOnlyTrustContext) Providers() []DID {
+	return nil
+}
+
+func (ctx ReadOnlyTrustContext) GetAnchor(did DID) (Anchor, error) {
+	return ctx.GetAnchorContext(context.Background(), did)
+}
+
+func (ctx ReadOnlyTrustContext) GetAnchorContext(resolveCtx context.Context, did DID) (Anchor, error) {
+	anchor, err := resolveAnchor(resolveCtx, ctx, did)
+	if err != nil {
+		return nil, fmt.Errorf("get anchor for did: %w", err)
+	}
+
+	return anchor, nil
+}
+
+func (ctx ReadOnlyTrustContext) GetAnchors(dids []DID) (map[DID]Anchor, map[DID]error) {
+	anchors := make(map[DID]Anchor, len(dids))
+	errs := make(map[DID]error)
+
+	seen := make(map[DID]struct{}, len(dids))
+	unique := make([]DID, 0, len(dids))
+	for _, did := range dids {
+		if _, ok := seen[did]; ok {
+			continue
+		}
+		seen[did] = struct{}{}
+		unique = append(unique, did)
+	}
+
+	resolveAnchors(unique, maxResolveWorkers, ctx.GetAnchor, anchors, errs)
+	return anchors, errs
+}
+
+func (ReadOnlyTrustContext) GetProvider(DID) (Provider, error) {
+	return nil, ErrNoProvider
+}
+
+// AddAnchor is a no-op.
+func (ReadOnlyTrustContext) AddAnchor(Anchor) {}
+
+// AddProvider is a no-op.
+func (ReadOnlyTrustContext) AddProvider(Provider) {}
+
+// RemoveAnchor is a no-op.
+func (ReadOnlyTrustContext) RemoveAnchor(DID) {}
+
+// RemoveProvider is a no-op.
+func (ReadOnlyTrustContext) RemoveProvider(DID) {}
+
+// Start is a no-op; there is nothing to collect.
+func (ReadOnlyTrustContext) Start(time.Duration) {}
+
+// Stop is a no-op.
+func (ReadOnlyTrustContext) Stop() {}
+
+// GC is a no-op.
+func (ReadOnlyTrustContext) GC() {}
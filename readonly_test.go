@@ -0,0 +1,60 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+func TestReadOnlyTrustContext(t *testing.T) {
+	ctx := NewReadOnlyTrustContext()
+
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	did := FromPublicKey(pubk)
+
+	anchor, err := ctx.GetAnchor(did)
+	require.NoError(t, err)
+	require.Equal(t, did, anchor.DID())
+
+	// untrusted input cannot poison the context
+	_, otherPubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	ctx.AddAnchor(NewAnchor(did, otherPubk))
+	require.Empty(t, ctx.Anchors())
+
+	anchor, err = ctx.GetAnchor(did)
+	require.NoError(t, err)
+	require.True(t, pubk.Equals(anchor.PublicKey()))
+
+	prov, err := ProviderFromPrivateKey(mustPrivKey(t))
+	require.NoError(t, err)
+	ctx.AddProvider(prov)
+	require.Empty(t, ctx.Providers())
+	_, err = ctx.GetProvider(prov.DID())
+	require.ErrorIs(t, err, ErrNoProvider)
+
+	bad := DID{URI: "did:web:example.com"}
+	anchors, errs := ctx.GetAnchors([]DID{did, bad, did})
+	require.Len(t, anchors, 1)
+	require.ErrorIs(t, errs[bad], ErrNoAnchorMethod)
+
+	require.NotPanics(t, func() {
+		ctx.RemoveAnchor(did)
+		ctx.RemoveProvider(prov.DID())
+		ctx.Start(time.Millisecond)
+		ctx.Stop()
+		ctx.GC()
+	})
+}
@@ -0,0 +1,84 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/depinkit/crypto"
+)
+
+// RemoteSigner is a signing service holding keys that never leave it, such
+// as a cloud KMS, an HSM or Vault. Signatures must be in the format the
+// key's anchor verifies (e.g. DER ECDSA over SHA-256 for secp256k1 keys).
+type RemoteSigner interface {
+	PublicKey(ctx context.Context, keyID string) (crypto.PubKey, error)
+	Sign(ctx context.Context, keyID string, data []byte) ([]byte, error)
+}
+
+// RemoteSignerProvider is a provider whose key is held by a RemoteSigner.
+type RemoteSignerProvider struct {
+	did    DID
+	pubk   crypto.PubKey
+	keyID  string
+	signer RemoteSigner
+}
+
+var (
+	_ Provider      = (*RemoteSignerProvider)(nil)
+	_ ContextSigner = (*RemoteSignerProvider)(nil)
+)
+
+// NewRemoteSignerProvider fetches the public key for keyID from signer once
+// and returns a provider for its did:key DID.
+func NewRemoteSignerProvider(ctx context.Context, signer RemoteSigner, keyID string) (Provider, error) {
+	pubk, err := signer.PublicKey(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("fetch remote public key: %w", err)
+	}
+
+	did := FromPublicKey(pubk)
+	if did.Empty() {
+		return nil, fmt.Errorf("remote key %s: %w", keyID, ErrInvalidKeyType)
+	}
+
+	return &RemoteSignerProvider{
+		did:    did,
+		pubk:   pubk,
+		keyID:  keyID,
+		signer: signer,
+	}, nil
+}
+
+func (p *RemoteSignerProvider) DID() DID {
+	return p.did
+}
+
+func (p *RemoteSignerProvider) Sign(data []byte) ([]byte, error) {
+	return p.SignContext(context.Background(), data)
+}
+
+// SignContext signs data with the remote signer; ctx bounds the request.
+func (p *RemoteSignerProvider) SignContext(ctx context.Context, data []byte) ([]byte, error) {
+	sig, err := p.signer.Sign(ctx, p.keyID, data)
+	if err != nil {
+		return nil, fmt.Errorf("remote sign: %w", err)
+	}
+
+	return sig, nil
+}
+
+func (p *RemoteSignerProvider) Anchor() Anchor {
+	return NewAnchor(p.did, p.pubk)
+}
+
+func (p *RemoteSignerProvider) PrivateKey() (crypto.PrivKey, error) {
+	return nil, fmt.Errorf("remote private key cannot be exported: %w", ErrHardwareKey)
+}
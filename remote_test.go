@@ -0,0 +1,66 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+type fakeRemoteSigner struct {
+	keys    map[string]crypto.PrivKey
+	fetches int
+}
+
+func (s *fakeRemoteSigner) PublicKey(_ context.Context, keyID string) (crypto.PubKey, error) {
+	s.fetches++
+	privk, ok := s.keys[keyID]
+	if !ok {
+		return nil, errors.New("no such key")
+	}
+	return privk.GetPublic(), nil
+}
+
+func (s *fakeRemoteSigner) Sign(ctx context.Context, keyID string, data []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.keys[keyID].Sign(data)
+}
+
+func TestRemoteSignerProvider(t *testing.T) {
+	privk := mustPrivKey(t)
+	signer := &fakeRemoteSigner{keys: map[string]crypto.PrivKey{"kms-key": privk}}
+
+	provider, err := NewRemoteSignerProvider(context.Background(), signer, "kms-key")
+	require.NoError(t, err)
+	require.Equal(t, FromPublicKey(privk.GetPublic()), provider.DID())
+
+	data := []byte("hello")
+	sig, err := provider.Sign(data)
+	require.NoError(t, err)
+	require.NoError(t, provider.Anchor().Verify(data, sig))
+	require.Equal(t, 1, signer.fetches, "public key is fetched once")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = provider.(ContextSigner).SignContext(ctx, data)
+	require.ErrorIs(t, err, context.Canceled)
+
+	_, err = provider.PrivateKey()
+	require.ErrorIs(t, err, ErrHardwareKey)
+
+	_, err = NewRemoteSignerProvider(context.Background(), signer, "missing")
+	require.Error(t, err)
+}
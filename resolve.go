@@ -0,0 +1,111 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ContentTypeDIDLDJSON is the media type of JSON-LD DID Documents.
+const ContentTypeDIDLDJSON = "application/did+ld+json"
+
+// Resolution error codes, per the W3C DID Resolution specification.
+const (
+	ResolutionErrorInvalidDID         = "invalidDid"
+	ResolutionErrorNotFound           = "notFound"
+	ResolutionErrorMethodNotSupported = "methodNotSupported"
+	ResolutionErrorInternal           = "internalError"
+)
+
+// ResolutionResult is the result of resolving a DID: its anchor and DID
+// Document with resolution and document metadata.
+type ResolutionResult struct {
+	Anchor             Anchor
+	Document           *Document
+	ResolutionMetadata ResolutionMetadata
+	DocumentMetadata   DocumentMetadata
+}
+
+// ResolutionMetadata describes the resolution process. Error holds one of
+// the ResolutionError codes if resolution failed.
+type ResolutionMetadata struct {
+	ContentType string    `json:"contentType,omitempty"`
+	Retrieved   time.Time `json:"retrieved,omitzero"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// DocumentMetadata describes the DID Document. Methods that derive the
+// document from the DID itself, such as did:key, leave the timestamps
+// zero.
+type DocumentMetadata struct {
+	Created     time.Time `json:"created,omitzero"`
+	Updated     time.Time `json:"updated,omitzero"`
+	Deactivated bool      `json:"deactivated,omitempty"`
+}
+
+// Resolve resolves did with the registered anchor methods; see
+// ResolveContext.
+func Resolve(did DID) (*ResolutionResult, error) {
+	return ResolveContext(context.Background(), did)
+}
+
+// ResolveContext resolves did and returns its anchor and DID Document with
+// metadata. On failure the result is still returned alongside the error,
+// with ResolutionMetadata.Error set to the matching error code. Anchors
+// that cannot be expressed as a document (e.g. a did:pkh account before
+// any signature has been verified) resolve with a nil Document.
+func ResolveContext(ctx context.Context, did DID) (*ResolutionResult, error) {
+	result := &ResolutionResult{
+		ResolutionMetadata: ResolutionMetadata{Retrieved: timeNow()},
+	}
+
+	if did.Empty() {
+		result.ResolutionMetadata.Error = ResolutionErrorInvalidDID
+		return result, &DIDError{Op: "resolve", Err: fmt.Errorf("%w: empty", ErrInvalidDID)}
+	}
+
+	if _, err := FromString(did.URI); err != nil {
+		result.ResolutionMetadata.Error = ResolutionErrorInvalidDID
+		return result, err
+	}
+
+	anchor, err := GetAnchorForDIDContext(ctx, did)
+	if err != nil {
+		result.ResolutionMetadata.Error = resolutionErrorCode(err)
+		return result, err
+	}
+	result.Anchor = anchor
+
+	doc, err := anchorDocument(anchor)
+	if err != nil {
+		log.Debugf("no document for %s: %s", did, err)
+		return result, nil
+	}
+
+	result.Document = doc
+	result.ResolutionMetadata.ContentType = ContentTypeDIDLDJSON
+	return result, nil
+}
+
+// resolutionErrorCode maps a resolution failure to its error code.
+func resolutionErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrNoAnchorMethod):
+		return ResolutionErrorMethodNotSupported
+	case errors.Is(err, ErrInvalidDID):
+		return ResolutionErrorInvalidDID
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ResolutionErrorInternal
+	default:
+		return ResolutionErrorNotFound
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+func TestResolve(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	orig := timeNow
+	t.Cleanup(func() { timeNow = orig })
+	timeNow = func() time.Time { return now }
+
+	_, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	did := FromPublicKey(pubk)
+
+	result, err := Resolve(did)
+	require.NoError(t, err)
+	require.Equal(t, did, result.Anchor.DID())
+	require.Equal(t, did.URI, result.Document.ID)
+	require.Equal(t, ResolutionMetadata{ContentType: ContentTypeDIDLDJSON, Retrieved: now}, result.ResolutionMetadata)
+	require.Zero(t, result.DocumentMetadata)
+
+	raw, err := json.Marshal(result.DocumentMetadata)
+	require.NoError(t, err)
+	require.JSONEq(t, `{}`, string(raw))
+
+	for uri, code := range map[string]string{
+		"":                    ResolutionErrorInvalidDID,
+		"did:Bad:x":           ResolutionErrorInvalidDID,
+		"did:unknown:example": ResolutionErrorMethodNotSupported,
+	} {
+		result, err := Resolve(DID{URI: uri})
+		require.Error(t, err, uri)
+		require.Equal(t, code, result.ResolutionMetadata.Error, uri)
+		require.Equal(t, now, result.ResolutionMetadata.Retrieved)
+		require.Nil(t, result.Anchor)
+	}
+
+	// did:pkh anchors have no document until a key has been recovered
+	sk, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	result, err = Resolve(ethAddressDID(ethAddress(sk.PubKey()), 1))
+	require.NoError(t, err)
+	require.NotNil(t, result.Anchor)
+	require.Nil(t, result.Document)
+	require.Empty(t, result.ResolutionMetadata.ContentType)
+}
@@ -0,0 +1,59 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"fmt"
+
+	"github.com/libp2p/go-libp2p/core/crypto/pb"
+
+	"github.com/depinkit/crypto"
+)
+
+// RevokedAnchor is the anchor of a revoked or denylisted DID: the DID
+// resolves, but no signature verifies. Added to a BasicTrustContext it is
+// kept until removed with RemoveAnchor and takes precedence over
+// resolution, which enforces a denylist.
+type RevokedAnchor struct {
+	did    DID
+	reason string
+}
+
+var _ Anchor = (*RevokedAnchor)(nil)
+
+// NewRevokedAnchor creates an anchor for did whose Verify always fails with
+// ErrRevoked and the reason.
+func NewRevokedAnchor(did DID, reason string) Anchor {
+	return &RevokedAnchor{did: did, reason: reason}
+}
+
+func (a *RevokedAnchor) DID() DID {
+	return a.did
+}
+
+func (a *RevokedAnchor) Verify(_ []byte, _ []byte) error {
+	return &DIDError{Op: "verify", DID: a.did.String(), Err: fmt.Errorf("%w: %s", ErrRevoked, a.reason)}
+}
+
+// Reason returns the reason the DID was revoked.
+func (a *RevokedAnchor) Reason() string {
+	return a.reason
+}
+
+func (a *RevokedAnchor) PublicKey() crypto.PubKey {
+	return nil
+}
+
+func (a *RevokedAnchor) KeyType() pb.KeyType {
+	return KeyTypeNone
+}
+
+func (a *RevokedAnchor) Algorithm() string {
+	return ""
+}
@@ -0,0 +1,81 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+func TestRevokedAnchor(t *testing.T) {
+	privk, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	did := FromPublicKey(pubk)
+
+	data := []byte("hello")
+	sig, err := privk.Sign(data)
+	require.NoError(t, err)
+
+	anchor := NewRevokedAnchor(did, "key compromised")
+	require.Equal(t, did, anchor.DID())
+	require.Nil(t, anchor.PublicKey())
+	require.Equal(t, KeyTypeNone, anchor.KeyType())
+
+	err = anchor.Verify(data, sig)
+	require.ErrorIs(t, err, ErrRevoked)
+	require.Contains(t, err.Error(), "key compromised")
+	require.NotErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestTrustContextRevokedAnchor(t *testing.T) {
+	mono := monotonicNow()
+	orig := monotonicNow
+	t.Cleanup(func() { monotonicNow = orig })
+	monotonicNow = func() deadline { return mono }
+
+	privk, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	did := DID{URI: "did:web:denied.example.com"}
+
+	data := []byte("hello")
+	sig, err := privk.Sign(data)
+	require.NoError(t, err)
+
+	ctx := NewTrustContextWithTTL(time.Minute).(*BasicTrustContext)
+	ctx.AddAnchor(NewAnchor(did, pubk))
+	ctx.AddAnchor(NewRevokedAnchor(did, "denylisted"))
+
+	// the revoked anchor survives the TTL and is not replaced by a resolved
+	// anchor
+	mono = mono.add(time.Hour)
+	ctx.GC()
+	ctx.AddAnchor(NewAnchor(did, pubk))
+
+	anchor, err := ctx.GetAnchor(did)
+	require.NoError(t, err)
+	require.ErrorIs(t, anchor.Verify(data, sig), ErrRevoked)
+
+	// until it is lifted
+	ctx.RemoveAnchor(did)
+	ctx.AddAnchor(NewAnchor(did, pubk))
+	anchor, err = ctx.GetAnchor(did)
+	require.NoError(t, err)
+	require.NoError(t, anchor.Verify(data, sig))
+
+	// did:key DIDs are denylisted ahead of their local resolution
+	keyDID := FromPublicKey(pubk)
+	ctx.AddAnchor(NewRevokedAnchor(keyDID, "denylisted"))
+	anchor, err = ctx.GetAnchor(keyDID)
+	require.NoError(t, err)
+	require.ErrorIs(t, anchor.Verify(data, sig), ErrRevoked)
+}
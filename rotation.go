@@ -0,0 +1,257 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto/pb"
+
+	"github.com/depinkit/crypto"
+)
+
+// RetiredKey is a rotated-out public key that is still accepted for
+// verification until Expire.
+type RetiredKey struct {
+	PublicKey crypto.PubKey
+	Expire    time.Time
+}
+
+// RotatableProvider is a provider for a long-lived DID whose signing key is
+// rotated periodically. It signs with the current key; retired keys remain
+// valid for verification for a grace period after rotation.
+//
+// The DID must not be derived from a key (did:key, did:peer), since it has
+// to stay the same across rotations; did:web is the typical choice.
+type RotatableProvider struct {
+	mx      sync.Mutex
+	did     DID
+	privk   crypto.PrivKey
+	retired []RetiredKey
+	grace   time.Duration
+}
+
+// RotatableAnchor verifies signatures for a DID with a current key and a
+// set of retired keys that are accepted until they expire.
+type RotatableAnchor struct {
+	did     DID
+	current *PublicKeyAnchor
+	retired []RetiredKey
+}
+
+var (
+	_ Provider      = (*RotatableProvider)(nil)
+	_ ContextSigner = (*RotatableProvider)(nil)
+	_ Anchor        = (*RotatableAnchor)(nil)
+)
+
+// NewRotatableProvider creates a rotatable provider for did, signing with
+// privk; keys retired by Rotate are accepted for grace afterwards.
+func NewRotatableProvider(did DID, privk crypto.PrivKey, grace time.Duration) (*RotatableProvider, error) {
+	if err := validateRotatableDID(did); err != nil {
+		return nil, err
+	}
+
+	if privk == nil {
+		return nil, fmt.Errorf("nil private key: %w", ErrInvalidKeyType)
+	}
+
+	if err := validateKeyWithDID(did, privk.GetPublic()); err != nil {
+		return nil, err
+	}
+
+	return &RotatableProvider{
+		did:   did,
+		privk: privk,
+		grace: grace,
+	}, nil
+}
+
+func validateRotatableDID(did DID) error {
+	switch did.Method() {
+	case "key", peerMethod:
+		return fmt.Errorf("did:%s is derived from a key and cannot rotate: %w", did.Method(), ErrInvalidDID)
+	}
+
+	return nil
+}
+
+func (p *RotatableProvider) DID() DID {
+	return p.did
+}
+
+func (p *RotatableProvider) Sign(data []byte) ([]byte, error) {
+	p.mx.Lock()
+	privk := p.privk
+	p.mx.Unlock()
+
+	return signData(privk, data)
+}
+
+// SignContext signs data with the current key; signing never blocks so the
+// context is ignored.
+func (p *RotatableProvider) SignContext(_ context.Context, data []byte) ([]byte, error) {
+	return p.Sign(data)
+}
+
+func (p *RotatableProvider) PrivateKey() (crypto.PrivKey, error) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	return p.privk, nil
+}
+
+// Anchor returns a snapshot anchor for the current key and the retired keys
+// that have not yet expired.
+func (p *RotatableProvider) Anchor() Anchor {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	p.pruneRetired(timeNow())
+	return newRotatableAnchor(p.did, p.privk.GetPublic(), p.retired)
+}
+
+// Rotate makes privk the signing key; the previous key is retired and still
+// accepted for verification for the provider's grace period.
+func (p *RotatableProvider) Rotate(privk crypto.PrivKey) error {
+	if privk == nil {
+		return fmt.Errorf("nil private key: %w", ErrInvalidKeyType)
+	}
+
+	if err := validateKeyWithDID(p.did, privk.GetPublic()); err != nil {
+		return err
+	}
+
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	now := timeNow()
+	p.pruneRetired(now)
+	p.retired = append(p.retired, RetiredKey{
+		PublicKey: p.privk.GetPublic(),
+		Expire:    now.Add(p.grace),
+	})
+	p.privk = privk
+
+	return nil
+}
+
+// RetiredKeys returns the retired keys that are still accepted.
+func (p *RotatableProvider) RetiredKeys() []RetiredKey {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	p.pruneRetired(timeNow())
+	return append([]RetiredKey(nil), p.retired...)
+}
+
+// pruneRetired drops expired retired keys; the caller holds the lock.
+func (p *RotatableProvider) pruneRetired(now time.Time) {
+	live := p.retired[:0]
+	for _, key := range p.retired {
+		if now.Before(key.Expire) {
+			live = append(live, key)
+		}
+	}
+	p.retired = live
+}
+
+// NewRotatableAnchor creates an anchor for did that accepts signatures from
+// current and, until they expire, from the retired keys.
+func NewRotatableAnchor(did DID, current crypto.PubKey, retired ...RetiredKey) (*RotatableAnchor, error) {
+	if err := validateRotatableDID(did); err != nil {
+		return nil, err
+	}
+
+	if err := validateKeyWithDID(did, current); err != nil {
+		return nil, err
+	}
+
+	for _, key := range retired {
+		if err := validateKeyWithDID(did, key.PublicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return newRotatableAnchor(did, current, retired), nil
+}
+
+func newRotatableAnchor(did DID, current crypto.PubKey, retired []RetiredKey) *RotatableAnchor {
+	return &RotatableAnchor{
+		did:     did,
+		current: &PublicKeyAnchor{did: did, pubk: current},
+		retired: append([]RetiredKey(nil), retired...),
+	}
+}
+
+func (a *RotatableAnchor) DID() DID {
+	return a.did
+}
+
+// Verify accepts a signature by the current key or by a retired key that
+// has not expired.
+func (a *RotatableAnchor) Verify(data []byte, sig []byte) error {
+	err := a.current.Verify(data, sig)
+	if err == nil {
+		return nil
+	}
+	errs := []error{fmt.Errorf("current key: %w", err)}
+
+	now := timeNow()
+	for i, key := range a.retired {
+		if !now.Before(key.Expire) {
+			continue
+		}
+
+		retired := &PublicKeyAnchor{did: a.did, pubk: key.PublicKey}
+		err := retired.Verify(data, sig)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("retired key %d: %w", i, err))
+	}
+
+	if len(errs) == 1 {
+		return err
+	}
+
+	return errors.Join(errs...)
+}
+
+// PublicKey returns the current key.
+func (a *RotatableAnchor) PublicKey() crypto.PubKey {
+	return a.current.PublicKey()
+}
+
+func (a *RotatableAnchor) KeyType() pb.KeyType {
+	return a.current.KeyType()
+}
+
+func (a *RotatableAnchor) Algorithm() string {
+	return a.current.Algorithm()
+}
+
+// Document produces a DID Document listing the current key first, followed
+// by the retired keys that have not expired.
+func (a *RotatableAnchor) Document() (*Document, error) {
+	pubks := []crypto.PubKey{a.current.PublicKey()}
+
+	now := timeNow()
+	for _, key := range a.retired {
+		if now.Before(key.Expire) {
+			pubks = append(pubks, key.PublicKey)
+		}
+	}
+
+	return newDocument(a.did, pubks)
+}
@@ -0,0 +1,108 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatableProvider(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	orig := timeNow
+	t.Cleanup(func() { timeNow = orig })
+	timeNow = func() time.Time { return now }
+
+	did := DID{URI: "did:web:service.example.com"}
+	first := mustPrivKey(t)
+	provider, err := NewRotatableProvider(did, first, time.Hour)
+	require.NoError(t, err)
+
+	data := []byte("hello")
+	oldSig, err := provider.Sign(data)
+	require.NoError(t, err)
+	require.NoError(t, provider.Anchor().Verify(data, oldSig))
+
+	second := mustPrivKey(t)
+	require.NoError(t, provider.Rotate(second))
+	require.Equal(t, did, provider.DID())
+	require.Len(t, provider.RetiredKeys(), 1)
+
+	newSig, err := provider.Sign(data)
+	require.NoError(t, err)
+	require.True(t, second.GetPublic().Equals(provider.Anchor().PublicKey()))
+
+	anchor := provider.Anchor()
+	require.NoError(t, anchor.Verify(data, newSig))
+	require.NoError(t, anchor.Verify(data, oldSig), "retired key within grace")
+
+	doc, err := anchor.(*RotatableAnchor).Document()
+	require.NoError(t, err)
+	require.Equal(t, []string{did.URI + "#key-1", did.URI + "#key-2"}, doc.Authentication)
+	require.Equal(t, []string{did.URI + "#key-3", did.URI + "#key-4"}, doc.KeyAgreement)
+
+	// past the grace window the retired key is no longer accepted, even by
+	// an anchor snapshot taken earlier
+	now = now.Add(time.Hour)
+	err = anchor.Verify(data, oldSig)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+	require.Equal(t, ErrInvalidSignature.Error(), err.Error())
+	require.NoError(t, anchor.Verify(data, newSig))
+	require.Empty(t, provider.RetiredKeys())
+
+	doc, err = provider.Anchor().(*RotatableAnchor).Document()
+	require.NoError(t, err)
+	require.Len(t, doc.Authentication, 1)
+}
+
+func TestRotatableProviderNISTCurves(t *testing.T) {
+	did := DID{URI: "did:web:service.example.com"}
+	data := []byte("hello")
+
+	for _, curve := range []elliptic.Curve{elliptic.P384(), elliptic.P521()} {
+		t.Run(curve.Params().Name, func(t *testing.T) {
+			privk, _, err := libp2p_crypto.GenerateECDSAKeyPairWithCurve(curve, rand.Reader)
+			require.NoError(t, err)
+			provider, err := NewRotatableProvider(did, privk, time.Hour)
+			require.NoError(t, err)
+
+			sig, err := provider.Sign(data)
+			require.NoError(t, err)
+			require.NoError(t, provider.Anchor().Verify(data, sig))
+
+			// the retired key still verifies after a rotation
+			next, _, err := libp2p_crypto.GenerateECDSAKeyPairWithCurve(curve, rand.Reader)
+			require.NoError(t, err)
+			require.NoError(t, provider.Rotate(next))
+			require.NoError(t, provider.Anchor().Verify(data, sig))
+		})
+	}
+}
+
+func TestRotatableProviderInvalid(t *testing.T) {
+	privk := mustPrivKey(t)
+
+	_, err := NewRotatableProvider(FromPublicKey(privk.GetPublic()), privk, time.Hour)
+	require.ErrorIs(t, err, ErrInvalidDID)
+
+	_, err = NewRotatableProvider(DID{URI: "did:web:example.com"}, nil, time.Hour)
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+
+	provider, err := NewRotatableProvider(DID{URI: "did:web:example.com"}, privk, time.Hour)
+	require.NoError(t, err)
+	require.ErrorIs(t, provider.Rotate(nil), ErrInvalidKeyType)
+
+	_, err = NewRotatableAnchor(DID{URI: "did:web:example.com"}, privk.GetPublic(), RetiredKey{PublicKey: bogusKey{}})
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+}
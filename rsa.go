@@ -0,0 +1,106 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	stdcrypto "crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+
+	"github.com/depinkit/crypto"
+)
+
+// RSAPSSAnchor verifies RSASSA-PSS signatures with SHA-256 over the data.
+// RSA keys in a PublicKeyAnchor verify RSASSA-PKCS1-v1_5 signatures, as
+// libp2p produces.
+type RSAPSSAnchor struct {
+	*PublicKeyAnchor
+	key *rsa.PublicKey
+}
+
+var _ Anchor = (*RSAPSSAnchor)(nil)
+
+// NewRSAPSSAnchor creates a PSS anchor for an RSA public key.
+func NewRSAPSSAnchor(did DID, pubk crypto.PubKey) (Anchor, error) {
+	key, err := rsaPublicKey(pubk)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RSAPSSAnchor{
+		PublicKeyAnchor: &PublicKeyAnchor{did: did, pubk: pubk},
+		key:             key,
+	}, nil
+}
+
+func (a *RSAPSSAnchor) Verify(data []byte, sig []byte) error {
+	digest := sha256.Sum256(data)
+	if err := rsa.VerifyPSS(a.key, stdcrypto.SHA256, digest[:], sig, nil); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+
+	return nil
+}
+
+func (a *RSAPSSAnchor) Algorithm() string {
+	return AlgPS256
+}
+
+func rsaPublicKey(pubk crypto.PubKey) (*rsa.PublicKey, error) {
+	if pubk.Type() != libp2p_crypto.RSA {
+		return nil, fmt.Errorf("key type %d is not an rsa key: %w", pubk.Type(), ErrInvalidKeyType)
+	}
+
+	std, err := libp2p_crypto.PubKeyToStdKey(pubk)
+	if err != nil {
+		return nil, fmt.Errorf("rsa public key: %w", err)
+	}
+
+	key, ok := std.(*rsa.PublicKey)
+	if !ok {
+		return nil, ErrInvalidKeyType
+	}
+
+	return key, nil
+}
+
+// rsaPKCS1Key returns the multicodec and the DER-encoded PKCS#1 form of an
+// RSA public key, as the did:key spec requires.
+func rsaPKCS1Key(pubk crypto.PubKey) (uint64, []byte, error) {
+	key, err := rsaPublicKey(pubk)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return multicodecKindRSAPubKey, x509.MarshalPKCS1PublicKey(key), nil
+}
+
+func unmarshalRSAPKCS1Key(data []byte) (crypto.PubKey, error) {
+	key, err := x509.ParsePKCS1PublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse rsa key: %w: %w", ErrInvalidKeyType, err)
+	}
+
+	pkix, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("marshal rsa key: %w", err)
+	}
+
+	// libp2p enforces its minimum RSA key size here
+	pubk, err := libp2p_crypto.UnmarshalRsaPublicKey(pkix)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKeyType, err)
+	}
+
+	return pubk, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"strings"
+	"testing"
+
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+	mb "github.com/multiformats/go-multibase"
+	"github.com/multiformats/go-varint"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRSAKeyURI(t *testing.T) {
+	privk, pubk, err := libp2p_crypto.GenerateRSAKeyPair(2048, rand.Reader)
+	require.NoError(t, err)
+
+	uri := FormatKeyURI(pubk)
+	require.True(t, strings.HasPrefix(uri, "did:key:z4MX"), uri)
+
+	parsed, err := ParseKeyURI(uri)
+	require.NoError(t, err)
+	require.True(t, pubk.Equals(parsed))
+
+	// the encoded key is DER PKCS#1, per the did:key spec
+	_, data, err := mb.Decode(strings.TrimPrefix(uri, keyPrefix+":"))
+	require.NoError(t, err)
+	codec, n, err := varint.FromUvarint(data)
+	require.NoError(t, err)
+	require.Equal(t, multicodecKindRSAPubKey, codec)
+	_, err = x509.ParsePKCS1PublicKey(data[n:])
+	require.NoError(t, err)
+
+	did := FromPublicKey(pubk)
+	provider, err := ProviderWithDID(did, privk)
+	require.NoError(t, err)
+	anchor, err := GetAnchorForDID(did)
+	require.NoError(t, err)
+	require.Equal(t, AlgRS256, anchor.Algorithm())
+
+	data = []byte("enterprise payload")
+	sig, err := provider.Sign(data)
+	require.NoError(t, err)
+	require.NoError(t, anchor.Verify(data, sig))
+}
+
+func TestRSAKeyURIRejectsGarbage(t *testing.T) {
+	data := varint.ToUvarint(multicodecKindRSAPubKey)
+	data = append(data, 0x30, 0x03, 0x02, 0x01, 0x01)
+	enc, err := mb.Encode(mb.Base58BTC, data)
+	require.NoError(t, err)
+
+	_, err = ParseKeyURI(keyPrefix + ":" + enc)
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+}
+
+func TestRSAPSSAnchor(t *testing.T) {
+	privk, pubk, err := libp2p_crypto.GenerateRSAKeyPair(2048, rand.Reader)
+	require.NoError(t, err)
+
+	std, err := libp2p_crypto.PrivKeyToStdKey(privk)
+	require.NoError(t, err)
+	rsaPriv := std.(*rsa.PrivateKey)
+
+	anchor, err := NewRSAPSSAnchor(FromPublicKey(pubk), pubk)
+	require.NoError(t, err)
+	require.Equal(t, AlgPS256, anchor.Algorithm())
+
+	data := []byte("pss payload")
+	digest := sha256.Sum256(data)
+	sig, err := rsa.SignPSS(rand.Reader, rsaPriv, crypto.SHA256, digest[:], nil)
+	require.NoError(t, err)
+	require.NoError(t, anchor.Verify(data, sig))
+	require.ErrorIs(t, anchor.Verify([]byte("other"), sig), ErrInvalidSignature)
+
+	// PKCS#1 v1.5 signatures are not PSS signatures
+	pkcs1, err := privk.Sign(data)
+	require.NoError(t, err)
+	require.ErrorIs(t, anchor.Verify(data, pkcs1), ErrInvalidSignature)
+
+	_, edPub, err := libp2p_crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	_, err = NewRSAPSSAnchor(FromPublicKey(edPub), edPub)
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+}
@@ -0,0 +1,136 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"slices"
+	"strings"
+)
+
+// DIDSet is a set of DIDs. The empty DID is never a member: adding it is a
+// no-op. A set created with NewNormalizedDIDSet stores and looks up DIDs in
+// their normal form (see Normalize), so equivalent spellings of a DID are
+// the same member; DIDs that cannot be normalized are kept as is.
+//
+// The zero value is an empty set that does not normalize, and a nil
+// *DIDSet reads as empty. A DIDSet is not safe for concurrent use.
+type DIDSet struct {
+	members   map[DID]struct{}
+	normalize bool
+}
+
+// NewDIDSet returns a set holding dids.
+func NewDIDSet(dids ...DID) *DIDSet {
+	s := &DIDSet{}
+	s.Add(dids...)
+	return s
+}
+
+// NewNormalizedDIDSet returns a set holding dids that normalizes DIDs on
+// insertion and lookup.
+func NewNormalizedDIDSet(dids ...DID) *DIDSet {
+	s := &DIDSet{normalize: true}
+	s.Add(dids...)
+	return s
+}
+
+func (s *DIDSet) key(did DID) DID {
+	if !s.normalize {
+		return did
+	}
+
+	normalized, err := did.Normalize()
+	if err != nil {
+		return did
+	}
+
+	return normalized
+}
+
+// Add adds dids to the set.
+func (s *DIDSet) Add(dids ...DID) {
+	for _, did := range dids {
+		if did.Empty() {
+			continue
+		}
+
+		if s.members == nil {
+			s.members = make(map[DID]struct{})
+		}
+		s.members[s.key(did)] = struct{}{}
+	}
+}
+
+// Contains reports whether did is a member of the set.
+func (s *DIDSet) Contains(did DID) bool {
+	if s == nil || did.Empty() {
+		return false
+	}
+
+	_, ok := s.members[s.key(did)]
+	return ok
+}
+
+// Remove removes did from the set; removing a non-member is a no-op.
+func (s *DIDSet) Remove(did DID) {
+	delete(s.members, s.key(did))
+}
+
+// Len returns the number of members.
+func (s *DIDSet) Len() int {
+	if s == nil {
+		return 0
+	}
+
+	return len(s.members)
+}
+
+// Slice returns the members sorted by URI.
+func (s *DIDSet) Slice() []DID {
+	result := make([]DID, 0, s.Len())
+	if s == nil {
+		return result
+	}
+
+	for did := range s.members {
+		result = append(result, did)
+	}
+	slices.SortFunc(result, func(a, b DID) int { return strings.Compare(a.URI, b.URI) })
+
+	return result
+}
+
+// Union returns a new set with the members of both sets; it normalizes if
+// s does.
+func (s *DIDSet) Union(other *DIDSet) *DIDSet {
+	result := &DIDSet{normalize: s.normalize}
+	for did := range s.members {
+		result.Add(did)
+	}
+	if other != nil {
+		for did := range other.members {
+			result.Add(did)
+		}
+	}
+
+	return result
+}
+
+// Intersect returns a new set with the members of s that are also members
+// of other, as looked up by other; it normalizes if s does.
+func (s *DIDSet) Intersect(other *DIDSet) *DIDSet {
+	result := &DIDSet{normalize: s.normalize}
+	for did := range s.members {
+		if other.Contains(did) {
+			result.Add(did)
+		}
+	}
+
+	return result
+}
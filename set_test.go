@@ -0,0 +1,68 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDIDSet(t *testing.T) {
+	a := DID{URI: "did:web:a.example"}
+	b := DID{URI: "did:web:b.example"}
+	c := DID{URI: "did:web:c.example"}
+
+	var zero DIDSet
+	require.False(t, zero.Contains(a))
+	zero.Add(a, DID{})
+	require.Equal(t, 1, zero.Len(), "the empty DID is never added")
+	require.False(t, zero.Contains(DID{}))
+
+	s := NewDIDSet(b, a, a)
+	require.Equal(t, 2, s.Len())
+	require.True(t, s.Contains(a))
+	require.False(t, s.Contains(c))
+	require.Equal(t, []DID{a, b}, s.Slice())
+
+	s.Remove(a)
+	s.Remove(c)
+	require.Equal(t, []DID{b}, s.Slice())
+
+	other := NewDIDSet(b, c)
+	require.Equal(t, []DID{b, c}, s.Union(other).Slice())
+	require.Equal(t, []DID{b}, s.Intersect(other).Slice())
+	require.Equal(t, []DID{b}, s.Union(nil).Slice())
+	require.Empty(t, s.Intersect(nil).Slice())
+
+	var nilSet *DIDSet
+	require.Equal(t, 0, nilSet.Len())
+	require.False(t, nilSet.Contains(a))
+	require.Empty(t, nilSet.Slice())
+}
+
+func TestNormalizedDIDSet(t *testing.T) {
+	upper := DID{URI: "did:web:Example.COM"}
+	lower := DID{URI: "did:web:example.com"}
+
+	require.False(t, NewDIDSet(upper).Contains(lower))
+
+	s := NewNormalizedDIDSet(upper)
+	require.True(t, s.Contains(lower))
+	s.Add(lower)
+	require.Equal(t, []DID{lower}, s.Slice())
+
+	// DIDs that cannot be normalized are kept as is
+	invalid := DID{URI: "not a did"}
+	s.Add(invalid)
+	require.True(t, s.Contains(invalid))
+
+	s.Remove(upper)
+	require.Equal(t, []DID{invalid}, s.Slice())
+}
@@ -0,0 +1,91 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	varint "github.com/multiformats/go-varint"
+)
+
+// SignBound signs data with the provider, binding the provider's DID into
+// the signed bytes so the signature cannot be attributed to another DID
+// sharing the same key.
+func SignBound(p Provider, data []byte) ([]byte, error) {
+	return p.Sign(boundMessage(p.DID(), data))
+}
+
+// VerifyBound verifies a signature produced by SignBound against the
+// anchor's DID.
+func VerifyBound(a Anchor, data, sig []byte) error {
+	return a.Verify(boundMessage(a.DID(), data), sig)
+}
+
+// VerifyAny verifies sig against each anchor in turn and returns the first
+// anchor that accepts it. If none does, the returned error wraps
+// ErrInvalidSignature together with the individual verification errors.
+func VerifyAny(anchors []Anchor, data, sig []byte) (Anchor, error) {
+	errs := make([]error, 0, len(anchors))
+	for _, anchor := range anchors {
+		err := anchor.Verify(data, sig)
+		if err == nil {
+			return anchor, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", anchor.DID(), err))
+	}
+
+	if len(errs) == 0 {
+		return nil, ErrInvalidSignature
+	}
+
+	return nil, fmt.Errorf("%w: %w", ErrInvalidSignature, errors.Join(errs...))
+}
+
+// timeNow is the clock used for signature age checks; tests replace it.
+var timeNow = time.Now
+
+// MaxClockSkew is how far ahead of the local clock VerifyTimed accepts a
+// signing timestamp.
+const MaxClockSkew = time.Minute
+
+// VerifyTimed verifies sig and then checks that signedAt, a separately
+// transmitted signing timestamp, is no older than maxAge. Stale signatures
+// yield ErrSignatureExpired; a signedAt more than MaxClockSkew in the future
+// yields ErrInvalidSignature.
+//
+// signedAt is not covered by sig: callers must include it in data, or a
+// replayed signature can be paired with a fresh timestamp.
+func VerifyTimed(a Anchor, data, sig []byte, signedAt time.Time, maxAge time.Duration) error {
+	if err := a.Verify(data, sig); err != nil {
+		return err
+	}
+
+	age := timeNow().Sub(signedAt)
+	if age < -MaxClockSkew {
+		return fmt.Errorf("%w: signed %s in the future, max skew %s", ErrInvalidSignature, -age, MaxClockSkew)
+	}
+	if age > maxAge {
+		return fmt.Errorf("%w: signed %s ago, max age %s", ErrSignatureExpired, age, maxAge)
+	}
+
+	return nil
+}
+
+// boundMessage frames data as uvarint(len(did)) || did || data.
+func boundMessage(did DID, data []byte) []byte {
+	uri := did.URI
+	size := varint.UvarintSize(uint64(len(uri)))
+	msg := make([]byte, size+len(uri)+len(data))
+	n := varint.PutUvarint(msg, uint64(len(uri)))
+	n += copy(msg[n:], uri)
+	copy(msg[n:], data)
+	return msg
+}
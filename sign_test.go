@@ -0,0 +1,101 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+func TestSignBound(t *testing.T) {
+	privk, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+
+	prov, err := ProviderFromPrivateKey(privk)
+	require.NoError(t, err)
+
+	msg := []byte("bound-message")
+	sig, err := SignBound(prov, msg)
+	require.NoError(t, err)
+
+	require.NoError(t, VerifyBound(prov.Anchor(), msg, sig))
+
+	// same key, different DID: the binding must not verify
+	other := NewAnchor(DID{URI: "did:web:example.com"}, pubk)
+	require.ErrorIs(t, VerifyBound(other, msg, sig), ErrInvalidSignature)
+
+	// a bound signature is not a plain signature over the data
+	require.ErrorIs(t, prov.Anchor().Verify(msg, sig), ErrInvalidSignature)
+}
+
+func TestVerifyAny(t *testing.T) {
+	var (
+		anchors   []Anchor
+		providers []Provider
+	)
+	for i := 0; i < 3; i++ {
+		privk, _, err := crypto.GenerateKeyPair(crypto.Ed25519)
+		require.NoError(t, err)
+		prov, err := ProviderFromPrivateKey(privk)
+		require.NoError(t, err)
+		providers = append(providers, prov)
+		anchors = append(anchors, prov.Anchor())
+	}
+
+	msg := []byte("rotated")
+	sig, err := providers[1].Sign(msg)
+	require.NoError(t, err)
+
+	matched, err := VerifyAny(anchors, msg, sig)
+	require.NoError(t, err)
+	require.Equal(t, providers[1].DID(), matched.DID())
+
+	_, err = VerifyAny([]Anchor{anchors[0], anchors[2]}, msg, sig)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+	require.Contains(t, err.Error(), anchors[0].DID().String())
+	require.Contains(t, err.Error(), anchors[2].DID().String())
+
+	_, err = VerifyAny(nil, msg, sig)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestVerifyTimed(t *testing.T) {
+	fixed := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	orig := timeNow
+	t.Cleanup(func() { timeNow = orig })
+	timeNow = func() time.Time { return fixed }
+
+	privk, _, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	prov, err := ProviderFromPrivateKey(privk)
+	require.NoError(t, err)
+
+	msg := []byte("frame")
+	sig, err := prov.Sign(msg)
+	require.NoError(t, err)
+	anchor := prov.Anchor()
+
+	require.NoError(t, VerifyTimed(anchor, msg, sig, fixed.Add(-30*time.Second), time.Minute))
+	require.NoError(t, VerifyTimed(anchor, msg, sig, fixed.Add(-time.Minute), time.Minute))
+	require.ErrorIs(t, VerifyTimed(anchor, msg, sig, fixed.Add(-2*time.Minute), time.Minute), ErrSignatureExpired)
+
+	// small clock skew is tolerated, far-future timestamps are not
+	require.NoError(t, VerifyTimed(anchor, msg, sig, fixed.Add(MaxClockSkew), time.Minute))
+	err = VerifyTimed(anchor, msg, sig, fixed.Add(24*time.Hour), time.Minute)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+	require.NotErrorIs(t, err, ErrSignatureExpired)
+
+	// a bad signature fails verification before the age check
+	err = VerifyTimed(anchor, []byte("other"), sig, fixed, time.Minute)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+}
@@ -0,0 +1,113 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	stdcrypto "crypto"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"io"
+
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+
+	"github.com/depinkit/crypto"
+)
+
+// StreamSigner is implemented by providers that can sign a message read
+// from a stream without buffering it.
+//
+// Stream signatures are computed over a digest of the stream. For Ed25519
+// this is Ed25519ph (RFC 8032 prehash mode), so Ed25519 stream signatures
+// and plain Sign signatures are not interchangeable: each must be verified
+// with its own counterpart (VerifyStream or Verify).
+type StreamSigner interface {
+	SignStream(r io.Reader) ([]byte, error)
+}
+
+// StreamVerifier is implemented by anchors that can verify a StreamSigner
+// signature over a message read from a stream.
+type StreamVerifier interface {
+	VerifyStream(r io.Reader, sig []byte) error
+}
+
+var (
+	_ StreamSigner   = (*PrivateKeyProvider)(nil)
+	_ StreamVerifier = (*PublicKeyAnchor)(nil)
+)
+
+// SignStream signs the stream's digest: SHA-512 with Ed25519ph for Ed25519
+// keys, SHA-256 for secp256k1 and ECDSA keys.
+func (p *PrivateKeyProvider) SignStream(r io.Reader) ([]byte, error) {
+	switch p.privk.Type() {
+	case crypto.Ed25519:
+		digest, err := streamDigest(sha512.New(), r)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := p.privk.Raw()
+		if err != nil {
+			return nil, fmt.Errorf("raw private key: %w", err)
+		}
+		return ed25519.PrivateKey(raw).Sign(nil, digest, &ed25519.Options{Hash: stdcrypto.SHA512})
+
+	case crypto.Secp256k1, libp2p_crypto.ECDSA:
+		digest, err := streamDigest(sha256.New(), r)
+		if err != nil {
+			return nil, err
+		}
+		return signDigest(p.privk, digest)
+
+	default:
+		return nil, fmt.Errorf("stream signing not supported for key type %d: %w", p.privk.Type(), ErrInvalidKeyType)
+	}
+}
+
+// VerifyStream verifies a SignStream signature over the stream.
+func (a *PublicKeyAnchor) VerifyStream(r io.Reader, sig []byte) error {
+	if err := checkSignatureSize(a.pubk, sig); err != nil {
+		return err
+	}
+
+	switch a.pubk.Type() {
+	case crypto.Ed25519:
+		digest, err := streamDigest(sha512.New(), r)
+		if err != nil {
+			return err
+		}
+		raw, err := a.pubk.Raw()
+		if err != nil {
+			return fmt.Errorf("raw public key: %w", err)
+		}
+		if err := ed25519.VerifyWithOptions(ed25519.PublicKey(raw), digest, sig, &ed25519.Options{Hash: stdcrypto.SHA512}); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+		}
+		return nil
+
+	case crypto.Secp256k1, libp2p_crypto.ECDSA:
+		digest, err := streamDigest(sha256.New(), r)
+		if err != nil {
+			return err
+		}
+		return verifyDigest(a.pubk, digest, sig)
+
+	default:
+		return fmt.Errorf("stream verification not supported for key type %d: %w", a.pubk.Type(), ErrInvalidKeyType)
+	}
+}
+
+func streamDigest(h hash.Hash, r io.Reader) ([]byte, error) {
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, fmt.Errorf("read stream: %w", err)
+	}
+
+	return h.Sum(nil), nil
+}
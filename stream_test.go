@@ -0,0 +1,58 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+func TestSignVerifyStream(t *testing.T) {
+	payload := make([]byte, 1<<20)
+	_, err := rand.Read(payload)
+	require.NoError(t, err)
+
+	ecPriv, _, err := libp2p_crypto.GenerateECDSAKeyPairWithCurve(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	edPriv, _, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	secpPriv, _, err := crypto.GenerateKeyPair(crypto.Secp256k1)
+	require.NoError(t, err)
+
+	for _, privk := range []crypto.PrivKey{edPriv, secpPriv, ecPriv} {
+		provider, err := ProviderFromPrivateKey(privk)
+		require.NoError(t, err)
+		anchor := provider.Anchor().(StreamVerifier)
+
+		sig, err := provider.(StreamSigner).SignStream(bytes.NewReader(payload))
+		require.NoError(t, err)
+		require.NoError(t, anchor.VerifyStream(bytes.NewReader(payload), sig))
+
+		tampered := append([]byte{0}, payload...)
+		require.Error(t, anchor.VerifyStream(bytes.NewReader(tampered), sig))
+	}
+
+	// Ed25519ph stream signatures are not plain Ed25519 signatures
+	provider, err := ProviderFromPrivateKey(edPriv)
+	require.NoError(t, err)
+	streamSig, err := provider.(StreamSigner).SignStream(bytes.NewReader(payload))
+	require.NoError(t, err)
+	require.Error(t, provider.Anchor().Verify(payload, streamSig))
+
+	plainSig, err := provider.Sign(payload)
+	require.NoError(t, err)
+	require.ErrorIs(t, provider.Anchor().(StreamVerifier).VerifyStream(bytes.NewReader(payload), plainSig), ErrInvalidSignature)
+}
@@ -0,0 +1,158 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	verifyCacheSize = 4096
+	verifyCacheTTL  = time.Minute
+)
+
+var defaultVerifyCache = NewVerifyCache(verifyCacheSize, verifyCacheTTL)
+
+// VerifyCached verifies sig over data with the anchor, memoizing the result
+// in a process-wide cache of 4096 entries that expire after a minute; see
+// VerifyCache.
+func VerifyCached(anchor Anchor, data, sig []byte) error {
+	return defaultVerifyCache.Verify(anchor, data, sig)
+}
+
+// VerifyCache is a bounded LRU of signature verification results, keyed on
+// a hash of the anchor type, DID, public key, data and signature, so that a
+// message verified repeatedly (e.g. as it propagates through gossip) costs
+// one verification per TTL. Invalid and malformed signatures are cached too;
+// other failures, such as a registry lookup error, are not. Anchors whose
+// result is not determined by their key bypass the cache; see
+// verifyCacheable. A VerifyCache is safe for concurrent use.
+type VerifyCache struct {
+	mx       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	entries  map[[sha256.Size]byte]*list.Element
+	order    *list.List // front is most recently used
+}
+
+type verifyCacheEntry struct {
+	key    [sha256.Size]byte
+	err    error
+	expire deadline
+}
+
+// NewVerifyCache creates a cache holding at most capacity results for ttl.
+func NewVerifyCache(capacity int, ttl time.Duration) *VerifyCache {
+	return &VerifyCache{
+		capacity: max(capacity, 1),
+		ttl:      ttl,
+		entries:  make(map[[sha256.Size]byte]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Verify returns the cached result for (anchor, data, sig) if there is an
+// unexpired one, and otherwise verifies and caches the result.
+func (c *VerifyCache) Verify(anchor Anchor, data, sig []byte) error {
+	if !verifyCacheable(anchor) {
+		return anchor.Verify(data, sig)
+	}
+
+	key := verifyCacheKey(anchor, data, sig)
+
+	c.mx.Lock()
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*verifyCacheEntry)
+		if monotonicNow().before(entry.expire) {
+			c.order.MoveToFront(elem)
+			c.mx.Unlock()
+			return entry.err
+		}
+		c.remove(elem)
+	}
+	c.mx.Unlock()
+
+	err := anchor.Verify(data, sig)
+	if err != nil && !errors.Is(err, ErrInvalidSignature) && !errors.Is(err, ErrMalformedSignature) {
+		return err
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	c.entries[key] = c.order.PushFront(&verifyCacheEntry{
+		key:    key,
+		err:    err,
+		expire: monotonicNow().add(c.ttl),
+	})
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+
+	return err
+}
+
+// Len returns the number of cached results, including expired ones not yet
+// evicted.
+func (c *VerifyCache) Len() int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return c.order.Len()
+}
+
+// remove drops a cache entry; the caller holds the lock.
+func (c *VerifyCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*verifyCacheEntry).key)
+}
+
+// verifyCacheable reports whether the anchor's verification result is
+// determined by its type, DID and public key. Anchors without a key (e.g. a
+// did:pkh anchor before its first verification, revoked and multi-key
+// anchors) could share entries with other anchors for the same DID; rotatable
+// anchors accept retired keys only until they expire, and hasher anchors
+// depend on the hasher.
+func verifyCacheable(anchor Anchor) bool {
+	switch anchor.(type) {
+	case *RevokedAnchor, *RotatableAnchor, *HasherAnchor:
+		return false
+	}
+
+	return anchor.PublicKey() != nil
+}
+
+// verifyCacheKey hashes the length-prefixed anchor type, DID, public key,
+// data and signature. Including the key means a rotated anchor is verified
+// afresh; including the type keeps e.g. a wallet anchor and a plain anchor
+// for the same key from sharing results.
+func verifyCacheKey(anchor Anchor, data, sig []byte) [sha256.Size]byte {
+	pubk, _ := anchor.PublicKey().Raw()
+
+	hasher := sha256.New()
+	for _, part := range [][]byte{[]byte(fmt.Sprintf("%T", anchor)), []byte(anchor.DID().URI), pubk, data, sig} {
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(len(part)))
+		hasher.Write(size[:])
+		hasher.Write(part)
+	}
+
+	var key [sha256.Size]byte
+	hasher.Sum(key[:0])
+	return key
+}
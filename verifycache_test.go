@@ -0,0 +1,170 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+// countingAnchor counts the verifications that reach the wrapped anchor.
+type countingAnchor struct {
+	Anchor
+	calls atomic.Int32
+	err   error
+}
+
+func (a *countingAnchor) Verify(data, sig []byte) error {
+	a.calls.Add(1)
+	if a.err != nil {
+		return a.err
+	}
+	return a.Anchor.Verify(data, sig)
+}
+
+func TestVerifyCache(t *testing.T) {
+	mono := monotonicNow()
+	orig := monotonicNow
+	t.Cleanup(func() { monotonicNow = orig })
+	monotonicNow = func() deadline { return mono }
+
+	privk, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	anchor := &countingAnchor{Anchor: NewAnchor(FromPublicKey(pubk), pubk)}
+
+	data := []byte("gossip message")
+	sig, err := privk.Sign(data)
+	require.NoError(t, err)
+
+	cache := NewVerifyCache(2, time.Minute)
+	require.NoError(t, cache.Verify(anchor, data, sig))
+	require.NoError(t, cache.Verify(anchor, data, sig))
+	require.Equal(t, int32(1), anchor.calls.Load(), "hit should skip verification")
+
+	// negative results are cached too
+	require.ErrorIs(t, cache.Verify(anchor, []byte("forged"), sig), ErrInvalidSignature)
+	require.ErrorIs(t, cache.Verify(anchor, []byte("forged"), sig), ErrInvalidSignature)
+	require.Equal(t, int32(2), anchor.calls.Load())
+
+	// expired entries are verified again
+	mono = mono.add(time.Minute)
+	require.NoError(t, cache.Verify(anchor, data, sig))
+	require.Equal(t, int32(3), anchor.calls.Load())
+
+	// the least recently used entry is evicted
+	require.NoError(t, cache.Verify(anchor, data, sig))
+	require.ErrorIs(t, cache.Verify(anchor, data, sig[:10]), ErrMalformedSignature)
+	require.ErrorIs(t, cache.Verify(anchor, []byte("other"), sig), ErrInvalidSignature)
+	require.Equal(t, 2, cache.Len())
+	require.NoError(t, cache.Verify(anchor, data, sig))
+	require.Equal(t, int32(6), anchor.calls.Load())
+
+	// other failures are not cached
+	failing := &countingAnchor{Anchor: anchor.Anchor, err: ErrTODO}
+	require.ErrorIs(t, cache.Verify(failing, []byte("unreachable"), sig), ErrTODO)
+	require.ErrorIs(t, cache.Verify(failing, []byte("unreachable"), sig), ErrTODO)
+	require.Equal(t, int32(2), failing.calls.Load())
+}
+
+func TestVerifyCacheKey(t *testing.T) {
+	_, pubk1, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	_, pubk2, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	did := DID{URI: "did:web:example.com"}
+
+	// a rotated key must not reuse the old key's results
+	require.NotEqual(t,
+		verifyCacheKey(NewAnchor(did, pubk1), []byte("data"), []byte("sig")),
+		verifyCacheKey(NewAnchor(did, pubk2), []byte("data"), []byte("sig")))
+
+	// parts are length-prefixed, so shifting bytes between them changes the key
+	anchor := NewAnchor(did, pubk1)
+	require.NotEqual(t,
+		verifyCacheKey(anchor, []byte("datas"), []byte("ig")),
+		verifyCacheKey(anchor, []byte("data"), []byte("sig")))
+}
+
+func TestVerifyCachedConcurrent(t *testing.T) {
+	privk, pubk, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	anchor := NewAnchor(FromPublicKey(pubk), pubk)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := []byte{byte(i % 4)}
+			sig, err := privk.Sign(data)
+			require.NoError(t, err)
+			for j := 0; j < 50; j++ {
+				require.NoError(t, VerifyCached(anchor, data, sig))
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestVerifyCacheBypass(t *testing.T) {
+	sk, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	did := ethAddressDID(ethAddress(sk.PubKey()), 1)
+	msg := []byte("sign in")
+	sig := personalSign(sk, msg)
+
+	cache := NewVerifyCache(16, time.Minute)
+	pkh, err := NewPKHAnchor(did)
+	require.NoError(t, err)
+
+	// a key-less anchor is verified without touching the cache
+	require.NoError(t, cache.Verify(pkh, msg, sig))
+	require.Zero(t, cache.Len())
+	require.NoError(t, cache.Verify(pkh, msg, sig))
+	require.Equal(t, 1, cache.Len())
+
+	// a cached success does not override a revocation of the same DID
+	require.ErrorIs(t, cache.Verify(NewRevokedAnchor(did, "stolen"), msg, sig), ErrRevoked)
+
+	// nor does a result cached for another anchor type with the same key
+	eth, err := NewEthPersonalSignAnchor(did, pkh.PublicKey())
+	require.NoError(t, err)
+	require.NoError(t, cache.Verify(eth, msg, sig))
+	require.Equal(t, 2, cache.Len())
+}
+
+func TestVerifyCacheRotatableAnchor(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	orig := timeNow
+	t.Cleanup(func() { timeNow = orig })
+	timeNow = func() time.Time { return now }
+
+	did := DID{URI: "did:web:service.example.com"}
+	provider, err := NewRotatableProvider(did, mustPrivKey(t), time.Hour)
+	require.NoError(t, err)
+	data := []byte("hello")
+	sig, err := provider.Sign(data)
+	require.NoError(t, err)
+	require.NoError(t, provider.Rotate(mustPrivKey(t)))
+
+	cache := NewVerifyCache(16, time.Hour)
+	anchor := provider.Anchor()
+	require.NoError(t, cache.Verify(anchor, data, sig))
+
+	// the retired key expires even though the cache TTL has not
+	now = now.Add(time.Hour)
+	require.ErrorIs(t, cache.Verify(anchor, data, sig), ErrInvalidSignature)
+}
@@ -0,0 +1,99 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"filippo.io/edwards25519"
+	"github.com/libp2p/go-libp2p/core/crypto/pb"
+	mb "github.com/multiformats/go-multibase"
+	varint "github.com/multiformats/go-varint"
+
+	"github.com/depinkit/crypto"
+)
+
+const (
+	multicodecKindX25519PubKey uint64 = 0xec
+
+	// KeyTypeX25519 is the key type of X25519 key agreement keys; libp2p
+	// has no such key type, so the multicodec is used.
+	KeyTypeX25519 pb.KeyType = 0xec
+)
+
+// X25519PublicKey is an X25519 Diffie-Hellman public key. It is a key
+// agreement key and cannot verify signatures.
+type X25519PublicKey struct {
+	raw []byte
+}
+
+var _ crypto.PubKey = (*X25519PublicKey)(nil)
+
+func (k *X25519PublicKey) Verify([]byte, []byte) (bool, error) {
+	return false, errors.New("x25519 key agreement keys cannot verify signatures")
+}
+
+func (k *X25519PublicKey) Raw() ([]byte, error) {
+	return append([]byte(nil), k.raw...), nil
+}
+
+func (k *X25519PublicKey) Type() pb.KeyType {
+	return KeyTypeX25519
+}
+
+func (k *X25519PublicKey) Equals(o crypto.Key) bool {
+	other, ok := o.(*X25519PublicKey)
+	return ok && bytes.Equal(k.raw, other.raw)
+}
+
+// KeyAgreementKey derives the X25519 key agreement key implied by an
+// Ed25519 did:key, converting the Edwards point to its Montgomery form
+// (u = (1 + y) / (1 - y)). It also returns the key's verification method ID,
+// did:key:<ed25519>#<x25519>, as specified by did:key. A fragment on did is
+// ignored.
+func KeyAgreementKey(did DID) (crypto.PubKey, string, error) {
+	base, _, _ := strings.Cut(did.URI, "#")
+	did = DID{URI: base}
+
+	pubk, err := PublicKeyFromDID(did)
+	if err != nil {
+		return nil, "", fmt.Errorf("public key from did: %w", err)
+	}
+
+	return keyAgreementKey(did, pubk)
+}
+
+func keyAgreementKey(did DID, pubk crypto.PubKey) (crypto.PubKey, string, error) {
+	if pubk.Type() != crypto.Ed25519 {
+		return nil, "", fmt.Errorf("key type %d has no key agreement key: %w", pubk.Type(), ErrInvalidKeyType)
+	}
+
+	raw, err := pubk.Raw()
+	if err != nil {
+		return nil, "", fmt.Errorf("raw public key: %w", err)
+	}
+
+	point, err := new(edwards25519.Point).SetBytes(raw)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid ed25519 point: %w: %w", ErrInvalidKeyType, err)
+	}
+
+	xkey := &X25519PublicKey{raw: point.BytesMontgomery()}
+
+	data := append(varint.ToUvarint(multicodecKindX25519PubKey), xkey.raw...)
+	fragment, err := mb.Encode(mb.Base58BTC, data)
+	if err != nil {
+		return nil, "", fmt.Errorf("encode key agreement key: %w", err)
+	}
+
+	return xkey, did.URI + "#" + fragment, nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2024, Nunet
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and limitations under the License.
+
+package did
+
+import (
+	"crypto/ecdh"
+	"crypto/sha512"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/depinkit/crypto"
+)
+
+func TestKeyAgreementKeySpecExample(t *testing.T) {
+	// from the did:key specification examples
+	for did, fragment := range map[string]string{
+		"did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK": "z6LSj72tK8brWgZja8NLRwPigth2T9QRiG1uH9oKZuKjdh9p",
+		"did:key:z6MkiTBz1ymuepAQ4HEHYSF1H8quG5GLVVQR3djdX3mDooWp": "z6LShs9GGnqk85isEBzzshkuVWrVKsRp24GnDuHk8QWkARMW",
+	} {
+		key, vmID, err := KeyAgreementKey(DID{URI: did})
+		require.NoError(t, err)
+		require.Equal(t, KeyTypeX25519, key.Type())
+		require.Equal(t, did+"#"+fragment, vmID)
+	}
+}
+
+func TestKeyAgreementKeyDiffieHellman(t *testing.T) {
+	alicePriv, alicePub, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+	_, bobPub, err := crypto.GenerateKeyPair(crypto.Ed25519)
+	require.NoError(t, err)
+
+	// the X25519 private scalar is the clamped SHA-512 of the Ed25519 seed
+	raw, err := alicePriv.Raw()
+	require.NoError(t, err)
+	h := sha512.Sum512(raw[:32])
+	xpriv, err := ecdh.X25519().NewPrivateKey(h[:32])
+	require.NoError(t, err)
+
+	key, _, err := KeyAgreementKey(FromPublicKey(alicePub))
+	require.NoError(t, err)
+	xraw, err := key.Raw()
+	require.NoError(t, err)
+	require.Equal(t, xpriv.PublicKey().Bytes(), xraw)
+
+	bobKey, _, err := KeyAgreementKey(FromPublicKey(bobPub))
+	require.NoError(t, err)
+	require.False(t, key.Equals(bobKey))
+
+	ok, err := key.Verify([]byte("data"), []byte("sig"))
+	require.Error(t, err)
+	require.False(t, ok)
+
+	_, secpPub, err := crypto.GenerateKeyPair(crypto.Secp256k1)
+	require.NoError(t, err)
+	_, _, err = KeyAgreementKey(FromPublicKey(secpPub))
+	require.ErrorIs(t, err, ErrInvalidKeyType)
+
+	_, _, err = KeyAgreementKey(DID{URI: "did:web:example.com"})
+	require.ErrorIs(t, err, ErrInvalidDID)
+}
+
+func TestPublicKeyFromDIDFragment(t *testing.T) {
+	did := DID{URI: "did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK"}
+	signing, err := PublicKeyFromDID(did)
+	require.NoError(t, err)
+
+	self, err := PublicKeyFromDID(DID{URI: did.URI + "#" + did.Identifier()})
+	require.NoError(t, err)
+	require.True(t, signing.Equals(self))
+
+	agreement, err := PublicKeyFromDID(DID{URI: did.URI + "#z6LSj72tK8brWgZja8NLRwPigth2T9QRiG1uH9oKZuKjdh9p"})
+	require.NoError(t, err)
+	require.Equal(t, KeyTypeX25519, agreement.Type())
+
+	xkey, _, err := KeyAgreementKey(did)
+	require.NoError(t, err)
+	require.True(t, xkey.Equals(agreement))
+
+	// a key agreement key of another DID is not a method of this one
+	_, err = PublicKeyFromDID(DID{URI: did.URI + "#z6LShs9GGnqk85isEBzzshkuVWrVKsRp24GnDuHk8QWkARMW"})
+	require.ErrorIs(t, err, ErrInvalidDID)
+
+	_, err = PublicKeyFromDID(DID{URI: did.URI + "#key-1"})
+	require.ErrorIs(t, err, ErrInvalidDID)
+}